@@ -9,6 +9,7 @@ import (
 	"maps"
 	"math"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 	"unicode"
@@ -18,8 +19,10 @@ import (
 	"github.com/go-telegram/bot/models"
 
 	"github.com/uchr/ToDoInfo/internal/auth"
+	"github.com/uchr/ToDoInfo/internal/clock"
 	"github.com/uchr/ToDoInfo/internal/service"
 	"github.com/uchr/ToDoInfo/internal/storage"
+	"github.com/uchr/ToDoInfo/internal/todoclient"
 	"github.com/uchr/ToDoInfo/internal/todometrics"
 )
 
@@ -28,24 +31,36 @@ type BotConfig struct {
 	Token            string
 	ChatID           int64
 	DailySummaryTime string // "HH:MM" format, e.g. "09:00"
+	// SomedayList names the list the zombie triage moves tasks to.
+	SomedayList string
+	// InboxList names the list captured tasks go to without a #list; empty
+	// means the To Do default list.
+	InboxList string
 }
 
 // Bot is the Telegram bot that serves task statistics.
 type Bot struct {
 	config    BotConfig
 	collector *service.Collector
+	store     storage.StatsStorage
 	auth      *auth.AuthClient
+	clock     clock.Clock
 	logger    *slog.Logger
 	tgBot     *bot.Bot
+	triage    *triageRegistry
 }
 
-// New creates a new Bot instance.
-func New(cfg BotConfig, collector *service.Collector, authClient *auth.AuthClient, logger *slog.Logger) *Bot {
+// New creates a new Bot instance. store holds the history the charts are
+// drawn from; clk schedules the daily summary and bounds the history chart.
+func New(cfg BotConfig, collector *service.Collector, store storage.StatsStorage, authClient *auth.AuthClient, clk clock.Clock, logger *slog.Logger) *Bot {
 	return &Bot{
 		config:    cfg,
 		collector: collector,
+		store:     store,
 		auth:      authClient,
+		clock:     clk,
 		logger:    logger,
+		triage:    newTriageRegistry(),
 	}
 }
 
@@ -67,6 +82,8 @@ func (b *Bot) Run(ctx context.Context) error {
 	b.tgBot.RegisterHandler(bot.HandlerTypeMessageText, "oldest", bot.MatchTypeCommand, b.handleOldest)
 	b.tgBot.RegisterHandler(bot.HandlerTypeMessageText, "refresh", bot.MatchTypeCommand, b.handleRefresh)
 	b.tgBot.RegisterHandler(bot.HandlerTypeMessageText, "login", bot.MatchTypeCommand, b.handleLogin)
+	b.tgBot.RegisterHandler(bot.HandlerTypeMessageText, "add", bot.MatchTypeCommand, b.handleAdd)
+	b.tgBot.RegisterHandler(bot.HandlerTypeCallbackQueryData, triagePrefix, bot.MatchTypePrefix, b.handleTriage)
 
 	b.registerCommandMenu(ctx)
 
@@ -87,8 +104,9 @@ func (b *Bot) registerCommandMenu(ctx context.Context) {
 		{Command: "summary", Description: "Text summary + radar + history chart"},
 		{Command: "stats", Description: "Text summary only"},
 		{Command: "chart", Description: "Radar + history chart"},
-		{Command: "zombies", Description: "Zombie tasks (14+ days)"},
+		{Command: "zombies", Description: "Triage zombie tasks and worse"},
 		{Command: "oldest", Description: "Oldest task"},
+		{Command: "add", Description: "Add a task: /add title #list due:friday !high"},
 		{Command: "refresh", Description: "Force refresh from Microsoft Graph"},
 		{Command: "login", Description: "Authenticate with Microsoft"},
 	}
@@ -124,6 +142,9 @@ func (b *Bot) defaultHandler(ctx context.Context, tg *bot.Bot, update *models.Up
 		b.logger.Warn("unauthorized chat", slog.Int64("chat_id", update.Message.Chat.ID))
 		return
 	}
+	if update.Message.ForwardOrigin != nil {
+		b.handleForward(ctx, tg, update)
+	}
 }
 
 func (b *Bot) handleStats(ctx context.Context, tg *bot.Bot, update *models.Update) {
@@ -155,9 +176,10 @@ func (b *Bot) handleZombies(ctx context.Context, tg *bot.Bot, update *models.Upd
 		return
 	}
 
+	zombieLevel := data.Policy.ZombieLevel()
 	var zombies []todometrics.TaskRottennessInfo
 	for _, t := range data.SortedTasks {
-		if t.Rottenness == todometrics.ZombieTaskRottenness {
+		if t.Rottenness >= zombieLevel {
 			zombies = append(zombies, t)
 		}
 	}
@@ -169,18 +191,12 @@ func (b *Bot) handleZombies(ctx context.Context, tg *bot.Bot, update *models.Upd
 
 	var sb strings.Builder
 	sb.WriteString(warning)
-	sb.WriteString(fmt.Sprintf("<b>%s Zombie Tasks (%d)</b>\n\n", todometrics.TaskRottenness(todometrics.ZombieTaskRottenness).String(), len(zombies)))
-	for i, t := range zombies {
-		sb.WriteString(fmt.Sprintf("%d. <b>%s</b>\n   %s | %d days %s\n",
-			i+1,
-			escapeHTML(t.TaskName),
-			escapeHTML(t.TaskList),
-			t.Age,
-			t.Rottenness.String(),
-		))
-	}
-
+	level := data.Policy.Level(zombieLevel)
+	sb.WriteString(fmt.Sprintf("<b>%s %s Tasks (%d)</b>\n", level.Emoji, escapeHTML(level.Name), len(zombies)))
+	sb.WriteString(formatAgeMode(data.AgeMode))
 	b.sendReply(ctx, tg, update, sb.String())
+
+	b.sendZombieTriage(ctx, tg, update.Message.Chat.ID, data, zombies)
 }
 
 func (b *Bot) handleOldest(ctx context.Context, tg *bot.Bot, update *models.Update) {
@@ -206,12 +222,13 @@ func (b *Bot) handleOldest(ctx context.Context, tg *bot.Bot, update *models.Upda
 		"%s<b>Champion Procrastinator</b>\n\n"+
 			"<b>%s</b>\n"+
 			"List: %s\n"+
-			"Age: %d days %s",
+			"Age: %d days %s\n%s",
 		warning,
 		escapeHTML(c.TaskName),
 		escapeHTML(c.TaskList),
 		c.Age,
-		c.Rottenness.String(),
+		data.Policy.Level(c.Rottenness).Emoji,
+		formatAgeMode(data.AgeMode),
 	)
 	b.sendReply(ctx, tg, update, text)
 }
@@ -350,7 +367,7 @@ func (b *Bot) dailyScheduler(ctx context.Context) {
 		next := b.nextDailySummary()
 		b.logger.Info("next daily summary", slog.Time("at", next))
 
-		timer := time.NewTimer(time.Until(next))
+		timer := time.NewTimer(next.Sub(b.clock.Now()))
 		select {
 		case <-ctx.Done():
 			timer.Stop()
@@ -370,7 +387,7 @@ func (b *Bot) nextDailySummary() time.Time {
 		}
 	}
 
-	now := time.Now()
+	now := b.clock.Now()
 	next := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
 	if !next.After(now) {
 		next = next.Add(24 * time.Hour)
@@ -418,6 +435,9 @@ func (b *Bot) handleSummary(ctx context.Context, tg *bot.Bot, update *models.Upd
 func (b *Bot) formatStats(data *service.StatsData) string {
 	var sb strings.Builder
 	sb.WriteString(fmt.Sprintf("<b>Total: %d days, %d tasks</b>\n", data.TotalAge, data.TotalTasks))
+	sb.WriteString(formatAgeMode(data.AgeMode))
+	sb.WriteString(formatMissingLists(data.MissingLists))
+	sb.WriteString(formatExcludedLists(data.ExcludedLists))
 
 	// Build a map: list name → top 5 oldest tasks
 	tasksByList := make(map[string][]todometrics.TaskRottennessInfo)
@@ -428,18 +448,96 @@ func (b *Bot) formatStats(data *service.StatsData) string {
 	}
 
 	for _, la := range data.ListAges.Ages {
-		sb.WriteString(fmt.Sprintf("\n<b>%s</b> (%d days, %d tasks)\n",
-			escapeHTML(la.Title), la.Age, la.TaskCount))
+		excluded := ""
+		if la.Excluded {
+			excluded = ", not in total"
+		}
+		sb.WriteString(fmt.Sprintf("\n<b>%s</b> (%d days, %d tasks%s)\n",
+			escapeHTML(la.Title), la.Age, la.TaskCount, excluded))
 
 		tasks := tasksByList[la.Title]
 		for _, t := range tasks {
 			sb.WriteString(fmt.Sprintf("  %s %s — %d days\n",
-				t.Rottenness.String(),
+				data.Policy.Level(t.Rottenness).Emoji,
+				escapeHTML(taskTitle(t)),
+				t.Age))
+		}
+	}
+
+	if len(data.RecurringTasks) > 0 {
+		sb.WriteString(fmt.Sprintf("\n<b>🔁 Recurring</b> (%d tasks, days since last missed occurrence)\n", len(data.RecurringTasks)))
+		for _, t := range data.RecurringTasks[:min(5, len(data.RecurringTasks))] {
+			sb.WriteString(fmt.Sprintf("  %s %s — %d days\n",
+				data.Policy.Level(t.Rottenness).Emoji,
 				escapeHTML(t.TaskName),
 				t.Age))
 		}
 	}
 
+	if data.Throughput != nil {
+		sb.WriteString(formatThroughput(data.Throughput))
+	}
+
+	return sb.String()
+}
+
+// taskTitle marks recurring tasks so they stand out among one-off ones.
+func taskTitle(t todometrics.TaskRottennessInfo) string {
+	if t.Recurring {
+		return "🔁 " + t.TaskName
+	}
+	return t.TaskName
+}
+
+// formatAgeMode renders the line telling which days task ages count.
+func formatAgeMode(mode string) string {
+	if mode == "" {
+		return ""
+	}
+	return fmt.Sprintf("<i>Ages in %s</i>\n", escapeHTML(mode))
+}
+
+// formatMissingLists warns that the stats leave out lists that failed to fetch.
+func formatMissingLists(missing []storage.MissingList) string {
+	if len(missing) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(missing))
+	for _, m := range missing {
+		names = append(names, escapeHTML(m.Name))
+	}
+	return fmt.Sprintf("⚠️ <i>Missing lists (failed to fetch): %s</i>\n", strings.Join(names, ", "))
+}
+
+// formatExcludedLists names the lists the list filter left out.
+func formatExcludedLists(excluded []todoclient.ExcludedList) string {
+	if len(excluded) == 0 {
+		return ""
+	}
+	escaped := make([]string, 0, len(excluded))
+	for _, list := range excluded {
+		escaped = append(escaped, escapeHTML(list.Name))
+	}
+	return fmt.Sprintf("<i>Excluded lists: %s</i>\n", strings.Join(escaped, ", "))
+}
+
+// formatThroughput renders the completion section appended to the stats text.
+func formatThroughput(throughput *todometrics.Throughput) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("\n<b>Throughput since %s</b>\n", throughput.WindowStart.Format("2006-01-02")))
+	sb.WriteString(fmt.Sprintf("Queued %d, completed %d, net flow %+d\n",
+		throughput.Queued, throughput.Completed, throughput.NetFlow))
+
+	weeks := make([]string, 0, len(throughput.Weekly))
+	for _, w := range throughput.Weekly {
+		weeks = append(weeks, strconv.Itoa(w.Completed))
+	}
+	sb.WriteString(fmt.Sprintf("Weekly: %s\n", strings.Join(weeks, " · ")))
+
+	for _, c := range throughput.CycleTimes {
+		sb.WriteString(fmt.Sprintf("  %s — cycle %.1f days avg, %.1f median (%d done)\n",
+			escapeHTML(c.Title), c.AverageDays, c.MedianDays, c.Completed))
+	}
 	return sb.String()
 }
 
@@ -511,18 +609,8 @@ func (b *Bot) renderRadarChart(data *service.StatsData) ([]byte, error) {
 
 // renderHistoryChart generates a line chart PNG showing total age and per-group age over time.
 func (b *Bot) renderHistoryChart(ctx context.Context) ([]byte, error) {
-	dbPath, err := storage.DefaultDBPath()
-	if err != nil {
-		return nil, fmt.Errorf("get db path: %w", err)
-	}
-	store, err := storage.NewSQLiteStorage(dbPath)
-	if err != nil {
-		return nil, fmt.Errorf("open storage: %w", err)
-	}
-	defer store.Close()
-
-	cutoff := time.Now().AddDate(0, 0, -90)
-	snapshots, err := store.GetHistory(ctx, cutoff, time.Now())
+	now := b.clock.Now()
+	snapshots, err := b.store.GetHistory(ctx, now.AddDate(0, 0, -90), now)
 	if err != nil {
 		return nil, fmt.Errorf("query history: %w", err)
 	}
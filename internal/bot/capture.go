@@ -0,0 +1,167 @@
+package bot
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"github.com/uchr/ToDoInfo/internal/service"
+	"github.com/uchr/ToDoInfo/internal/todo"
+	"github.com/uchr/ToDoInfo/internal/todoclient"
+)
+
+// maxTitleLength keeps titles of forwarded messages short; the full text goes
+// into the task notes.
+const maxTitleLength = 200
+
+const addUsage = "Usage: /add &lt;title&gt; [#list] [due:friday] [!high]"
+
+// handleAdd creates a task from "/add <title> [#list] [due:...] [!high]".
+func (b *Bot) handleAdd(ctx context.Context, tg *bot.Bot, update *models.Update) {
+	if !b.authorizeChat(update) {
+		return
+	}
+
+	// Drop the command itself, which may carry the bot name: "/add@todoinfo_bot".
+	text := ""
+	if i := strings.IndexFunc(update.Message.Text, unicode.IsSpace); i >= 0 {
+		text = update.Message.Text[i:]
+	}
+	if strings.TrimSpace(text) == "" {
+		b.sendReply(ctx, tg, update, addUsage)
+		return
+	}
+	data, ok := b.captureData(ctx, tg, update)
+	if !ok {
+		return
+	}
+	add, err := todo.ParseQuickAdd(text, b.clock.Now(), knownLists(data))
+	if err != nil {
+		b.sendReply(ctx, tg, update, fmt.Sprintf("⚠️ %s\n%s", escapeHTML(err.Error()), addUsage))
+		return
+	}
+
+	task := todoclient.NewTask{Title: add.Title, DueDateTime: add.Due, Importance: add.Importance}
+	b.capture(ctx, tg, update, data, add.List, task)
+}
+
+// handleForward turns a forwarded message into a task in the inbox list:
+// its first line becomes the title and the whole text the notes.
+func (b *Bot) handleForward(ctx context.Context, tg *bot.Bot, update *models.Update) {
+	message := update.Message
+	text := strings.TrimSpace(cmp.Or(message.Text, message.Caption))
+	if text == "" {
+		b.sendReply(ctx, tg, update, "⚠️ Only forwarded text can become a task.")
+		return
+	}
+
+	title, _, _ := strings.Cut(text, "\n")
+	if runes := []rune(title); len(runes) > maxTitleLength {
+		title = string(runes[:maxTitleLength-1]) + "…"
+	}
+	notes := text
+	if from := forwardedFrom(message.ForwardOrigin); from != "" {
+		notes = fmt.Sprintf("Forwarded from %s:\n\n%s", from, text)
+	}
+
+	data, ok := b.captureData(ctx, tg, update)
+	if !ok {
+		return
+	}
+	task := todoclient.NewTask{Title: title, Body: &todo.TaskBody{Content: notes, ContentType: "text"}}
+	b.capture(ctx, tg, update, data, "", task)
+}
+
+// captureData returns the cached lists new tasks are matched against,
+// refreshing them if there are none yet. It replies itself when that fails.
+func (b *Bot) captureData(ctx context.Context, tg *bot.Bot, update *models.Update) (*service.StatsData, bool) {
+	data := b.collector.GetLatest()
+	if data == nil {
+		warning := b.ensureFresh(ctx)
+		if data = b.collector.GetLatest(); data == nil {
+			b.sendReply(ctx, tg, update, warning+"No data yet. Use /login first.")
+			return nil, false
+		}
+	}
+	return data, true
+}
+
+// capture creates task in the list matching listQuery, or in the inbox
+// list, and replies with where it landed.
+func (b *Bot) capture(ctx context.Context, tg *bot.Bot, update *models.Update, data *service.StatsData, listQuery string, task todoclient.NewTask) {
+	list, err := b.captureList(data, listQuery)
+	if err != nil {
+		b.sendReply(ctx, tg, update, "⚠️ "+escapeHTML(err.Error()))
+		return
+	}
+
+	created, err := b.collector.CreateTask(ctx, list.ID, task)
+	if err != nil {
+		b.logger.Error("failed to create task", slog.String("list", list.Name), slog.Any("error", err))
+		if isAuthError(err) {
+			b.sendReply(ctx, tg, update, "⚠️ Auth may have expired — use /login to reconnect.")
+			return
+		}
+		b.sendReply(ctx, tg, update, "⚠️ Failed to add the task: "+escapeHTML(err.Error()))
+		return
+	}
+
+	b.sendReply(ctx, tg, update, formatCreated(created, list))
+}
+
+// captureList resolves the list a new task goes to: the queried one, else
+// the configured inbox list, else the To Do default list.
+func (b *Bot) captureList(data *service.StatsData, query string) (todo.TaskList, error) {
+	if query != "" {
+		return findList(data, query)
+	}
+	if b.config.InboxList != "" {
+		list, err := findList(data, b.config.InboxList)
+		if err != nil {
+			return todo.TaskList{}, fmt.Errorf("%w, check --inbox-list", err)
+		}
+		return list, nil
+	}
+	for _, list := range knownLists(data) {
+		if list.WellknownListName == todoclient.WellknownDefaultList {
+			return list, nil
+		}
+	}
+	return todo.TaskList{}, fmt.Errorf("the default list wasn't found, set --inbox-list or use #list")
+}
+
+func formatCreated(task todo.Task, list todo.TaskList) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("✅ Added <b>%s</b> to <i>%s</i>", escapeHTML(task.Title), escapeHTML(list.Name)))
+	if task.DueDateTime != nil {
+		sb.WriteString("\n📅 Due " + task.DueDateTime.Time.Format("Mon 2 Jan"))
+	}
+	if task.Importance == todo.ImportanceHigh {
+		sb.WriteString("\n❗ High importance")
+	}
+	return sb.String()
+}
+
+// forwardedFrom names the original sender of a forwarded message.
+func forwardedFrom(origin *models.MessageOrigin) string {
+	switch {
+	case origin == nil:
+		return ""
+	case origin.MessageOriginUser != nil:
+		user := origin.MessageOriginUser.SenderUser
+		return strings.TrimSpace(user.FirstName + " " + user.LastName)
+	case origin.MessageOriginHiddenUser != nil:
+		return origin.MessageOriginHiddenUser.SenderUserName
+	case origin.MessageOriginChat != nil:
+		return origin.MessageOriginChat.SenderChat.Title
+	case origin.MessageOriginChannel != nil:
+		return origin.MessageOriginChannel.Chat.Title
+	}
+	return ""
+}
@@ -0,0 +1,287 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"github.com/uchr/ToDoInfo/internal/service"
+	"github.com/uchr/ToDoInfo/internal/todo"
+	"github.com/uchr/ToDoInfo/internal/todoclient"
+	"github.com/uchr/ToDoInfo/internal/todometrics"
+)
+
+// triagePrefix starts the callback data of the zombie triage buttons:
+// "zt:<key>:<action>". Graph IDs don't fit Telegram's 64 byte callback data,
+// so the buttons carry a short key into triageRegistry instead.
+const triagePrefix = "zt:"
+
+// maxTriageMessages caps how many zombies /zombies sends with buttons; the
+// rest are only counted, they show up again once the first ones are dealt with.
+const maxTriageMessages = 10
+
+// snoozeDays is how far the snooze button pushes the due date back.
+const snoozeDays = 7
+
+// triageErrorMark separates a zombie message from the error of a failed action.
+const triageErrorMark = "\n\n⚠️ "
+
+// Triage actions, as carried in the callback data.
+const (
+	triageDone   = "d"
+	triageSnooze = "s"
+	triageMove   = "m"
+	triageDelete = "x"
+)
+
+// triageTask identifies the task behind a set of triage buttons.
+type triageTask struct {
+	ListID   string
+	TaskID   string
+	Title    string
+	ListName string
+}
+
+// maxTriageEntries bounds the registry; the oldest keys are forgotten first
+// and their buttons answer with a hint to send /zombies again.
+const maxTriageEntries = 500
+
+// triageRegistry maps the short keys in callback data to tasks. It lives in
+// memory only, so buttons sent before a restart stop working.
+type triageRegistry struct {
+	mu      sync.Mutex
+	next    int64
+	entries map[string]triageTask
+	order   []string
+}
+
+func newTriageRegistry() *triageRegistry {
+	return &triageRegistry{entries: make(map[string]triageTask)}
+}
+
+// add stores task and returns its key.
+func (r *triageRegistry) add(task triageTask) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.next++
+	key := strconv.FormatInt(r.next, 36)
+	r.entries[key] = task
+	r.order = append(r.order, key)
+	for len(r.order) > maxTriageEntries {
+		delete(r.entries, r.order[0])
+		r.order = r.order[1:]
+	}
+	return key
+}
+
+// take looks key up and forgets it in one step, so that of two taps on the
+// same buttons only the first one acts.
+func (r *triageRegistry) take(key string) (triageTask, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	task, ok := r.entries[key]
+	delete(r.entries, key)
+	return task, ok
+}
+
+// restore puts back a key taken for an action that changed nothing, so its
+// buttons can retry. Keys evicted in the meantime stay forgotten.
+func (r *triageRegistry) restore(key string, task triageTask) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if slices.Contains(r.order, key) {
+		r.entries[key] = task
+	}
+}
+
+// sendZombieTriage sends one message per zombie with triage buttons.
+func (b *Bot) sendZombieTriage(ctx context.Context, tg *bot.Bot, chatID int64, data *service.StatsData, zombies []todometrics.TaskRottennessInfo) {
+	for i, t := range zombies {
+		if i == maxTriageMessages {
+			b.sendTo(ctx, chatID, fmt.Sprintf("<i>%d more — deal with these first, then send /zombies again.</i>", len(zombies)-i))
+			return
+		}
+
+		key := b.triage.add(triageTask{ListID: t.ListID, TaskID: t.TaskID, Title: t.TaskName, ListName: t.TaskList})
+		_, err := tg.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:      chatID,
+			Text:        formatZombie(data, t),
+			ParseMode:   models.ParseModeHTML,
+			ReplyMarkup: triageKeyboard(key, b.config.SomedayList),
+		})
+		if err != nil {
+			b.logger.Error("failed to send zombie", slog.Any("error", err))
+			return
+		}
+	}
+}
+
+func formatZombie(data *service.StatsData, t todometrics.TaskRottennessInfo) string {
+	return fmt.Sprintf("<b>%s</b>\n%s | %d days %s",
+		escapeHTML(taskTitle(t)),
+		escapeHTML(t.TaskList),
+		t.Age,
+		data.Policy.Level(t.Rottenness).Emoji,
+	)
+}
+
+func triageKeyboard(key, somedayList string) *models.InlineKeyboardMarkup {
+	button := func(text, action string) models.InlineKeyboardButton {
+		return models.InlineKeyboardButton{Text: text, CallbackData: triagePrefix + key + ":" + action}
+	}
+	return &models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{
+		{button("✅ Done", triageDone), button(fmt.Sprintf("⏰ Snooze %dd", snoozeDays), triageSnooze)},
+		{button("📦 Move to "+somedayList, triageMove), button("🗑 Delete", triageDelete)},
+	}}
+}
+
+// handleTriage carries out a triage button: it changes the task in Graph,
+// edits the zombie message to show the outcome and invalidates the cache.
+func (b *Bot) handleTriage(ctx context.Context, tg *bot.Bot, update *models.Update) {
+	query := update.CallbackQuery
+	message := query.Message.Message
+	if message == nil || message.Chat.ID != b.config.ChatID {
+		return
+	}
+
+	key, action, _ := strings.Cut(strings.TrimPrefix(query.Data, triagePrefix), ":")
+	task, ok := b.triage.take(key)
+	if !ok {
+		b.answerCallback(ctx, tg, query, "These buttons have expired, send /zombies again.")
+		return
+	}
+	b.answerCallback(ctx, tg, query, "")
+
+	done, applied, err := b.applyTriage(ctx, task, action)
+	if err != nil {
+		b.logger.Warn("zombie triage failed", slog.String("task", task.Title), slog.String("action", action), slog.Any("error", err))
+		// Keep the buttons so the action can be retried, replacing any
+		// earlier error, unless part of it went through: retrying a half
+		// done move would copy the task again.
+		retry := applied == 0
+		if retry {
+			b.triage.restore(key, task)
+		}
+		text, _, _ := strings.Cut(message.Text, triageErrorMark)
+		b.editTriageMessage(ctx, tg, message, escapeHTML(text)+triageErrorMark+escapeHTML(err.Error()), retry, key)
+		return
+	}
+
+	b.editTriageMessage(ctx, tg, message, fmt.Sprintf("<s>%s</s>\n%s", escapeHTML(task.Title), done), false, key)
+}
+
+// applyTriage sends the Graph requests for action and describes the result,
+// along with how many requests succeeded. The task is looked up in the
+// cached lists, which hold the due date the snooze request needs. They come
+// from delta syncs without checklist items and linked resources, so a move
+// fetches the whole task first.
+func (b *Bot) applyTriage(ctx context.Context, task triageTask, action string) (string, int, error) {
+	data := b.collector.GetLatest()
+	if data == nil {
+		return "", 0, fmt.Errorf("no data yet, use /login first")
+	}
+	ref, ok := todo.FindTask(data.TaskLists, task.ListID, task.TaskID)
+	if !ok {
+		return "", 0, fmt.Errorf("%q is no longer open in %s", task.Title, task.ListName)
+	}
+	list, current := ref.List, ref.Task
+
+	parser := b.collector.Parser()
+	var (
+		requests []todoclient.WriteRequest
+		done     string
+	)
+	switch action {
+	case triageDone:
+		requests = append(requests, parser.CompleteRequest(list.ID, current.ID))
+		done = "✅ Completed"
+	case triageSnooze:
+		requests = append(requests, parser.SnoozeRequest(list.ID, current, snoozeDays, b.clock.Now()))
+		done = fmt.Sprintf("⏰ Snoozed for %d days", snoozeDays)
+	case triageMove:
+		target, err := b.somedayList(data)
+		if err != nil {
+			return "", 0, err
+		}
+		if target.ID == list.ID {
+			return "", 0, fmt.Errorf("%q is already in %s", task.Title, target.Name)
+		}
+		full, err := b.collector.GetTask(ctx, list.ID, current.ID)
+		if err != nil {
+			return "", 0, err
+		}
+		requests = parser.MoveRequests(list.ID, full, target.ID)
+		done = "📦 Moved to " + escapeHTML(target.Name)
+	case triageDelete:
+		requests = append(requests, parser.DeleteRequest(list.ID, current.ID))
+		done = "🗑 Deleted"
+	default:
+		return "", 0, fmt.Errorf("unknown action %q", action)
+	}
+
+	applied, err := b.collector.Apply(ctx, requests...)
+	if err != nil {
+		return "", applied, err
+	}
+	return done, applied, nil
+}
+
+// somedayList resolves the configured Someday list by name or ID.
+func (b *Bot) somedayList(data *service.StatsData) (todo.TaskList, error) {
+	list, err := findList(data, b.config.SomedayList)
+	if err != nil {
+		return todo.TaskList{}, fmt.Errorf("%w, check --someday-list", err)
+	}
+	return list, nil
+}
+
+// knownLists returns every list of the last refresh. The list filter usually
+// leaves lists such as Someday or Tasks out of the stats, so excluded lists
+// count too; they come without tasks.
+func knownLists(data *service.StatsData) []todo.TaskList {
+	lists := append([]todo.TaskList(nil), data.TaskLists...)
+	for _, excluded := range data.ExcludedLists {
+		lists = append(lists, todo.TaskList{ID: excluded.ID, Name: excluded.Name, WellknownListName: excluded.WellknownListName})
+	}
+	return lists
+}
+
+// findList resolves a list name or ID among the known lists.
+func findList(data *service.StatsData, query string) (todo.TaskList, error) {
+	return todo.FindList(knownLists(data), query)
+}
+
+func (b *Bot) answerCallback(ctx context.Context, tg *bot.Bot, query *models.CallbackQuery, text string) {
+	_, err := tg.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+		CallbackQueryID: query.ID,
+		Text:            text,
+	})
+	if err != nil {
+		b.logger.Warn("failed to answer callback query", slog.Any("error", err))
+	}
+}
+
+// editTriageMessage replaces the zombie message text, keeping the buttons
+// only if keepButtons is set.
+func (b *Bot) editTriageMessage(ctx context.Context, tg *bot.Bot, message *models.Message, text string, keepButtons bool, key string) {
+	params := &bot.EditMessageTextParams{
+		ChatID:    message.Chat.ID,
+		MessageID: message.ID,
+		Text:      text,
+		ParseMode: models.ParseModeHTML,
+	}
+	if keepButtons {
+		params.ReplyMarkup = triageKeyboard(key, b.config.SomedayList)
+	}
+	if _, err := tg.EditMessageText(ctx, params); err != nil {
+		b.logger.Error("failed to edit zombie message", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,277 @@
+package cleanup
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/uchr/ToDoInfo/internal/clock"
+	"github.com/uchr/ToDoInfo/internal/todo"
+	"github.com/uchr/ToDoInfo/internal/todoclient"
+)
+
+// LogEntry records one change made by Apply or Undo. Undo needs the due
+// date before a snooze and the list and ID a task was moved to.
+type LogEntry struct {
+	Time     time.Time `json:"time"`
+	Action   Action    `json:"action"`
+	ListID   string    `json:"list_id"`
+	ListName string    `json:"list_name,omitempty"`
+	TaskID   string    `json:"task_id"`
+	Title    string    `json:"title"`
+	// DueBefore is nil when the task had no due date.
+	DueBefore  *todo.DateTimeTimeZone `json:"due_before,omitempty"`
+	DueAfter   *todo.DateTimeTimeZone `json:"due_after,omitempty"`
+	ToListID   string                 `json:"to_list_id,omitempty"`
+	ToListName string                 `json:"to_list_name,omitempty"`
+	NewTaskID  string                 `json:"new_task_id,omitempty"`
+	// Error is set when the change failed. Only a move can fail half way,
+	// leaving the copy NewTaskID next to the original.
+	Error string `json:"error,omitempty"`
+}
+
+// ReadLog parses a JSONL change log.
+func ReadLog(r io.Reader) ([]LogEntry, error) {
+	var entries []LogEntry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry LogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("log line %d: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// Target is where changes are carried out: a Graph client and token, and the
+// open tasks of every list, which snoozes and moves start from.
+type Target struct {
+	Parser *todoclient.TodoParser
+	Logger *slog.Logger
+	Token  string
+	Lists  []todo.TaskList
+	Clock  clock.Clock
+	// Log receives a JSON line per change, written as soon as it is made.
+	Log io.Writer
+}
+
+// Options fill in what plan entries leave out.
+type Options struct {
+	SnoozeDays int
+	// MoveTo is the default move target, a list name or ID.
+	MoveTo string
+}
+
+// Summary counts the outcome of Apply or Undo.
+type Summary struct {
+	Changed int
+	Kept    int
+	Failed  int
+	// Skipped counts log entries Undo can't reverse, such as deletions.
+	Skipped int
+}
+
+// Apply carries out plan. A task that fails is logged and counted, and the
+// rest of the plan still runs; the error is only for a log that can't be
+// written or a cancelled context.
+func Apply(ctx context.Context, target Target, plan Plan, opts Options) (Summary, error) {
+	var summary Summary
+	for _, entry := range plan.Tasks {
+		if entry.Action == ActionKeep {
+			summary.Kept++
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return summary, err
+		}
+
+		logEntry := target.apply(ctx, entry, opts)
+		if logEntry.Error != "" {
+			summary.Failed++
+		} else {
+			summary.Changed++
+		}
+		if err := target.write(logEntry); err != nil {
+			return summary, err
+		}
+	}
+	return summary, nil
+}
+
+func (t Target) apply(ctx context.Context, entry Entry, opts Options) LogEntry {
+	logEntry := LogEntry{
+		Time:     t.Clock.Now(),
+		Action:   entry.Action,
+		ListID:   entry.ListID,
+		ListName: entry.List,
+		TaskID:   entry.TaskID,
+		Title:    entry.Title,
+	}
+	ref, ok := todo.FindTask(t.Lists, entry.ListID, entry.TaskID)
+	if !ok {
+		logEntry.Error = "task is no longer open"
+		return logEntry
+	}
+	list, task := ref.List, ref.Task
+	logEntry.ListName = list.Name
+	logEntry.Title = task.Title
+
+	var requests []todoclient.WriteRequest
+	switch entry.Action {
+	case ActionComplete:
+		requests = append(requests, t.Parser.CompleteRequest(list.ID, task.ID))
+	case ActionDelete:
+		requests = append(requests, t.Parser.DeleteRequest(list.ID, task.ID))
+	case ActionSnooze:
+		days := cmp.Or(entry.Days, opts.SnoozeDays)
+		if days <= 0 {
+			logEntry.Error = "no snooze length, set days or --days"
+			return logEntry
+		}
+		logEntry.DueBefore = task.DueDateTime
+		requests = append(requests, t.Parser.SnoozeRequest(list.ID, task, days, t.Clock.Now()))
+	case ActionMove:
+		target, err := findList(t.Lists, cmp.Or(entry.To, opts.MoveTo))
+		if err != nil {
+			logEntry.Error = err.Error()
+			return logEntry
+		}
+		if target.ID == list.ID {
+			logEntry.Error = "task is already in " + target.Name
+			return logEntry
+		}
+		logEntry.ToListID = target.ID
+		logEntry.ToListName = target.Name
+		requests = t.Parser.MoveRequests(list.ID, task, target.ID)
+	}
+
+	responses, err := t.Parser.Apply(ctx, t.Logger, t.Token, requests...)
+	// Snooze answers with the patched task, move with the copy it created;
+	// the copy is logged even if deleting the original failed.
+	if len(responses) > 0 {
+		switch entry.Action {
+		case ActionSnooze:
+			logEntry.DueAfter = responseTask(responses[0]).DueDateTime
+		case ActionMove:
+			logEntry.NewTaskID = responseTask(responses[0]).ID
+		}
+	}
+	if err != nil {
+		logEntry.Error = err.Error()
+	}
+	return logEntry
+}
+
+// Undo reverses the changes in a log, newest first: snoozes get their old due
+// date back, moved tasks are moved back and completed tasks are reopened.
+// Deletions and failed changes are skipped. Undo logs its own changes in the
+// same format, so its log can be undone in turn.
+func Undo(ctx context.Context, target Target, entries []LogEntry) (Summary, error) {
+	var summary Summary
+	for _, entry := range slices.Backward(entries) {
+		if entry.Error != "" || entry.Action == ActionDelete {
+			summary.Skipped++
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return summary, err
+		}
+
+		logEntry := target.undo(ctx, entry)
+		if logEntry.Error != "" {
+			summary.Failed++
+		} else {
+			summary.Changed++
+		}
+		if err := target.write(logEntry); err != nil {
+			return summary, err
+		}
+	}
+	return summary, nil
+}
+
+func (t Target) undo(ctx context.Context, entry LogEntry) LogEntry {
+	logEntry := LogEntry{
+		Time:     t.Clock.Now(),
+		ListID:   entry.ListID,
+		ListName: entry.ListName,
+		TaskID:   entry.TaskID,
+		Title:    entry.Title,
+	}
+
+	var requests []todoclient.WriteRequest
+	switch entry.Action {
+	case ActionComplete:
+		logEntry.Action = ActionReopen
+		requests = append(requests, t.Parser.ReopenRequest(entry.ListID, entry.TaskID))
+	case ActionReopen:
+		logEntry.Action = ActionComplete
+		requests = append(requests, t.Parser.CompleteRequest(entry.ListID, entry.TaskID))
+	case ActionSnooze:
+		logEntry.Action = ActionSnooze
+		logEntry.DueBefore = entry.DueAfter
+		logEntry.DueAfter = entry.DueBefore
+		requests = append(requests, t.Parser.DueRequest(entry.ListID, entry.TaskID, entry.DueBefore))
+	case ActionMove:
+		// The moved copy goes back where the task came from.
+		logEntry.Action = ActionMove
+		logEntry.ListID, logEntry.ListName, logEntry.TaskID = entry.ToListID, entry.ToListName, entry.NewTaskID
+		logEntry.ToListID, logEntry.ToListName = entry.ListID, entry.ListName
+		ref, ok := todo.FindTask(t.Lists, entry.ToListID, entry.NewTaskID)
+		if !ok {
+			logEntry.Error = "moved task is no longer open"
+			return logEntry
+		}
+		requests = t.Parser.MoveRequests(entry.ToListID, ref.Task, entry.ListID)
+	default:
+		logEntry.Action = entry.Action
+		logEntry.Error = fmt.Sprintf("can't undo %q", entry.Action)
+		return logEntry
+	}
+
+	responses, err := t.Parser.Apply(ctx, t.Logger, t.Token, requests...)
+	if entry.Action == ActionMove && len(responses) > 0 {
+		logEntry.NewTaskID = responseTask(responses[0]).ID
+	}
+	if err != nil {
+		logEntry.Error = err.Error()
+	}
+	return logEntry
+}
+
+func (t Target) write(entry LogEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("marshal log entry: %w", err)
+	}
+	if _, err := t.Log.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write log: %w", err)
+	}
+	return nil
+}
+
+// responseTask parses a task returned by Graph; a body that doesn't parse
+// yields an empty task, which only loses the detail needed for undo.
+func responseTask(body []byte) todo.Task {
+	var task todo.Task
+	_ = json.Unmarshal(body, &task)
+	return task
+}
+
+func findList(lists []todo.TaskList, query string) (todo.TaskList, error) {
+	if query == "" {
+		return todo.TaskList{}, fmt.Errorf("no move target, set to or --to")
+	}
+	return todo.FindList(lists, query)
+}
@@ -0,0 +1,103 @@
+package cleanup
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uchr/ToDoInfo/internal/clock"
+	"github.com/uchr/ToDoInfo/internal/graphfake"
+	"github.com/uchr/ToDoInfo/internal/todo"
+	"github.com/uchr/ToDoInfo/internal/todoclient"
+)
+
+// newTestTarget fetches every list of server and logs changes to log.
+func newTestTarget(t *testing.T, server *graphfake.Server, log io.Writer) Target {
+	t.Helper()
+	parser := todoclient.New(todoclient.DefaultConfig().
+		WithBaseURL(server.URL()).
+		WithHTTPClient(server.HTTPClient()).
+		WithListFilter(todoclient.ListFilter{Include: []string{"*"}}))
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	result, err := parser.GetTasks(t.Context(), logger, graphfake.Token)
+	require.NoError(t, err)
+	return Target{
+		Parser: parser,
+		Logger: logger,
+		Token:  graphfake.Token,
+		Lists:  result.TaskLists,
+		Clock:  clock.NewFixed(testNow),
+		Log:    log,
+	}
+}
+
+func TestApplyAndUndo(t *testing.T) {
+	server := graphfake.New(t, "basic")
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	due := &todo.DateTimeTimeZone{Time: time.Date(2026, 10, 20, 0, 0, 0, 0, berlin), TimeZone: "W. Europe Standard Time"}
+	server.PutTask("work", todo.Task{ID: "w-due", Status: todo.StatusNotStarted, Title: "Renew passport", DueDateTime: due})
+
+	plan := Plan{Tasks: []Entry{
+		{Action: ActionKeep, ListID: "work", TaskID: "w-1"},
+		{Action: ActionComplete, ListID: "work", TaskID: "w-2"},
+		{Action: ActionSnooze, ListID: "work", TaskID: "w-due"},
+		{Action: ActionSnooze, ListID: "home", TaskID: "h-2", Days: 3},
+		{Action: ActionMove, ListID: "home", TaskID: "h-1"},
+		{Action: ActionDelete, ListID: "work", TaskID: "w-3"},
+		{Action: ActionComplete, ListID: "work", TaskID: "w-4"},
+	}}
+	var log bytes.Buffer
+	summary, err := Apply(t.Context(), newTestTarget(t, server, &log), plan, Options{SnoozeDays: 7, MoveTo: "fam"})
+	require.NoError(t, err)
+	assert.Equal(t, Summary{Changed: 5, Kept: 1, Failed: 1}, summary)
+
+	completed, _ := server.Task("work", "w-2")
+	assert.Equal(t, todo.StatusCompleted, completed.Status)
+	snoozed, _ := server.Task("work", "w-due")
+	assert.True(t, due.Time.AddDate(0, 0, 7).Equal(snoozed.DueDateTime.Time))
+	_, ok := server.Task("home", "h-1")
+	assert.False(t, ok, "moved away")
+	_, ok = server.Task("work", "w-3")
+	assert.False(t, ok, "deleted")
+
+	entries, err := ReadLog(&log)
+	require.NoError(t, err)
+	require.Len(t, entries, 6)
+	assert.Equal(t, "task is no longer open", entries[5].Error, "w-4 was already completed")
+	move := entries[3]
+	assert.Equal(t, "family", move.ToListID)
+	require.NotEmpty(t, move.NewTaskID)
+	assert.Equal(t, "Fix the fence", server.Tasks("family")[1].Title)
+
+	// Undo runs against the lists as they are now.
+	var undoLog bytes.Buffer
+	summary, err = Undo(t.Context(), newTestTarget(t, server, &undoLog), entries)
+	require.NoError(t, err)
+	assert.Equal(t, Summary{Changed: 4, Skipped: 2}, summary)
+
+	reopened, _ := server.Task("work", "w-2")
+	assert.Equal(t, todo.StatusNotStarted, reopened.Status)
+	restored, _ := server.Task("work", "w-due")
+	assert.True(t, due.Time.Equal(restored.DueDateTime.Time))
+	assert.Equal(t, "W. Europe Standard Time", restored.DueDateTime.TimeZone)
+	unsnoozed, _ := server.Task("home", "h-2")
+	assert.Nil(t, unsnoozed.DueDateTime, "a task without a due date loses the snoozed one")
+	_, ok = server.Task("family", move.NewTaskID)
+	assert.False(t, ok)
+	home := server.Tasks("home")
+	assert.Equal(t, "Fix the fence", home[len(home)-1].Title)
+
+	undone, err := ReadLog(&undoLog)
+	require.NoError(t, err)
+	require.Len(t, undone, 4)
+	assert.Equal(t, ActionMove, undone[0].Action)
+	assert.Equal(t, "home", undone[0].ToListID)
+	assert.Equal(t, ActionReopen, undone[3].Action)
+}
@@ -0,0 +1,289 @@
+package cleanup
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+
+	"github.com/uchr/ToDoInfo/internal/todo"
+	"github.com/uchr/ToDoInfo/internal/todometrics"
+)
+
+// Action is what a plan does with a task.
+type Action string
+
+const (
+	ActionKeep     Action = "keep"
+	ActionComplete Action = "complete"
+	ActionSnooze   Action = "snooze"
+	ActionMove     Action = "move"
+	ActionDelete   Action = "delete"
+	// ActionReopen only appears in logs, when a completion is undone.
+	ActionReopen Action = "reopen"
+)
+
+// planActions are the actions a plan may ask for.
+var planActions = []Action{ActionKeep, ActionComplete, ActionSnooze, ActionMove, ActionDelete}
+
+// ParseAction checks that name is a plan action.
+func ParseAction(name string) (Action, error) {
+	for _, action := range planActions {
+		if strings.EqualFold(name, string(action)) {
+			return action, nil
+		}
+	}
+	return "", fmt.Errorf("unknown action %q, use one of keep, complete, snooze, move or delete", name)
+}
+
+// Entry is one task in a plan. Title, List, Age and Level are for the
+// reviewer; ListID and TaskID identify the task.
+type Entry struct {
+	Action Action `yaml:"action"`
+	// Days overrides the snooze length for this task.
+	Days int `yaml:"days,omitempty"`
+	// To overrides the move target for this task, a list name or ID.
+	To     string `yaml:"to,omitempty"`
+	Title  string `yaml:"title"`
+	List   string `yaml:"list"`
+	Age    int    `yaml:"age"`
+	Level  string `yaml:"level"`
+	ListID string `yaml:"list_id"`
+	TaskID string `yaml:"task_id"`
+}
+
+// Plan is the reviewable list of changes written by `todoinfo cleanup`.
+type Plan struct {
+	// Snapshot is when the tasks were fetched.
+	Snapshot time.Time `yaml:"snapshot"`
+	Tasks    []Entry   `yaml:"tasks"`
+}
+
+// Filter selects the tasks a plan covers. Zero fields select everything.
+type Filter struct {
+	// MinLevel is the least rotten level included.
+	MinLevel todometrics.TaskRottenness
+	// Lists are list name globs; a task must be in a matching list.
+	Lists  []string
+	MinAge int
+}
+
+func (f Filter) matches(task todometrics.TaskRottennessInfo) bool {
+	if task.Rottenness < f.MinLevel || task.Age < f.MinAge {
+		return false
+	}
+	if len(f.Lists) == 0 {
+		return true
+	}
+	for _, glob := range f.Lists {
+		if todo.MatchName(glob, task.TaskList) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewPlan selects the tasks of metrics that pass filter, oldest first, and
+// gives each of them action.
+func NewPlan(metrics *todometrics.Metrics, filter Filter, action Action) Plan {
+	plan := Plan{Snapshot: metrics.Now()}
+	policy := metrics.Policy()
+	for _, task := range metrics.GetSortedTasks() {
+		if !filter.matches(task) {
+			continue
+		}
+		plan.Tasks = append(plan.Tasks, Entry{
+			Action: action,
+			Title:  task.TaskName,
+			List:   task.TaskList,
+			Age:    task.Age,
+			Level:  policy.Level(task.Rottenness).Name,
+			ListID: task.ListID,
+			TaskID: task.TaskID,
+		})
+	}
+	return plan
+}
+
+// Format is a plan file format.
+type Format string
+
+const (
+	FormatYAML Format = "yaml"
+	FormatTSV  Format = "tsv"
+)
+
+// FormatFromPath picks the plan format from the file extension.
+func FormatFromPath(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML, nil
+	case ".tsv", ".txt":
+		return FormatTSV, nil
+	}
+	return "", fmt.Errorf("can't tell the plan format of %q, use a .yaml or .tsv file", path)
+}
+
+const planHeader = `Cleanup plan. Set the action of each task, then run
+  todoinfo cleanup apply <this file>
+Actions: keep, complete, snooze, move, delete.
+snooze pushes the due date back by days (default --days), move recreates the
+task in the list given by to (default --to). Every change is logged so
+snoozes, moves and completions can be undone.`
+
+// Write writes plan in format.
+func Write(w io.Writer, plan Plan, format Format) error {
+	var header strings.Builder
+	for line := range strings.Lines(planHeader) {
+		header.WriteString("# " + line)
+	}
+	header.WriteString("\n")
+
+	if format == FormatYAML {
+		body, err := yaml.Marshal(plan)
+		if err != nil {
+			return fmt.Errorf("marshal plan: %w", err)
+		}
+		_, err = io.WriteString(w, header.String()+"\n"+string(body))
+		return err
+	}
+
+	if _, err := fmt.Fprintf(w, "%s# snapshot: %s\n", header.String(), plan.Snapshot.Format(time.RFC3339)); err != nil {
+		return err
+	}
+	tsv := csv.NewWriter(w)
+	tsv.Comma = '\t'
+	if err := tsv.Write(tsvColumns); err != nil {
+		return err
+	}
+	for _, entry := range plan.Tasks {
+		days := ""
+		if entry.Days != 0 {
+			days = strconv.Itoa(entry.Days)
+		}
+		if err := tsv.Write([]string{
+			string(entry.Action), days, entry.To, entry.Level, strconv.Itoa(entry.Age),
+			oneLine(entry.List), oneLine(entry.Title), entry.ListID, entry.TaskID,
+		}); err != nil {
+			return err
+		}
+	}
+	tsv.Flush()
+	return tsv.Error()
+}
+
+var tsvColumns = []string{"action", "days", "to", "level", "age", "list", "title", "list_id", "task_id"}
+
+// oneLine keeps titles from breaking TSV rows.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// Read parses a plan in format and checks every action.
+func Read(r io.Reader, format Format) (Plan, error) {
+	var (
+		plan Plan
+		err  error
+	)
+	if format == FormatYAML {
+		err = yaml.NewDecoder(r).Decode(&plan)
+		if err == io.EOF {
+			err = nil
+		}
+	} else {
+		plan, err = readTSV(r)
+	}
+	if err != nil {
+		return Plan{}, fmt.Errorf("parse plan: %w", err)
+	}
+
+	for i, entry := range plan.Tasks {
+		action, err := ParseAction(string(entry.Action))
+		if err != nil {
+			return Plan{}, fmt.Errorf("task %d (%s): %w", i+1, entry.Title, err)
+		}
+		if entry.ListID == "" || entry.TaskID == "" {
+			return Plan{}, fmt.Errorf("task %d (%s): list_id and task_id are required", i+1, entry.Title)
+		}
+		if entry.Days < 0 {
+			return Plan{}, fmt.Errorf("task %d (%s): days must be positive, got %d", i+1, entry.Title, entry.Days)
+		}
+		plan.Tasks[i].Action = action
+	}
+	return plan, nil
+}
+
+// readTSV reads rows by column name, so columns may be reordered or dropped;
+// only action, list_id and task_id are required.
+func readTSV(r io.Reader) (Plan, error) {
+	var (
+		plan    Plan
+		content strings.Builder
+	)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if comment, ok := strings.CutPrefix(line, "#"); ok {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(comment), "snapshot:"); ok {
+				plan.Snapshot, _ = time.Parse(time.RFC3339, strings.TrimSpace(value))
+			}
+			continue
+		}
+		content.WriteString(line + "\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return Plan{}, err
+	}
+
+	tsv := csv.NewReader(strings.NewReader(content.String()))
+	tsv.Comma = '\t'
+	tsv.FieldsPerRecord = -1
+	tsv.LazyQuotes = true
+	rows, err := tsv.ReadAll()
+	if err != nil || len(rows) == 0 {
+		return plan, err
+	}
+
+	columns := map[string]int{}
+	for i, name := range rows[0] {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, required := range []string{"action", "list_id", "task_id"} {
+		if _, ok := columns[required]; !ok {
+			return Plan{}, fmt.Errorf("missing column %q", required)
+		}
+	}
+
+	for n, row := range rows[1:] {
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[i])
+		}
+		entry := Entry{
+			Action: Action(field("action")),
+			To:     field("to"),
+			Title:  field("title"),
+			List:   field("list"),
+			Level:  field("level"),
+			ListID: field("list_id"),
+			TaskID: field("task_id"),
+		}
+		if days := field("days"); days != "" {
+			if entry.Days, err = strconv.Atoi(days); err != nil {
+				return Plan{}, fmt.Errorf("task %d: invalid days %q", n+1, days)
+			}
+		}
+		entry.Age, _ = strconv.Atoi(field("age"))
+		plan.Tasks = append(plan.Tasks, entry)
+	}
+	return plan, nil
+}
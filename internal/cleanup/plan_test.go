@@ -0,0 +1,80 @@
+package cleanup
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uchr/ToDoInfo/internal/clock"
+	"github.com/uchr/ToDoInfo/internal/todo"
+	"github.com/uchr/ToDoInfo/internal/todometrics"
+)
+
+var testNow = time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+
+func testMetrics() *todometrics.Metrics {
+	task := func(id, title string, age int) todo.Task {
+		return todo.Task{ID: id, Title: title, Status: todo.StatusNotStarted, CreatedDateTime: testNow.AddDate(0, 0, -age)}
+	}
+	lists := []todo.TaskList{
+		{ID: "work", Name: "Work", Tasks: []todo.Task{task("w-1", "Write report", 40), task("w-2", "Book\tflights", 3)}},
+		{ID: "home", Name: "Home", Tasks: []todo.Task{task("h-1", "Fix the fence", 60)}},
+	}
+	return todometrics.New(lists, todometrics.WithClock(clock.NewFixed(testNow)))
+}
+
+func TestNewPlanFilters(t *testing.T) {
+	metrics := testMetrics()
+	zombie := metrics.Policy().ZombieLevel()
+
+	plan := NewPlan(metrics, Filter{MinLevel: zombie}, ActionKeep)
+	require.Len(t, plan.Tasks, 2)
+	assert.Equal(t, "h-1", plan.Tasks[0].TaskID, "oldest first")
+	assert.Equal(t, "Zombie", plan.Tasks[0].Level)
+	assert.Equal(t, testNow, plan.Snapshot)
+
+	plan = NewPlan(metrics, Filter{Lists: []string{"wo*"}, MinAge: 2}, ActionSnooze)
+	require.Len(t, plan.Tasks, 2)
+	assert.Equal(t, "Write report", plan.Tasks[0].Title)
+	assert.Equal(t, ActionSnooze, plan.Tasks[1].Action)
+}
+
+func TestPlanRoundTrip(t *testing.T) {
+	plan := NewPlan(testMetrics(), Filter{}, ActionKeep)
+	plan.Tasks[0].Action = ActionMove
+	plan.Tasks[0].To = "Someday"
+	plan.Tasks[1].Action = ActionSnooze
+	plan.Tasks[1].Days = 14
+
+	for _, format := range []Format{FormatYAML, FormatTSV} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, Write(&buf, plan, format))
+			assert.True(t, strings.HasPrefix(buf.String(), "# Cleanup plan."))
+
+			read, err := Read(&buf, format)
+			require.NoError(t, err)
+			assert.True(t, plan.Snapshot.Equal(read.Snapshot))
+			require.Len(t, read.Tasks, len(plan.Tasks))
+			assert.Equal(t, plan.Tasks[:2], read.Tasks[:2])
+			if format == FormatTSV {
+				assert.Equal(t, "Book flights", read.Tasks[2].Title, "tabs in titles become spaces")
+			}
+		})
+	}
+}
+
+func TestReadRejectsUnknownAction(t *testing.T) {
+	tsv := "action\tlist_id\ttask_id\nComplete\twork\tw-1\narchive\twork\tw-2\n"
+
+	_, err := Read(strings.NewReader(tsv), FormatTSV)
+	assert.ErrorContains(t, err, `task 2 (): unknown action "archive"`)
+
+	plan, err := Read(strings.NewReader("action\tlist_id\ttask_id\nComplete\twork\tw-1\n"), FormatTSV)
+	require.NoError(t, err)
+	assert.Equal(t, ActionComplete, plan.Tasks[0].Action, "actions ignore case")
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/uchr/ToDoInfo/internal/auth"
 	tgbot "github.com/uchr/ToDoInfo/internal/bot"
+	"github.com/uchr/ToDoInfo/internal/clock"
 	"github.com/uchr/ToDoInfo/internal/service"
 )
 
@@ -25,10 +26,13 @@ from Microsoft Graph and provides on-demand queries + daily summaries.
 Commands available in the bot:
   /login   - Authenticate via device code flow
   /stats   - Show task statistics summary
-  /zombies - List all zombie tasks (14+ days old)
+  /add     - Add a task: /add <title> [#list] [due:friday] [!high]
+  /zombies - Triage zombie tasks: complete, snooze, move or delete them
   /oldest  - Show the oldest task
   /chart   - Send radar chart of tasks by project
-  /refresh - Force data refresh`,
+  /refresh - Force data refresh
+
+Forwarded messages become tasks in the inbox list.`,
 	RunE: runBot,
 }
 
@@ -38,10 +42,16 @@ func init() {
 	botCmd.Flags().String("telegram-token", "", "Telegram Bot API token")
 	botCmd.Flags().Int64("telegram-chat-id", 0, "Allowed Telegram chat ID")
 	botCmd.Flags().String("refresh-interval", "4h", "Data refresh interval (e.g. 4h, 30m)")
+	botCmd.Flags().String("someday-list", "Someday", "List the /zombies move button sends tasks to")
+	botCmd.Flags().Bool("auto-prune", true, "Prune old snapshots after every refresh (see --keep-all-days)")
+	botCmd.Flags().String("inbox-list", "", "List /add and forwarded messages go to without a #list (default the To Do default list)")
 
 	_ = viper.BindPFlag("telegram-token", botCmd.Flags().Lookup("telegram-token"))
 	_ = viper.BindPFlag("telegram-chat-id", botCmd.Flags().Lookup("telegram-chat-id"))
 	_ = viper.BindPFlag("refresh-interval", botCmd.Flags().Lookup("refresh-interval"))
+	_ = viper.BindPFlag("someday-list", botCmd.Flags().Lookup("someday-list"))
+	_ = viper.BindPFlag("inbox-list", botCmd.Flags().Lookup("inbox-list"))
+	_ = viper.BindPFlag("auto-prune", botCmd.Flags().Lookup("auto-prune"))
 }
 
 func runBot(cmd *cobra.Command, args []string) error {
@@ -112,14 +122,38 @@ func runBot(cmd *cobra.Command, args []string) error {
 		botLogger.Info("restored authentication from cache")
 	}
 
-	collector := service.NewCollector(authClient, botLogger, refreshInterval)
+	opts, err := metricsOptions()
+	if err != nil {
+		return err
+	}
+	clk := clock.Real()
+	parser, err := newTodoParser(clk)
+	if err != nil {
+		return err
+	}
+	store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	collector := service.NewCollector(authClient, parser, store, botLogger, refreshInterval, clk, opts...)
+	if viper.GetBool("auto-prune") {
+		retention, err := retentionPolicy()
+		if err != nil {
+			return err
+		}
+		collector.WithRetention(retention)
+	}
 
 	botCfg := tgbot.BotConfig{
 		Token:            telegramToken,
 		ChatID:           chatID,
 		DailySummaryTime: dailySummaryTime,
+		SomedayList:      viper.GetString("someday-list"),
+		InboxList:        viper.GetString("inbox-list"),
 	}
-	telegramBot = tgbot.New(botCfg, collector, authClient, botLogger)
+	telegramBot = tgbot.New(botCfg, collector, store, authClient, clk, botLogger)
 
 	// Start collector in background
 	go func() {
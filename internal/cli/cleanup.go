@@ -0,0 +1,225 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/uchr/ToDoInfo/internal/cleanup"
+)
+
+var cleanupCmd = &cobra.Command{
+	Use:   "cleanup",
+	Short: "Write a reviewable plan for cleaning up stale tasks",
+	Long: `Select stale tasks from the latest stored snapshot and write a plan file
+with an action per task: keep, complete, snooze, move or delete.
+
+Tasks are selected by rottenness level (--level, default Zombie), list name
+globs (--list) and age (--min-age). Every task starts with --action, keep by
+default. Edit the plan, then carry it out with 'todoinfo cleanup apply'.
+The plan is YAML or TSV depending on the file extension.`,
+	Args: cobra.NoArgs,
+	RunE: runCleanup,
+}
+
+var cleanupApplyCmd = &cobra.Command{
+	Use:   "apply <plan>",
+	Short: "Carry out a cleanup plan",
+	Long: `Carry out a cleanup plan through Microsoft Graph. Tasks that were completed
+or removed since the plan was written are skipped, as is any task whose
+change fails; the rest of the plan still runs.
+
+Every change is appended to a JSON lines log (--log, default the plan path
+with .log.jsonl). 'todoinfo cleanup undo' reads it to reverse the snoozes,
+moves and completions.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runCleanupApply,
+}
+
+var cleanupUndoCmd = &cobra.Command{
+	Use:   "undo <log>",
+	Short: "Reverse the changes recorded in a cleanup log",
+	Long: `Reverse the changes recorded by 'todoinfo cleanup apply', newest first.
+Snoozed tasks get their old due date back, moved tasks are moved back and
+completed tasks are reopened. Deleted tasks can't be restored.
+
+The undo is itself logged (--log, default the log path with .undo.jsonl).`,
+	Args: cobra.ExactArgs(1),
+	RunE: runCleanupUndo,
+}
+
+func init() {
+	rootCmd.AddCommand(cleanupCmd)
+	cleanupCmd.AddCommand(cleanupApplyCmd, cleanupUndoCmd)
+
+	cleanupCmd.Flags().String("level", "", "Least rotten level to include (default the Zombie level)")
+	cleanupCmd.Flags().StringSlice("list", nil, "Only include lists matching these name globs")
+	cleanupCmd.Flags().Int("min-age", 0, "Only include tasks at least this many days old")
+	cleanupCmd.Flags().String("action", string(cleanup.ActionKeep), "Action every task starts with")
+	cleanupCmd.Flags().StringP("output", "o", "cleanup-plan.yaml", "Plan file to write (.yaml or .tsv)")
+	cleanupCmd.Flags().Bool("force", false, "Overwrite an existing plan file")
+
+	cleanupApplyCmd.Flags().Int("days", 7, "Days to snooze tasks whose entry sets none")
+	cleanupApplyCmd.Flags().String("to", "Someday", "List to move tasks to whose entry sets none")
+	cleanupApplyCmd.Flags().String("log", "", "Change log to append to")
+
+	cleanupUndoCmd.Flags().String("log", "", "Log for the undo itself")
+}
+
+func runCleanup(cmd *cobra.Command, args []string) error {
+	output, _ := cmd.Flags().GetString("output")
+	format, err := cleanup.FormatFromPath(output)
+	if err != nil {
+		return err
+	}
+	actionName, _ := cmd.Flags().GetString("action")
+	action, err := cleanup.ParseAction(actionName)
+	if err != nil {
+		return err
+	}
+
+	store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	snapshot, err := loadSnapshot(cmd.Context(), store, time.Now())
+	if err != nil {
+		return err
+	}
+	opts, err := metricsOptions()
+	if err != nil {
+		return err
+	}
+	metrics := createMetricsFromSnapshot(snapshot, opts)
+
+	filter := cleanup.Filter{MinLevel: metrics.Policy().ZombieLevel()}
+	if level, _ := cmd.Flags().GetString("level"); level != "" {
+		var ok bool
+		if filter.MinLevel, ok = metrics.Policy().Find(level); !ok {
+			return fmt.Errorf("unknown rottenness level %q", level)
+		}
+	}
+	filter.Lists, _ = cmd.Flags().GetStringSlice("list")
+	filter.MinAge, _ = cmd.Flags().GetInt("min-age")
+
+	plan := cleanup.NewPlan(metrics, filter, action)
+	plan.Snapshot = snapshot.Timestamp
+	if len(plan.Tasks) == 0 {
+		fmt.Println(successStyle.Render("✓ No tasks match, nothing to clean up"))
+		return nil
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if force, _ := cmd.Flags().GetBool("force"); force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	file, err := os.OpenFile(output, flags, 0o644)
+	if os.IsExist(err) {
+		return fmt.Errorf("%s already exists, use --force to overwrite it", output)
+	}
+	if err != nil {
+		return fmt.Errorf("create plan: %w", err)
+	}
+	if err := cleanup.Write(file, plan, format); err != nil {
+		file.Close()
+		return fmt.Errorf("write plan: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("write plan: %w", err)
+	}
+
+	fmt.Println(successStyle.Render(fmt.Sprintf("✓ Wrote %d tasks from the snapshot of %s to %s",
+		len(plan.Tasks), snapshot.Timestamp.Format("2006-01-02 15:04"), output)))
+	fmt.Println(infoStyle.Render("Edit the actions, then run: todoinfo cleanup apply " + output))
+	return nil
+}
+
+func runCleanupApply(cmd *cobra.Command, args []string) error {
+	planPath := args[0]
+	format, err := cleanup.FormatFromPath(planPath)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(planPath)
+	if err != nil {
+		return fmt.Errorf("open plan: %w", err)
+	}
+	plan, err := cleanup.Read(file, format)
+	file.Close()
+	if err != nil {
+		return err
+	}
+
+	days, _ := cmd.Flags().GetInt("days")
+	to, _ := cmd.Flags().GetString("to")
+	logPath, _ := cmd.Flags().GetString("log")
+	if logPath == "" {
+		logPath = strings.TrimSuffix(planPath, filepath.Ext(planPath)) + ".log.jsonl"
+	}
+
+	return runCleanupTarget(cmd, logPath, func(target cleanup.Target) (cleanup.Summary, error) {
+		return cleanup.Apply(cmd.Context(), target, plan, cleanup.Options{SnoozeDays: days, MoveTo: to})
+	})
+}
+
+func runCleanupUndo(cmd *cobra.Command, args []string) error {
+	file, err := os.Open(args[0])
+	if err != nil {
+		return fmt.Errorf("open log: %w", err)
+	}
+	entries, err := cleanup.ReadLog(file)
+	file.Close()
+	if err != nil {
+		return err
+	}
+
+	logPath, _ := cmd.Flags().GetString("log")
+	if logPath == "" {
+		logPath = strings.TrimSuffix(args[0], ".jsonl") + ".undo.jsonl"
+	}
+
+	return runCleanupTarget(cmd, logPath, func(target cleanup.Target) (cleanup.Summary, error) {
+		return cleanup.Undo(cmd.Context(), target, entries)
+	})
+}
+
+// runCleanupTarget fetches every list, opens the change log and runs apply
+// or undo against them.
+func runCleanupTarget(cmd *cobra.Command, logPath string, run func(cleanup.Target) (cleanup.Summary, error)) error {
+	session, err := newTaskSession(cmd)
+	if err != nil {
+		return err
+	}
+
+	log, err := os.OpenFile(logPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open change log: %w", err)
+	}
+	defer log.Close()
+
+	summary, err := run(cleanup.Target{
+		Parser: session.parser,
+		Logger: logger,
+		Token:  session.token,
+		Lists:  session.lists,
+		Clock:  session.clock,
+		Log:    log,
+	})
+	fmt.Println(successStyle.Render(fmt.Sprintf("✓ Changed %d tasks", summary.Changed)))
+	if summary.Kept > 0 {
+		fmt.Println(infoStyle.Render(fmt.Sprintf("Kept %d tasks", summary.Kept)))
+	}
+	if summary.Skipped > 0 {
+		fmt.Println(infoStyle.Render(fmt.Sprintf("Skipped %d changes that failed or can't be undone", summary.Skipped)))
+	}
+	if summary.Failed > 0 {
+		fmt.Println(warningStyle.Render(fmt.Sprintf("⚠ %d changes failed, see %s", summary.Failed, logPath)))
+	}
+	fmt.Println(infoStyle.Render("Changes logged to " + logPath))
+	return err
+}
@@ -0,0 +1,202 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/uchr/ToDoInfo/internal/clock"
+	"github.com/uchr/ToDoInfo/internal/httpclient"
+	"github.com/uchr/ToDoInfo/internal/storage"
+	"github.com/uchr/ToDoInfo/internal/todoclient"
+	"github.com/uchr/ToDoInfo/internal/todometrics"
+)
+
+// newTodoParser builds a To Do client from the viper configuration, dating
+// the completed-task window with clk.
+func newTodoParser(clk clock.Clock) (*todoclient.TodoParser, error) {
+	cfg, err := todoParserConfig()
+	if err != nil {
+		return nil, err
+	}
+	return todoclient.New(cfg.WithClock(clk)), nil
+}
+
+// todoParserConfig reads the To Do client settings from viper.
+func todoParserConfig() (*todoclient.Config, error) {
+	filter, err := listFilter()
+	if err != nil {
+		return nil, err
+	}
+
+	return todoclient.DefaultConfig().
+		WithBaseURL(viper.GetString("graph-url")).
+		WithPageSize(viper.GetInt("page-size")).
+		WithMaxPages(viper.GetInt("max-pages")).
+		WithCompletedDays(viper.GetInt("completed-days")).
+		WithConcurrency(viper.GetInt("graph-concurrency")).
+		WithListFilter(filter).
+		WithHTTPClient(newHTTPClient()), nil
+}
+
+// listFilter builds the list selection from the "lists" config section.
+func listFilter() (todoclient.ListFilter, error) {
+	filter := todoclient.ListFilter{
+		Include: viper.GetStringSlice("lists.include"),
+		Exclude: viper.GetStringSlice("lists.exclude"),
+	}
+	for _, name := range viper.GetStringSlice("lists.types") {
+		wellknown, err := todoclient.ParseWellknownList(name)
+		if err != nil {
+			return filter, fmt.Errorf("invalid lists config: %w", err)
+		}
+		filter.Wellknown = append(filter.Wellknown, wellknown)
+	}
+	return filter, nil
+}
+
+// newHTTPClient builds the Graph HTTP client from the viper configuration.
+func newHTTPClient() *httpclient.Client {
+	cfg := httpclient.DefaultConfig().
+		WithTimeout(viper.GetDuration("http-timeout")).
+		WithMaxRetries(viper.GetInt("http-retries"))
+	return httpclient.New(cfg)
+}
+
+// openStore opens and migrates the statistics database named by the
+// "database" setting.
+func openStore() (storage.Backend, error) {
+	store, err := storage.New(viper.GetString("database"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open storage: %w", err)
+	}
+	return store, nil
+}
+
+// retentionPolicy reads the "retention" config section.
+func retentionPolicy() (storage.RetentionPolicy, error) {
+	policy := storage.RetentionPolicy{
+		KeepAllDays: viper.GetInt("retention.keep-all-days"),
+		DailyMonths: viper.GetInt("retention.daily-months"),
+		BlobDays:    viper.GetInt("retention.task-lists-days"),
+	}
+	if policy.KeepAllDays < 0 || policy.DailyMonths < 0 || policy.BlobDays < 0 {
+		return policy, fmt.Errorf("invalid retention config: %+v, values can't be negative", policy)
+	}
+	return policy, nil
+}
+
+// completedWindow returns the configured completion window; zero when disabled.
+func completedWindow() time.Duration {
+	days := viper.GetInt("completed-days")
+	if days <= 0 {
+		return 0
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// rottennessPolicy reads the "rottenness" section of the config file,
+// falling back to the built-in ladder when it is absent.
+func rottennessPolicy() (todometrics.RottennessPolicy, error) {
+	if !viper.IsSet("rottenness") {
+		return todometrics.DefaultRottennessPolicy(), nil
+	}
+
+	var policy todometrics.RottennessPolicy
+	if err := viper.UnmarshalKey("rottenness", &policy); err != nil {
+		return policy, fmt.Errorf("parse rottenness config: %w", err)
+	}
+	if err := policy.Validate(); err != nil {
+		return policy, fmt.Errorf("invalid rottenness config: %w", err)
+	}
+	return policy, nil
+}
+
+// metricsOptions collects the todometrics options configured through viper.
+func metricsOptions() ([]todometrics.Option, error) {
+	policy, err := rottennessPolicy()
+	if err != nil {
+		return nil, err
+	}
+	calendar, err := workCalendar()
+	if err != nil {
+		return nil, err
+	}
+	recurrence, err := todometrics.ParseRecurrenceMode(viper.GetString("recurring"))
+	if err != nil {
+		return nil, err
+	}
+	return []todometrics.Option{
+		todometrics.WithPolicy(policy),
+		todometrics.WithCalendar(calendar),
+		todometrics.WithRecurrenceMode(recurrence),
+	}, nil
+}
+
+// workCalendar builds the business-day calendar from the "business-days"
+// config section. It returns nil in the default calendar age mode.
+func workCalendar() (*todometrics.WorkCalendar, error) {
+	switch mode := viper.GetString("age-mode"); mode {
+	case "", "calendar":
+		return nil, nil
+	case "business":
+	default:
+		return nil, fmt.Errorf("unknown age mode %q (use calendar or business)", mode)
+	}
+
+	var weekend []time.Weekday
+	for _, name := range viper.GetStringSlice("business-days.weekend") {
+		day, err := todometrics.ParseWeekday(name)
+		if err != nil {
+			return nil, fmt.Errorf("business-days.weekend: %w", err)
+		}
+		weekend = append(weekend, day)
+	}
+	calendar := todometrics.NewWorkCalendar().WithWeekend(weekend)
+
+	if path := viper.GetString("business-days.holidays"); path != "" {
+		holidays, err := loadHolidays(path)
+		if err != nil {
+			return nil, err
+		}
+		calendar.WithHolidays(holidays)
+	}
+
+	if viper.IsSet("business-days.vacation") {
+		// YAML decodes bare dates as timestamps; GetTime accepts both forms.
+		from := viper.GetTime("business-days.vacation.from")
+		to := viper.GetTime("business-days.vacation.to")
+		if from.IsZero() || to.IsZero() || to.Before(from) {
+			return nil, fmt.Errorf("business-days.vacation needs from and to dates, from not after to")
+		}
+		calendar.WithVacation(from, to)
+	}
+
+	return calendar, nil
+}
+
+func loadHolidays(path string) ([]todometrics.Holiday, error) {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("get home directory: %w", err)
+		}
+		path = filepath.Join(home, rest)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open holidays file: %w", err)
+	}
+	defer f.Close()
+
+	holidays, err := todometrics.ParseICSHolidays(f)
+	if err != nil {
+		return nil, fmt.Errorf("parse holidays file %s: %w", path, err)
+	}
+	return holidays, nil
+}
@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/uchr/ToDoInfo/internal/storage"
+)
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Inspect and upgrade the statistics database",
+}
+
+var dbMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Apply pending schema migrations",
+	Long: `Apply the schema migrations the database is missing, oldest first. Each
+migration runs in a transaction, so a failure leaves the database at the
+last version that applied.
+
+Every command that opens the database migrates it as well; this command
+makes the upgrade explicit, for example before deploying a new image.`,
+	Args: cobra.NoArgs,
+	RunE: runDBMigrate,
+}
+
+var dbStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the applied and pending schema migrations",
+	Args:  cobra.NoArgs,
+	RunE:  runDBStatus,
+}
+
+var dbPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Thin out old snapshots",
+	Long: `Thin out stored snapshots: keep every snapshot from the last --keep-all-days,
+then the last one of each day for --keep-daily-months, then the last one of
+each week. Snapshots older than --keep-task-lists-days lose their task lists
+but keep their totals, so trend charts still cover them. The task history
+is never pruned.
+
+The bot prunes after every refresh. The freed space is reused by new
+snapshots; --vacuum also shrinks the database file.`,
+	Args: cobra.NoArgs,
+	RunE: runDBPrune,
+}
+
+func init() {
+	rootCmd.AddCommand(dbCmd)
+	dbCmd.AddCommand(dbMigrateCmd, dbStatusCmd, dbPruneCmd)
+
+	dbPruneCmd.Flags().Bool("vacuum", false, "Rebuild the database file to give the freed space back")
+}
+
+// openDB opens the database named by --database without migrating it and
+// returns its name for display, without the password of a DSN.
+func openDB() (storage.Backend, string, error) {
+	dsn := viper.GetString("database")
+	store, err := storage.Open(dsn)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open storage: %w", err)
+	}
+	name := dsn
+	if u, err := url.Parse(dsn); err == nil && u.User != nil {
+		name = u.Redacted()
+	}
+	if name == "" {
+		name, _ = storage.DefaultDBPath()
+	}
+	return store, name, nil
+}
+
+func runDBMigrate(cmd *cobra.Command, args []string) error {
+	store, name, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	applied, err := store.Migrate(cmd.Context())
+	for _, m := range applied {
+		fmt.Println(successStyle.Render(fmt.Sprintf("✓ Applied %d: %s", m.Version, m.Name)))
+	}
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 {
+		fmt.Println(infoStyle.Render(name + " is up to date"))
+	}
+	return nil
+}
+
+func runDBPrune(cmd *cobra.Command, args []string) error {
+	policy, err := retentionPolicy()
+	if err != nil {
+		return err
+	}
+	store, _, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	if _, err := store.Migrate(cmd.Context()); err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+
+	result, err := store.Prune(cmd.Context(), policy, time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Println(successStyle.Render(fmt.Sprintf("✓ Deleted %d snapshots and the task lists of %d more, freeing %s",
+		result.SnapshotsDeleted, result.BlobsDropped, formatBytes(result.BytesFreed))))
+
+	if vacuum, _ := cmd.Flags().GetBool("vacuum"); vacuum {
+		shrunk, err := store.Vacuum(cmd.Context())
+		if err != nil {
+			return err
+		}
+		fmt.Println(successStyle.Render("✓ Database file shrank by " + formatBytes(shrunk)))
+	}
+	return nil
+}
+
+// formatBytes renders n in KiB or MiB.
+func formatBytes(n int64) string {
+	if n < 1<<20 {
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+}
+
+func runDBStatus(cmd *cobra.Command, args []string) error {
+	store, name, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	statuses, err := store.MigrationStatus(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(headerStyle.Render("🗄  " + name))
+	if !slices.ContainsFunc(statuses, func(m storage.MigrationStatus) bool { return m.AppliedAt != nil }) {
+		fmt.Println(warningStyle.Render("  unversioned: no migration has been applied"))
+	}
+	pending := 0
+	for _, m := range statuses {
+		if m.AppliedAt == nil {
+			pending++
+			fmt.Println(warningStyle.Render(fmt.Sprintf("  %3d  %-32s pending", m.Version, m.Name)))
+			continue
+		}
+		fmt.Println(infoStyle.Render(fmt.Sprintf("  %3d  %-32s applied %s", m.Version, m.Name, m.AppliedAt.Local().Format("2006-01-02 15:04"))))
+	}
+	if pending > 0 {
+		fmt.Println(warningStyle.Render(fmt.Sprintf("⚠ %d pending, run: todoinfo db migrate", pending)))
+	}
+	return nil
+}
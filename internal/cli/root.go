@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/charmbracelet/lipgloss"
@@ -54,10 +55,42 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.todoinfo.yaml)")
 	rootCmd.PersistentFlags().StringVar(&clientID, "client-id", "", "Azure AD Client ID (required)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
+	rootCmd.PersistentFlags().Int("page-size", 100, "Number of items requested per Microsoft Graph page")
+	rootCmd.PersistentFlags().Int("max-pages", 50, "Maximum number of Microsoft Graph pages followed per collection")
+	rootCmd.PersistentFlags().Int("completed-days", 0, "Also fetch tasks completed within this many days and report throughput (0 disables)")
+	rootCmd.PersistentFlags().Duration("http-timeout", 30*time.Second, "Timeout for a single Microsoft Graph request attempt")
+	rootCmd.PersistentFlags().Int("http-retries", 4, "Retries for throttled (429) or failed (5xx) Microsoft Graph requests")
+	rootCmd.PersistentFlags().String("graph-url", "https://graph.microsoft.com/v1.0", "Microsoft Graph base URL")
+	rootCmd.PersistentFlags().Int("graph-concurrency", 4, "Number of task lists fetched from Microsoft Graph at the same time")
+	rootCmd.PersistentFlags().StringSlice("list-types", []string{"custom"}, "List types to fetch: custom, tasks (the default \"Tasks\" list) and flagged (\"Flagged email\")")
+	rootCmd.PersistentFlags().StringSlice("include-lists", nil, "Also fetch lists whose names match these globs, whatever their type")
+	rootCmd.PersistentFlags().StringSlice("exclude-lists", nil, "Skip lists whose names match these globs")
+	rootCmd.PersistentFlags().String("age-mode", "calendar", "How task age is counted: calendar or business (working days only)")
+	rootCmd.PersistentFlags().String("recurring", "include", "How recurring tasks are reported: include, separate or exclude")
+	rootCmd.PersistentFlags().Int("keep-all-days", 14, "Keep every stored snapshot this many days")
+	rootCmd.PersistentFlags().Int("keep-daily-months", 6, "Then keep one snapshot a day for this many months, and one a week after that")
+	rootCmd.PersistentFlags().Int("keep-task-lists-days", 90, "Drop the task lists of older snapshots, keeping their totals (0 keeps them)")
+	rootCmd.PersistentFlags().String("database", "", "SQLite database path or postgres:// DSN (default ~/.todoinfo/data/stats.db)")
 
 	// Bind flags to viper
 	viper.BindPFlag("client-id", rootCmd.PersistentFlags().Lookup("client-id"))
 	viper.BindPFlag("verbose", rootCmd.PersistentFlags().Lookup("verbose"))
+	viper.BindPFlag("page-size", rootCmd.PersistentFlags().Lookup("page-size"))
+	viper.BindPFlag("max-pages", rootCmd.PersistentFlags().Lookup("max-pages"))
+	viper.BindPFlag("completed-days", rootCmd.PersistentFlags().Lookup("completed-days"))
+	viper.BindPFlag("http-timeout", rootCmd.PersistentFlags().Lookup("http-timeout"))
+	viper.BindPFlag("http-retries", rootCmd.PersistentFlags().Lookup("http-retries"))
+	viper.BindPFlag("graph-url", rootCmd.PersistentFlags().Lookup("graph-url"))
+	viper.BindPFlag("graph-concurrency", rootCmd.PersistentFlags().Lookup("graph-concurrency"))
+	viper.BindPFlag("lists.types", rootCmd.PersistentFlags().Lookup("list-types"))
+	viper.BindPFlag("lists.include", rootCmd.PersistentFlags().Lookup("include-lists"))
+	viper.BindPFlag("lists.exclude", rootCmd.PersistentFlags().Lookup("exclude-lists"))
+	viper.BindPFlag("age-mode", rootCmd.PersistentFlags().Lookup("age-mode"))
+	viper.BindPFlag("recurring", rootCmd.PersistentFlags().Lookup("recurring"))
+	viper.BindPFlag("retention.keep-all-days", rootCmd.PersistentFlags().Lookup("keep-all-days"))
+	viper.BindPFlag("retention.daily-months", rootCmd.PersistentFlags().Lookup("keep-daily-months"))
+	viper.BindPFlag("retention.task-lists-days", rootCmd.PersistentFlags().Lookup("keep-task-lists-days"))
+	viper.BindPFlag("database", rootCmd.PersistentFlags().Lookup("database"))
 
 	// Note: client-id is marked as required per command, not globally
 }
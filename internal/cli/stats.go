@@ -16,6 +16,7 @@ import (
 	"github.com/spf13/viper"
 
 	"github.com/uchr/ToDoInfo/internal/auth"
+	"github.com/uchr/ToDoInfo/internal/clock"
 	"github.com/uchr/ToDoInfo/internal/storage"
 	"github.com/uchr/ToDoInfo/internal/todo"
 	"github.com/uchr/ToDoInfo/internal/todoclient"
@@ -26,12 +27,17 @@ var statsCmd = &cobra.Command{
 	Use:   "stats",
 	Short: "Display beautiful statistics about your ToDo tasks",
 	Long: `Analyze your Microsoft ToDo tasks and display comprehensive statistics including:
-- Task rottenness levels (Fresh, Ripe, Tired, Zombie)
+- Task rottenness levels (Fresh, Ripe, Tired, Zombie, or the ladder set under
+  "rottenness" in the config file)
 - Age distribution across lists
 - Complete list of all tasks sorted by age
+- Completion throughput, cycle time and net flow (with --completed-days)
 - Historical trends and charts
 
-Use --offline flag to view previously stored data without authentication.`,
+Use --offline flag to view previously stored data without authentication.
+Use --as-of to re-evaluate the snapshot stored at that time as if it were
+that time (e.g. --as-of 2026-10-12 or --as-of 2026-10-12T09:00:00+02:00).
+A bare date means the end of that day; --as-of implies --offline.`,
 	RunE: runStats,
 }
 
@@ -40,6 +46,7 @@ func init() {
 
 	// Add offline flag
 	statsCmd.Flags().Bool("offline", false, "Use existing stored data without fetching new statistics (no authentication required)")
+	statsCmd.Flags().String("as-of", "", "Evaluate stored data at a past date or time (implies --offline)")
 
 	// Don't mark client-id as required since we have offline mode
 	// The command will check for it when not in offline mode
@@ -54,6 +61,31 @@ func runStats(cmd *cobra.Command, args []string) error {
 	// Check if offline mode is enabled
 	offlineMode, _ := cmd.Flags().GetBool("offline")
 
+	clk := clock.Real()
+	asOf, _ := cmd.Flags().GetString("as-of")
+	if asOf != "" {
+		at, err := parseAsOf(asOf)
+		if err != nil {
+			return err
+		}
+		clk = clock.NewFixed(at)
+		offlineMode = true
+	}
+
+	opts, err := metricsOptions()
+	if err != nil {
+		return err
+	}
+	opts = append(opts, todometrics.WithClock(clk))
+
+	// The store is optional online: the statistics are shown without history.
+	store, storeErr := openStore()
+	if storeErr == nil {
+		defer store.Close()
+	} else if offlineMode {
+		return fmt.Errorf("failed to load stored data: %w", storeErr)
+	}
+
 	var metrics *todometrics.Metrics
 
 	if !offlineMode {
@@ -79,16 +111,21 @@ func runStats(cmd *cobra.Command, args []string) error {
 		fmt.Println(" " + successStyle.Render("✓ Authentication successful!"))
 
 		// Fetch tasks with progress
-		tasks, err := fetchTasks(ctx, logger, authClient)
+		result, err := fetchTasks(ctx, logger, authClient, clk)
 		if err != nil {
 			return fmt.Errorf("failed to fetch tasks: %w", err)
 		}
+		missingLists := storage.NewMissingLists(result.Failed)
+		displayMissingLists(missingLists, true)
+		displayExcludedLists(result.ExcludedNames())
 
 		// Calculate metrics
-		metrics = todometrics.New(tasks)
+		metrics = todometrics.New(result.TaskLists, opts...)
 
 		// Store statistics for historical tracking
-		if err := storeStatistics(ctx, metrics, tasks); err != nil {
+		if storeErr != nil {
+			fmt.Println(warningStyle.Render(fmt.Sprintf("⚠ Failed to store statistics: %v", storeErr)))
+		} else if err := storeStatistics(ctx, store, metrics, result.TaskLists, missingLists); err != nil {
 			// Don't fail the command if storage fails, just log a warning
 			fmt.Println(warningStyle.Render(fmt.Sprintf("⚠ Failed to store statistics: %v", err)))
 		}
@@ -97,16 +134,20 @@ func runStats(cmd *cobra.Command, args []string) error {
 		fmt.Println(infoStyle.Render("📊 Offline Mode: Using existing stored data"))
 		fmt.Println()
 
-		snapshot, err := loadLatestSnapshot(ctx)
+		snapshot, err := loadSnapshot(ctx, store, clk.Now())
 		if err != nil {
 			return fmt.Errorf("failed to load stored data: %w", err)
 		}
 
-		fmt.Println(infoStyle.Render(fmt.Sprintf("📅 Data from: %s", snapshot.Timestamp.Format("2006-01-02 15:04"))))
+		fmt.Println(infoStyle.Render(fmt.Sprintf("📅 Data from: %s", snapshot.Timestamp.Local().Format("2006-01-02 15:04"))))
+		if asOf != "" {
+			fmt.Println(infoStyle.Render(fmt.Sprintf("⏱ Evaluated as of: %s", clk.Now().Format("2006-01-02 15:04"))))
+		}
 		fmt.Println()
+		displayMissingLists(snapshot.MissingLists, false)
 
 		// Create metrics from stored snapshot
-		metrics = createMetricsFromSnapshot(snapshot)
+		metrics = createMetricsFromSnapshot(snapshot, opts)
 
 		// If no tasks available (due to parsing issues or old format), show limited info
 		if len(metrics.GetSortedTasks()) == 0 && len(snapshot.TaskLists) > 0 {
@@ -118,45 +159,60 @@ func runStats(cmd *cobra.Command, args []string) error {
 	// Always use the same render function
 	displayStatistics(metrics)
 
+	if window := completedWindow(); window > 0 {
+		displayThroughput(metrics.GetThroughput(window))
+	}
+
 	// Display historical graphs at the bottom
-	displayHistoricalGraphs(ctx)
+	if storeErr != nil {
+		fmt.Println(warningStyle.Render("⚠ Cannot access historical data storage"))
+	} else {
+		displayHistoricalGraphs(ctx, store, metrics.Now())
+	}
 
 	return nil
 }
 
-// loadLatestSnapshot loads the most recent stored statistics snapshot
-func loadLatestSnapshot(ctx context.Context) (*storage.StatsSnapshot, error) {
-	dbPath, err := storage.DefaultDBPath()
-	if err != nil {
-		return nil, err
+// parseAsOf accepts RFC 3339, "2006-01-02 15:04" or a bare date, which means
+// the end of that day. Times without an offset are local.
+func parseAsOf(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
 	}
-
-	store, err := storage.NewSQLiteStorage(dbPath)
-	if err != nil {
-		return nil, fmt.Errorf("cannot access data directory: %w", err)
+	if t, err := time.ParseInLocation("2006-01-02 15:04", value, time.Local); err == nil {
+		return t, nil
 	}
-	defer store.Close()
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return t.AddDate(0, 0, 1).Add(-time.Second), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --as-of %q: use YYYY-MM-DD, \"YYYY-MM-DD HH:MM\" or RFC 3339", value)
+}
 
-	snapshot, err := store.GetLatest(ctx)
+// loadSnapshot loads the newest stored statistics snapshot taken at or before at
+func loadSnapshot(ctx context.Context, store storage.StatsStorage, at time.Time) (*storage.StatsSnapshot, error) {
+	snapshot, err := store.GetAt(ctx, at)
 	if err != nil {
 		return nil, fmt.Errorf("no stored data available - run 'todoinfo stats' with authentication first to generate data: %w", err)
 	}
+	if snapshot == nil {
+		return nil, fmt.Errorf("no stored data before %s - run 'todoinfo stats' with authentication first to generate data", at.Format("2006-01-02 15:04"))
+	}
 
 	return snapshot, nil
 }
 
 // createMetricsFromSnapshot creates a metrics object from stored snapshot data
-func createMetricsFromSnapshot(snapshot *storage.StatsSnapshot) *todometrics.Metrics {
+func createMetricsFromSnapshot(snapshot *storage.StatsSnapshot, opts []todometrics.Option) *todometrics.Metrics {
 	// If we have full task data stored, try to use it to create proper metrics
 	if len(snapshot.TaskLists) > 0 {
 		// Try to create metrics from stored task data
 		// If there are issues with the data format, we'll fall back to empty metrics
-		return todometrics.New(snapshot.TaskLists)
+		return todometrics.New(snapshot.TaskLists, opts...)
 	}
 
 	// Fallback for backward compatibility with old snapshots that don't have task data
 	// Return empty metrics - the display functions will show limited info from snapshot data
-	return todometrics.New([]todo.TaskList{})
+	return todometrics.New([]todo.TaskList{}, opts...)
 }
 
 var (
@@ -230,22 +286,54 @@ func showBanner() {
 	fmt.Println()
 }
 
-func fetchTasks(ctx context.Context, logger *slog.Logger, authClient *auth.AuthClient) ([]todo.TaskList, error) {
+func fetchTasks(ctx context.Context, logger *slog.Logger, authClient *auth.AuthClient, clk clock.Clock) (*todoclient.FetchResult, error) {
 	// Extract access token from the auth client for use with old HTTP client
 	token, err := extractAccessToken(ctx, authClient)
 	if err != nil {
 		return nil, fmt.Errorf("failed to extract access token: %w", err)
 	}
 
-	// Use the old TodoParser that was working
-	parser := todoclient.New()
+	parser, err := newTodoParser(clk)
+	if err != nil {
+		return nil, err
+	}
 
-	taskLists, err := parser.GetTasks(ctx, logger, token)
+	result, err := parser.GetTasks(ctx, logger, token)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get task lists: %w", err)
 	}
 
-	return taskLists, nil
+	httpStats := parser.HTTPStats()
+	logger.DebugContext(ctx, "Graph requests done", slog.Float64("rate", httpStats.Rate), slog.Int("throttled", httpStats.Throttled))
+
+	return result, nil
+}
+
+// displayMissingLists warns that lists failed to fetch and are left out of
+// the statistics. fresh tells a fetch that just ran from a stored snapshot.
+func displayMissingLists(missing []storage.MissingList, fresh bool) {
+	if len(missing) == 0 {
+		return
+	}
+
+	if fresh {
+		fmt.Println(warningStyle.Render(fmt.Sprintf("⚠ %d list(s) failed to fetch and are missing from these statistics:", len(missing))))
+	} else {
+		fmt.Println(warningStyle.Render(fmt.Sprintf("⚠ This snapshot is partial, %d list(s) failed to fetch:", len(missing))))
+	}
+	for _, m := range missing {
+		fmt.Println(warningStyle.Render(fmt.Sprintf("  • %s: %s", m.Name, m.Error)))
+	}
+	fmt.Println()
+}
+
+// displayExcludedLists names the lists the list filter left out.
+func displayExcludedLists(names []string) {
+	if len(names) == 0 {
+		return
+	}
+	fmt.Println(infoStyle.Render("🙈 Excluded lists: " + strings.Join(names, ", ")))
+	fmt.Println()
 }
 
 // extractAccessToken gets the access token from the new auth client
@@ -263,6 +351,10 @@ func displayStatistics(metrics *todometrics.Metrics) {
 	// Top 10 Oldest Tasks
 	displayTopOldestTasks(metrics)
 
+	if metrics.RecurrenceMode() == todometrics.RecurrenceSeparate {
+		displayRecurringTasks(metrics)
+	}
+
 	// Champion Procrastinator box at the very top
 	displayChampionProcrastinator(metrics)
 }
@@ -275,7 +367,7 @@ func displayChampionProcrastinator(metrics *todometrics.Metrics) {
 			truncateString(oldest.TaskName, 50),
 			truncateString(oldest.TaskList, 30),
 			oldest.Age,
-			oldest.Rottenness.String(),
+			metrics.Policy().Level(oldest.Rottenness).Emoji,
 		)
 
 		box := boxStyle.
@@ -293,7 +385,9 @@ func displayGlobalStats(metrics *todometrics.Metrics) {
 	allTasksInfo := metrics.GetSortedTasks()
 	totalAge := 0
 	for _, task := range allTasksInfo {
-		totalAge += task.Age
+		if !task.ExcludedFromTotal {
+			totalAge += task.Age
+		}
 	}
 
 	// Create lipgloss table
@@ -308,7 +402,9 @@ func displayGlobalStats(metrics *todometrics.Metrics) {
 		}).
 		Headers("Metric", "Value").
 		Row("Tasks", fmt.Sprintf("%d", len(allTasksInfo))).
-		Row("Total Age", fmt.Sprintf("%d days", totalAge))
+		Row("Total Age", fmt.Sprintf("%d days", totalAge)).
+		Row("Age Mode", metrics.AgeMode()).
+		Row("Recurring Tasks", string(metrics.RecurrenceMode()))
 
 	fmt.Println(t.Render())
 }
@@ -317,30 +413,47 @@ func displayListAges(metrics *todometrics.Metrics) {
 	fmt.Println(headerStyle.Render("📋 Task Age by List"))
 
 	listAges := metrics.GetListAges()
+	oldestTasks := metrics.GetOldestTaskForList()
+	policy := metrics.Policy()
+
+	// Each row is coloured by the rottenness of the list's oldest task.
+	rowColors := make([]string, 0, len(listAges.Ages))
 
 	// Create lipgloss table
 	t := table.New().
 		Border(lipgloss.RoundedBorder()).
 		BorderStyle(lipgloss.NewStyle().Foreground(lipgloss.Color("#FFB86C"))).
 		StyleFunc(func(row, col int) lipgloss.Style {
-			if row == 0 {
+			if row == table.HeaderRow {
 				return tableHeaderStyle
 			}
+			if row >= 0 && row < len(rowColors) {
+				return lipgloss.NewStyle().Foreground(lipgloss.Color(rowColors[row]))
+			}
 			return lipgloss.NewStyle()
 		}).
-		Headers("List Name", "Total Age (days)", "Task Count", "Share")
+		Headers("List Name", "Total Age (days)", "Task Count", "Share", "Oldest")
 
 	for _, listAge := range listAges.Ages {
-		percentage := 0.0
-		if listAges.TotalAge > 0 {
-			percentage = float64(listAge.Age) / float64(listAges.TotalAge) * 100
+		share := "excluded"
+		if !listAge.Excluded {
+			percentage := 0.0
+			if listAges.TotalAge > 0 {
+				percentage = float64(listAge.Age) / float64(listAges.TotalAge) * 100
+			}
+			share = fmt.Sprintf("%.1f%%", percentage)
 		}
 
+		oldest := oldestTasks[listAge.Title]
+		level := policy.Level(oldest.Rottenness)
+		rowColors = append(rowColors, policy.LevelColor(oldest.Rottenness))
+
 		t.Row(
 			listAge.Title,
 			strconv.Itoa(listAge.Age),
 			strconv.Itoa(listAge.TaskCount),
-			fmt.Sprintf("%.1f%%", percentage),
+			share,
+			fmt.Sprintf("%s %s", level.Emoji, level.Name),
 		)
 	}
 
@@ -373,16 +486,120 @@ func displayTopOldestTasks(metrics *todometrics.Metrics) {
 	for i, task := range allTasks {
 		t.Row(
 			strconv.Itoa(i+1),
+			taskTitle(task),
+			task.TaskList,
+			strconv.Itoa(task.Age),
+			metrics.Policy().Level(task.Rottenness).Emoji,
+		)
+	}
+
+	fmt.Println(t.Render())
+}
+
+func displayRecurringTasks(metrics *todometrics.Metrics) {
+	fmt.Println(headerStyle.Render("🔁 Recurring Tasks (Since Last Missed Occurrence)"))
+
+	recurring := metrics.GetRecurringTasks()
+	if len(recurring) == 0 {
+		fmt.Println(infoStyle.Render("No recurring tasks found!"))
+		return
+	}
+
+	t := table.New().
+		Border(lipgloss.RoundedBorder()).
+		BorderStyle(lipgloss.NewStyle().Foreground(lipgloss.Color("#FFB86C"))).
+		StyleFunc(func(row, col int) lipgloss.Style {
+			if row == table.HeaderRow {
+				return tableHeaderStyle
+			}
+			return lipgloss.NewStyle()
+		}).
+		Headers("Task", "List", "Overdue (days)", "Status")
+
+	for _, task := range recurring {
+		t.Row(
 			task.TaskName,
 			task.TaskList,
 			strconv.Itoa(task.Age),
-			task.Rottenness.String(),
+			metrics.Policy().Level(task.Rottenness).Emoji,
 		)
 	}
 
 	fmt.Println(t.Render())
 }
 
+// taskTitle marks recurring tasks so they stand out among one-off ones.
+func taskTitle(task todometrics.TaskRottennessInfo) string {
+	if task.Recurring {
+		return "🔁 " + task.TaskName
+	}
+	return task.TaskName
+}
+
+func displayThroughput(throughput todometrics.Throughput) {
+	fmt.Println(headerStyle.Render(fmt.Sprintf("✅ Throughput (since %s)", throughput.WindowStart.Format("2006-01-02"))))
+
+	summary := table.New().
+		Border(lipgloss.RoundedBorder()).
+		BorderStyle(lipgloss.NewStyle().Foreground(lipgloss.Color("#FFB86C"))).
+		StyleFunc(func(row, col int) lipgloss.Style {
+			if col == 0 {
+				return tableHeaderStyle
+			}
+			return lipgloss.NewStyle()
+		}).
+		Headers("Metric", "Value").
+		Row("Queued", strconv.Itoa(throughput.Queued)).
+		Row("Completed", strconv.Itoa(throughput.Completed)).
+		Row("Net Flow", fmt.Sprintf("%+d", throughput.NetFlow))
+
+	fmt.Println(summary.Render())
+
+	weekly := table.New().
+		Border(lipgloss.RoundedBorder()).
+		BorderStyle(lipgloss.NewStyle().Foreground(lipgloss.Color("#FFB86C"))).
+		StyleFunc(func(row, col int) lipgloss.Style {
+			if row == 0 {
+				return tableHeaderStyle
+			}
+			return lipgloss.NewStyle()
+		}).
+		Headers("Week", "Completed")
+
+	for _, week := range throughput.Weekly {
+		weekly.Row(week.WeekStart.Format("2006-01-02"), strconv.Itoa(week.Completed))
+	}
+
+	fmt.Println(weekly.Render())
+
+	if len(throughput.CycleTimes) == 0 {
+		fmt.Println(infoStyle.Render("No completed tasks in this window."))
+		return
+	}
+
+	cycleTimes := table.New().
+		Border(lipgloss.RoundedBorder()).
+		BorderStyle(lipgloss.NewStyle().Foreground(lipgloss.Color("#FFB86C"))).
+		StyleFunc(func(row, col int) lipgloss.Style {
+			if row == 0 {
+				return tableHeaderStyle
+			}
+			return lipgloss.NewStyle()
+		}).
+		Headers("List Name", "Completed", "Avg Cycle (days)", "Median Cycle (days)")
+
+	for _, cycle := range throughput.CycleTimes {
+		cycleTimes.Row(
+			cycle.Title,
+			strconv.Itoa(cycle.Completed),
+			fmt.Sprintf("%.1f", cycle.AverageDays),
+			fmt.Sprintf("%.1f", cycle.MedianDays),
+		)
+	}
+
+	fmt.Println(cycleTimes.Render())
+}
+
 func truncateString(s string, maxLen int) string {
 	if len(s) <= maxLen {
 		return s
@@ -391,60 +608,36 @@ func truncateString(s string, maxLen int) string {
 }
 
 // storeStatistics stores current statistics to persistent storage
-func storeStatistics(ctx context.Context, metrics *todometrics.Metrics, tasks []todo.TaskList) error {
-	dbPath, err := storage.DefaultDBPath()
-	if err != nil {
-		return fmt.Errorf("failed to get user home directory: %w", err)
-	}
-
-	// Create storage
-	store, err := storage.NewSQLiteStorage(dbPath)
-	if err != nil {
-		return fmt.Errorf("failed to create storage: %w", err)
-	}
-	defer store.Close()
-
+func storeStatistics(ctx context.Context, store storage.StatsStorage, metrics *todometrics.Metrics, tasks []todo.TaskList, missingLists []storage.MissingList) error {
 	// Calculate max age and task count
 	allTasks := metrics.GetSortedTasks()
 	totalAge := 0
-	if len(allTasks) > 0 {
-		for _, task := range allTasks {
+	for _, task := range allTasks {
+		if !task.ExcludedFromTotal {
 			totalAge += task.Age
 		}
 	}
 
 	// Create snapshot with full task data for better offline support
 	snapshot := storage.StatsSnapshot{
-		Timestamp: time.Now(),
+		Timestamp: metrics.Now(),
 		GlobalStats: storage.GlobalStats{
 			TotalAge:  totalAge,
 			TaskCount: len(allTasks),
 		},
-		ListAges:  metrics.GetListAges(),
-		TaskLists: tasks,
+		ListAges:     metrics.GetListAges(),
+		TaskLists:    tasks,
+		Partial:      len(missingLists) > 0,
+		MissingLists: missingLists,
 	}
 
 	return store.Store(ctx, snapshot)
 }
 
-// displayHistoricalGraphs displays the historical graphs at the bottom
-func displayHistoricalGraphs(ctx context.Context) {
-	dbPath, err := storage.DefaultDBPath()
-	if err != nil {
-		fmt.Println(warningStyle.Render("⚠ Cannot get home directory for historical data"))
-		return
-	}
-
-	// Create storage
-	store, err := storage.NewSQLiteStorage(dbPath)
-	if err != nil {
-		fmt.Println(warningStyle.Render("⚠ Cannot access historical data storage"))
-		return
-	}
-	defer store.Close()
-
+// displayHistoricalGraphs displays the historical graphs up to asOf at the bottom
+func displayHistoricalGraphs(ctx context.Context, store storage.StatsStorage, asOf time.Time) {
 	// Get time series data for the last 90 days
-	points, err := store.GetTimeSeriesData(ctx, 90)
+	points, err := store.GetTimeSeriesData(ctx, asOf, 90)
 	if err != nil {
 		fmt.Println(warningStyle.Render("⚠ No historical data available yet - run stats a few times to build history"))
 		return
@@ -0,0 +1,246 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/uchr/ToDoInfo/internal/clock"
+	"github.com/uchr/ToDoInfo/internal/todo"
+	"github.com/uchr/ToDoInfo/internal/todoclient"
+)
+
+var taskCmd = &cobra.Command{
+	Use:   "task",
+	Short: "Complete, snooze, move or tag tasks in Microsoft ToDo",
+	Long: `Change tasks in Microsoft ToDo.
+
+A task is selected by its ID or by its title. Titles match ignoring case:
+an exact title wins over a prefix, a prefix over a substring, and a
+substring over titles merely containing every word. When several tasks match
+equally well the candidates are listed with their IDs. Use --list to narrow
+the search to one list.
+
+Use --dry-run to print the Microsoft Graph requests instead of sending them.`,
+}
+
+var taskCompleteCmd = &cobra.Command{
+	Use:   "complete <task>",
+	Short: "Mark a task completed",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runTaskComplete,
+}
+
+var taskSnoozeCmd = &cobra.Command{
+	Use:   "snooze <task>",
+	Short: "Snooze a task for a number of days",
+	Long: `Make a task due --days days after its due date. An overdue task, or one
+without a due date, becomes due that many days from today.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTaskSnooze,
+}
+
+var taskMoveCmd = &cobra.Command{
+	Use:   "move <task>",
+	Short: "Move a task to another list",
+	Long: `Move a task to the list given with --to. Microsoft Graph can't move tasks,
+so the task is recreated in the target list and then deleted. The copy keeps
+the title, notes, importance, categories, dates, recurrence, checklist and
+linked resources, but gets a new ID and creation time, so its age restarts.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTaskMove,
+}
+
+var taskTagCmd = &cobra.Command{
+	Use:   "tag <task> <category>...",
+	Short: "Add categories to a task",
+	Args:  cobra.MinimumNArgs(2),
+	RunE:  runTaskTag,
+}
+
+func init() {
+	rootCmd.AddCommand(taskCmd)
+	taskCmd.AddCommand(taskCompleteCmd, taskSnoozeCmd, taskMoveCmd, taskTagCmd)
+
+	taskCmd.PersistentFlags().Bool("dry-run", false, "Print the Microsoft Graph requests instead of sending them")
+	taskCmd.PersistentFlags().String("list", "", "Only look for the task in this list (name or ID)")
+	taskSnoozeCmd.Flags().Int("days", 1, "Number of days to snooze the task for")
+	taskMoveCmd.Flags().String("to", "", "Target list (name or ID)")
+	_ = taskMoveCmd.MarkFlagRequired("to")
+}
+
+// taskSession holds what every task subcommand needs: an authorised client,
+// the open tasks of all lists, whatever the list filter says, and the clock
+// snoozes count from.
+type taskSession struct {
+	parser *todoclient.TodoParser
+	token  string
+	lists  []todo.TaskList
+	clock  clock.Clock
+	dryRun bool
+}
+
+func newTaskSession(cmd *cobra.Command) (*taskSession, error) {
+	ctx := cmd.Context()
+
+	clientID := viper.GetString("client-id")
+	if clientID == "" {
+		return nil, fmt.Errorf("client-id is required (use --client-id flag or AZURE_CLIENT_ID env var)")
+	}
+	authClient, err := createAuthClient(clientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create auth client: %w", err)
+	}
+	if err := authClient.Authenticate(ctx, logger); err != nil {
+		return nil, fmt.Errorf("authentication failed: %w", err)
+	}
+	token, err := authClient.GetAccessToken(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access token: %w", err)
+	}
+
+	cfg, err := todoParserConfig()
+	if err != nil {
+		return nil, err
+	}
+	clk := clock.Real()
+	// Tasks can be picked from, and moved to, lists the stats leave out.
+	parser := todoclient.New(cfg.WithListFilter(todoclient.ListFilter{Include: []string{"*"}}).WithClock(clk))
+
+	result, err := parser.GetTasks(ctx, logger, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task lists: %w", err)
+	}
+	for _, failed := range result.Failed {
+		fmt.Println(warningStyle.Render(fmt.Sprintf("⚠ List %s could not be read: %v", failed.ListName, failed.Err)))
+	}
+
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	return &taskSession{parser: parser, token: token, lists: result.TaskLists, clock: clk, dryRun: dryRun}, nil
+}
+
+// selectTask resolves the task argument, narrowed to the --list list if set.
+func (s *taskSession) selectTask(cmd *cobra.Command, query string) (todo.TaskRef, error) {
+	lists := s.lists
+	if listQuery, _ := cmd.Flags().GetString("list"); listQuery != "" {
+		list, err := s.selectList(listQuery)
+		if err != nil {
+			return todo.TaskRef{}, err
+		}
+		lists = []todo.TaskList{list}
+	}
+
+	refs := todo.FindTasks(lists, query)
+	switch len(refs) {
+	case 0:
+		return todo.TaskRef{}, fmt.Errorf("no open task matches %q", query)
+	case 1:
+		return refs[0], nil
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d tasks match %q, pass an ID instead:", len(refs), query)
+	for _, ref := range refs {
+		fmt.Fprintf(&sb, "\n  %s  %s / %s", ref.Task.ID, ref.List.Name, ref.Task.Title)
+	}
+	return todo.TaskRef{}, fmt.Errorf("%s", sb.String())
+}
+
+// selectList resolves a list name or ID.
+func (s *taskSession) selectList(query string) (todo.TaskList, error) {
+	return todo.FindList(s.lists, query)
+}
+
+// apply sends requests, or prints them on a dry run, and reports done.
+func (s *taskSession) apply(ctx context.Context, done string, requests ...todoclient.WriteRequest) error {
+	if s.dryRun {
+		fmt.Println(infoStyle.Render("Dry run, these requests would be sent:"))
+		for _, request := range requests {
+			fmt.Println(request.String())
+		}
+		return nil
+	}
+
+	if _, err := s.parser.Apply(ctx, logger, s.token, requests...); err != nil {
+		return err
+	}
+	fmt.Println(successStyle.Render("✓ " + done))
+	return nil
+}
+
+func runTaskComplete(cmd *cobra.Command, args []string) error {
+	session, err := newTaskSession(cmd)
+	if err != nil {
+		return err
+	}
+	ref, err := session.selectTask(cmd, args[0])
+	if err != nil {
+		return err
+	}
+
+	return session.apply(cmd.Context(),
+		fmt.Sprintf("Completed %q in %s", ref.Task.Title, ref.List.Name),
+		session.parser.CompleteRequest(ref.List.ID, ref.Task.ID))
+}
+
+func runTaskSnooze(cmd *cobra.Command, args []string) error {
+	days, _ := cmd.Flags().GetInt("days")
+	if days <= 0 {
+		return fmt.Errorf("--days must be positive, got %d", days)
+	}
+
+	session, err := newTaskSession(cmd)
+	if err != nil {
+		return err
+	}
+	ref, err := session.selectTask(cmd, args[0])
+	if err != nil {
+		return err
+	}
+
+	return session.apply(cmd.Context(),
+		fmt.Sprintf("Snoozed %q by %d day(s)", ref.Task.Title, days),
+		session.parser.SnoozeRequest(ref.List.ID, ref.Task, days, session.clock.Now()))
+}
+
+func runTaskMove(cmd *cobra.Command, args []string) error {
+	session, err := newTaskSession(cmd)
+	if err != nil {
+		return err
+	}
+	ref, err := session.selectTask(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	to, _ := cmd.Flags().GetString("to")
+	target, err := session.selectList(to)
+	if err != nil {
+		return err
+	}
+	if target.ID == ref.List.ID {
+		return fmt.Errorf("%q is already in %s", ref.Task.Title, target.Name)
+	}
+
+	return session.apply(cmd.Context(),
+		fmt.Sprintf("Moved %q from %s to %s", ref.Task.Title, ref.List.Name, target.Name),
+		session.parser.MoveRequests(ref.List.ID, ref.Task, target.ID)...)
+}
+
+func runTaskTag(cmd *cobra.Command, args []string) error {
+	session, err := newTaskSession(cmd)
+	if err != nil {
+		return err
+	}
+	ref, err := session.selectTask(cmd, args[0])
+	if err != nil {
+		return err
+	}
+
+	categories := args[1:]
+	return session.apply(cmd.Context(),
+		fmt.Sprintf("Tagged %q with %s", ref.Task.Title, strings.Join(categories, ", ")),
+		session.parser.TagRequest(ref.List.ID, ref.Task, categories))
+}
@@ -0,0 +1,55 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the current time. Code that computes ages or schedules work
+// takes a Clock instead of calling time.Now, so reports can be evaluated at
+// a historical instant and tests don't depend on the wall clock.
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// Real returns the wall clock.
+func Real() Clock {
+	return realClock{}
+}
+
+// Fixed is a Clock that only moves when told to.
+type Fixed struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFixed returns a clock stopped at now.
+func NewFixed(now time.Time) *Fixed {
+	return &Fixed{now: now}
+}
+
+func (f *Fixed) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Set moves the clock to now.
+func (f *Fixed) Set(now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = now
+}
+
+// Advance moves the clock forward by d.
+func (f *Fixed) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
@@ -0,0 +1,658 @@
+// Package graphfake serves the To Do part of Microsoft Graph from fixture
+// files so the Graph client and the collector can be tested end to end.
+// It supports paging, delta queries, throttling, per-list failures and
+// rejected tokens.
+package graphfake
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/uchr/ToDoInfo/internal/httpclient"
+	"github.com/uchr/ToDoInfo/internal/todo"
+)
+
+// Token is the access token the server accepts unless SetToken changes it.
+const Token = "fake-graph-token"
+
+//go:embed fixtures/*.json
+var fixtures embed.FS
+
+// Fixture is the content of a fixture file: the user's lists and their tasks.
+type Fixture struct {
+	Lists []ListFixture `json:"lists"`
+}
+
+// ListFixture is one To Do list. Tasks are kept as raw Graph JSON so fixtures
+// can carry any property the client understands.
+type ListFixture struct {
+	ID                string            `json:"id"`
+	DisplayName       string            `json:"displayName"`
+	WellknownListName string            `json:"wellknownListName"`
+	IsShared          bool              `json:"isShared"`
+	Tasks             []json.RawMessage `json:"tasks,omitempty"`
+}
+
+// Server is an httptest server speaking the Graph To Do endpoints under
+// URL()+"/v1.0". Every change made through its methods bumps a version that
+// delta links are issued against.
+type Server struct {
+	server *httptest.Server
+
+	mu          sync.Mutex
+	token       string
+	pageSize    int
+	version     int
+	expiredTill int
+	// listExpiredTill expires the task delta links of single lists.
+	listExpiredTill map[string]int
+	lists           []*list
+	throttle        int
+	retryAfter      time.Duration
+	dropped         int
+	failures        map[string]int
+	requests        int
+	created         int
+}
+
+type list struct {
+	info    ListFixture
+	version int
+	removed bool
+	tasks   []*task
+}
+
+type task struct {
+	id      string
+	status  string
+	raw     json.RawMessage
+	version int
+	removed bool
+}
+
+// New starts a server loaded with the named fixture (e.g. "basic") and
+// closes it when the test ends.
+func New(t testing.TB, fixture string) *Server {
+	t.Helper()
+
+	data, err := fixtures.ReadFile("fixtures/" + fixture + ".json")
+	if err != nil {
+		t.Fatalf("read fixture %q: %v", fixture, err)
+	}
+	var f Fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		t.Fatalf("parse fixture %q: %v", fixture, err)
+	}
+
+	s := NewFromFixture(f)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// NewFromFixture starts a server serving f. Call Close when done.
+func NewFromFixture(f Fixture) *Server {
+	s := &Server{
+		token:           Token,
+		version:         1,
+		failures:        make(map[string]int),
+		listExpiredTill: make(map[string]int),
+	}
+	for _, lf := range f.Lists {
+		l := &list{info: lf, version: s.version}
+		for _, raw := range lf.Tasks {
+			l.tasks = append(l.tasks, newTask(raw, s.version))
+		}
+		l.info.Tasks = nil
+		s.lists = append(s.lists, l)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1.0/me/todo/lists/microsoft.graph.delta()", s.handleListsDelta)
+	mux.HandleFunc("GET /v1.0/me/todo/lists/{listID}/tasks", s.handleTasks)
+	mux.HandleFunc("GET /v1.0/me/todo/lists/{listID}/tasks/delta()", s.handleTasksDelta)
+	mux.HandleFunc("POST /v1.0/me/todo/lists/{listID}/tasks", s.handleCreateTask)
+	mux.HandleFunc("GET /v1.0/me/todo/lists/{listID}/tasks/{taskID}", s.handleGetTask)
+	mux.HandleFunc("PATCH /v1.0/me/todo/lists/{listID}/tasks/{taskID}", s.handleUpdateTask)
+	mux.HandleFunc("DELETE /v1.0/me/todo/lists/{listID}/tasks/{taskID}", s.handleDeleteTask)
+	s.server = httptest.NewServer(s.guard(mux))
+	return s
+}
+
+func newTask(raw json.RawMessage, version int) *task {
+	var head struct {
+		ID     string `json:"id"`
+		Status string `json:"status"`
+	}
+	_ = json.Unmarshal(raw, &head)
+	return &task{id: head.ID, status: head.Status, raw: raw, version: version}
+}
+
+// URL returns the Graph base URL to configure the client with.
+func (s *Server) URL() string {
+	return s.server.URL + "/v1.0"
+}
+
+// HTTPClient returns a Graph HTTP client for talking to the server: it has
+// its own unthrottled limiter and retries after a millisecond, so tests
+// don't wait on the shared limiter or real backoff.
+func (s *Server) HTTPClient() *httpclient.Client {
+	limiter := httpclient.NewLimiter(httpclient.LimiterConfig{Rate: 1000, MinRate: 1000, MaxRate: 1000, Burst: 1000})
+	cfg := httpclient.DefaultConfig().
+		WithBackoff(time.Millisecond, time.Millisecond).
+		WithTimeout(5 * time.Second).
+		WithLimiter(limiter)
+	return httpclient.New(cfg)
+}
+
+// Close shuts the server down.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Requests returns how many requests the server has received.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// SetToken changes the accepted access token; requests with any other token
+// get 401 InvalidAuthenticationToken.
+func (s *Server) SetToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = token
+}
+
+// SetPageSize caps every page at n items regardless of $top. Zero honours $top.
+func (s *Server) SetPageSize(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pageSize = n
+}
+
+// Throttle answers the next n requests with 429, sending Retry-After when
+// retryAfter is positive.
+func (s *Server) Throttle(n int, retryAfter time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.throttle = n
+	s.retryAfter = retryAfter
+}
+
+// DropResponses handles the next n requests but closes their connections
+// instead of answering, as when a response is lost on the way back.
+func (s *Server) DropResponses(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dropped = n
+}
+
+// FailList answers requests for the tasks of listID with status until
+// cleared with a zero status.
+func (s *Server) FailList(listID string, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if status == 0 {
+		delete(s.failures, listID)
+		return
+	}
+	s.failures[listID] = status
+}
+
+// ExpireDeltaLinks makes every delta link issued so far fail with
+// syncStateNotFound, forcing the client to resync.
+func (s *Server) ExpireDeltaLinks() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.version++
+	s.expiredTill = s.version
+}
+
+// ExpireTaskDeltaLinks makes the task delta links of listID issued so far
+// fail with syncStateNotFound, leaving every other delta link valid.
+func (s *Server) ExpireTaskDeltaLinks(listID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.version++
+	s.listExpiredTill[listID] = s.version
+}
+
+// PutTask adds t to listID or replaces the task with the same ID.
+func (s *Server) PutTask(listID string, t todo.Task) {
+	raw, err := json.Marshal(&t)
+	if err != nil {
+		panic(fmt.Sprintf("graphfake: marshal task: %v", err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l := s.findList(listID)
+	if l == nil {
+		panic(fmt.Sprintf("graphfake: unknown list %q", listID))
+	}
+	s.version++
+	for _, existing := range l.tasks {
+		if existing.id == t.ID {
+			*existing = *newTask(raw, s.version)
+			return
+		}
+	}
+	l.tasks = append(l.tasks, newTask(raw, s.version))
+}
+
+// RemoveTask deletes a task; delta queries report it as @removed.
+func (s *Server) RemoveTask(listID, taskID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if l := s.findList(listID); l != nil {
+		s.version++
+		for _, t := range l.tasks {
+			if t.id == taskID {
+				t.removed = true
+				t.version = s.version
+			}
+		}
+	}
+}
+
+// RemoveList deletes a list; the lists delta reports it as @removed.
+func (s *Server) RemoveList(listID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if l := s.findList(listID); l != nil {
+		s.version++
+		l.removed = true
+		l.version = s.version
+	}
+}
+
+// Task returns the current state of a task, if it exists.
+func (s *Server) Task(listID, taskID string) (todo.Task, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l := s.findList(listID)
+	if l == nil {
+		return todo.Task{}, false
+	}
+	t := l.findTask(taskID)
+	if t == nil {
+		return todo.Task{}, false
+	}
+	var result todo.Task
+	if err := json.Unmarshal(t.raw, &result); err != nil {
+		panic(fmt.Sprintf("graphfake: unmarshal task: %v", err))
+	}
+	return result, true
+}
+
+// Tasks returns the tasks of a list, in creation order.
+func (s *Server) Tasks(listID string) []todo.Task {
+	s.mu.Lock()
+	var ids []string
+	if l := s.findList(listID); l != nil {
+		for _, t := range l.tasks {
+			if !t.removed {
+				ids = append(ids, t.id)
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	var tasks []todo.Task
+	for _, id := range ids {
+		if t, ok := s.Task(listID, id); ok {
+			tasks = append(tasks, t)
+		}
+	}
+	return tasks
+}
+
+func (l *list) findTask(id string) *task {
+	for _, t := range l.tasks {
+		if t.id == id && !t.removed {
+			return t
+		}
+	}
+	return nil
+}
+
+func (s *Server) findList(id string) *list {
+	for _, l := range s.lists {
+		if l.info.ID == id && !l.removed {
+			return l
+		}
+	}
+	return nil
+}
+
+// guard counts requests and applies token checks and throttling before
+// the request reaches its handler.
+func (s *Server) guard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests++
+		token := s.token
+		throttled := s.throttle > 0
+		retryAfter := s.retryAfter
+		if throttled {
+			s.throttle--
+		}
+		drop := !throttled && s.dropped > 0
+		if drop {
+			s.dropped--
+		}
+		s.mu.Unlock()
+
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			writeError(w, http.StatusUnauthorized, "InvalidAuthenticationToken", "Access token validation failure.")
+			return
+		}
+		if throttled {
+			if retryAfter > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+			}
+			writeError(w, http.StatusTooManyRequests, "TooManyRequests", "Too many requests.")
+			return
+		}
+		if drop {
+			next.ServeHTTP(httptest.NewRecorder(), r)
+			if conn, _, err := http.NewResponseController(w).Hijack(); err == nil {
+				conn.Close()
+			}
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleListsDelta(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	since, ok := s.deltaToken(w, r, s.expiredTill)
+	if !ok {
+		return
+	}
+
+	var items []any
+	for _, l := range s.lists {
+		if l.version <= since {
+			continue
+		}
+		if l.removed {
+			if since > 0 {
+				items = append(items, removedItem(l.info.ID))
+			}
+			continue
+		}
+		items = append(items, l.info)
+	}
+	s.writePage(w, r, items, true)
+}
+
+func (s *Server) handleTasks(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.taskList(w, r)
+	if !ok {
+		return
+	}
+
+	// Only the status clause of $filter is honoured.
+	filter := r.URL.Query().Get("$filter")
+	var items []any
+	for _, t := range l.tasks {
+		if t.removed || !strings.Contains(filter, "'"+t.status+"'") {
+			continue
+		}
+		items = append(items, taskItem(t, r))
+	}
+	s.writePage(w, r, items, false)
+}
+
+func (s *Server) handleTasksDelta(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Expired links are rejected before FailList applies to the list.
+	since, ok := s.deltaToken(w, r, max(s.expiredTill, s.listExpiredTill[r.PathValue("listID")]))
+	if !ok {
+		return
+	}
+	l, ok := s.taskList(w, r)
+	if !ok {
+		return
+	}
+
+	var items []any
+	for _, t := range l.tasks {
+		if t.version <= since {
+			continue
+		}
+		if t.removed {
+			if since > 0 {
+				items = append(items, removedItem(t.id))
+			}
+			continue
+		}
+		items = append(items, taskItem(t, r))
+	}
+	s.writePage(w, r, items, true)
+}
+
+func (s *Server) handleGetTask(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.requestTask(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(taskItem(t, r))
+}
+
+// taskItem renders t for a response. Like Graph, it inlines checklist items
+// and linked resources only when $expand asks for them.
+func taskItem(t *task, r *http.Request) any {
+	if strings.Contains(r.URL.Query().Get("$expand"), "checklistItems") {
+		return t.raw
+	}
+	fields := map[string]any{}
+	_ = json.Unmarshal(t.raw, &fields)
+	delete(fields, "checklistItems")
+	delete(fields, "linkedResources")
+	return fields
+}
+
+func (s *Server) handleCreateTask(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.taskList(w, r)
+	if !ok {
+		return
+	}
+	fields, ok := readFields(w, r)
+	if !ok {
+		return
+	}
+
+	s.version++
+	s.created++
+	now := time.Now().UTC().Format(time.RFC3339)
+	fields["id"] = fmt.Sprintf("created-%d", s.created)
+	fields["createdDateTime"] = now
+	fields["lastModifiedDateTime"] = now
+	if _, ok := fields["status"]; !ok {
+		fields["status"] = todo.StatusNotStarted
+	}
+	raw, _ := json.Marshal(fields)
+	l.tasks = append(l.tasks, newTask(raw, s.version))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_, _ = w.Write(raw)
+}
+
+func (s *Server) handleUpdateTask(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.requestTask(w, r)
+	if !ok {
+		return
+	}
+	patch, ok := readFields(w, r)
+	if !ok {
+		return
+	}
+
+	fields := map[string]any{}
+	_ = json.Unmarshal(t.raw, &fields)
+	for k, v := range patch {
+		fields[k] = v
+	}
+	fields["lastModifiedDateTime"] = time.Now().UTC().Format(time.RFC3339)
+	raw, _ := json.Marshal(fields)
+	s.version++
+	*t = *newTask(raw, s.version)
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(raw)
+}
+
+func (s *Server) handleDeleteTask(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.requestTask(w, r)
+	if !ok {
+		return
+	}
+	s.version++
+	t.removed = true
+	t.version = s.version
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requestTask resolves the task of a single-task request, answering 404 when it
+// doesn't exist.
+func (s *Server) requestTask(w http.ResponseWriter, r *http.Request) (*task, bool) {
+	l, ok := s.taskList(w, r)
+	if !ok {
+		return nil, false
+	}
+	t := l.findTask(r.PathValue("taskID"))
+	if t == nil {
+		writeError(w, http.StatusNotFound, "ErrorItemNotFound", "The specified object was not found in the store.")
+		return nil, false
+	}
+	return t, true
+}
+
+func readFields(w http.ResponseWriter, r *http.Request) (map[string]any, bool) {
+	fields := map[string]any{}
+	if err := json.NewDecoder(r.Body).Decode(&fields); err != nil {
+		writeError(w, http.StatusBadRequest, "invalidRequest", fmt.Sprintf("Invalid JSON body: %v.", err))
+		return nil, false
+	}
+	return fields, true
+}
+
+// taskList resolves the list of a tasks request, answering 404 or the
+// configured failure when it can't be served.
+func (s *Server) taskList(w http.ResponseWriter, r *http.Request) (*list, bool) {
+	listID := r.PathValue("listID")
+	if status, ok := s.failures[listID]; ok {
+		writeError(w, status, http.StatusText(status), fmt.Sprintf("Simulated failure for list %s.", listID))
+		return nil, false
+	}
+	l := s.findList(listID)
+	if l == nil {
+		writeError(w, http.StatusNotFound, "ErrorItemNotFound", "The specified object was not found in the store.")
+		return nil, false
+	}
+	return l, true
+}
+
+// deltaToken returns the version a delta link was issued at, zero for an
+// initial delta query. Expired tokens are answered with syncStateNotFound.
+func (s *Server) deltaToken(w http.ResponseWriter, r *http.Request, expiredTill int) (int, bool) {
+	value := r.URL.Query().Get("$deltatoken")
+	if value == "" {
+		return 0, true
+	}
+	since, err := strconv.Atoi(value)
+	if err != nil || since < expiredTill {
+		writeError(w, http.StatusGone, "syncStateNotFound", "The sync state generation is not found.")
+		return 0, false
+	}
+	return since, true
+}
+
+// writePage writes the page of items selected by $top and $skiptoken,
+// linking to the next page or, for delta queries, to the next delta round.
+func (s *Server) writePage(w http.ResponseWriter, r *http.Request, items []any, delta bool) {
+	query := r.URL.Query()
+	size := len(items)
+	if top, err := strconv.Atoi(query.Get("$top")); err == nil && top > 0 {
+		size = top
+	}
+	if s.pageSize > 0 {
+		size = min(size, s.pageSize)
+	}
+	skip, _ := strconv.Atoi(query.Get("$skiptoken"))
+	end := min(skip+max(size, 1), len(items))
+	skip = min(skip, end)
+
+	page := map[string]any{"value": items[skip:end]}
+	if end < len(items) {
+		next := cloneQuery(query)
+		next.Set("$skiptoken", strconv.Itoa(end))
+		page["@odata.nextLink"] = s.link(r, next)
+	} else if delta {
+		next := url.Values{}
+		next.Set("$deltatoken", strconv.Itoa(s.version))
+		page["@odata.deltaLink"] = s.link(r, next)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(page)
+}
+
+func (s *Server) link(r *http.Request, query url.Values) string {
+	return s.server.URL + r.URL.Path + "?" + query.Encode()
+}
+
+func cloneQuery(query url.Values) url.Values {
+	clone := url.Values{}
+	for k, v := range query {
+		clone[k] = append([]string(nil), v...)
+	}
+	return clone
+}
+
+func removedItem(id string) map[string]any {
+	return map[string]any{"id": id, "@removed": map[string]string{"reason": "deleted"}}
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("request-id", fmt.Sprintf("fake-%d-%s", status, code))
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]string{"code": code, "message": message},
+	})
+}
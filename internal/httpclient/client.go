@@ -0,0 +1,284 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Client sends Graph requests with a per-attempt timeout and retries
+// throttled (429) and failed (5xx) requests with exponential backoff and
+// jitter, honouring Retry-After; Send narrows this for writes that mustn't
+// run twice. Every attempt is paced by a Limiter.
+// It is safe for concurrent use.
+type Client struct {
+	http    *http.Client
+	config  *Config
+	limiter *Limiter
+	// sleep waits between attempts; tests replace it to avoid real delays.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// New creates a Client. A nil cfg uses DefaultConfig.
+func New(cfg *Config) *Client {
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+	limiter := cfg.Limiter
+	if limiter == nil {
+		limiter = sharedLimiter
+	}
+	return &Client{
+		http:    &http.Client{},
+		config:  cfg,
+		limiter: limiter,
+		sleep:   sleepContext,
+	}
+}
+
+// Stats returns the state of the client's Limiter.
+func (c *Client) Stats() LimiterStats {
+	return c.limiter.Stats()
+}
+
+// Get sends a GET authorised with token and returns the body of a 2xx response.
+func (c *Client) Get(ctx context.Context, logger *slog.Logger, requestUrl string, token string) ([]byte, error) {
+	return c.Do(ctx, logger, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestUrl, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Add("Authorization", "Bearer "+token)
+		return req, nil
+	})
+}
+
+// Send sends an authorised request with an optional JSON body, for the
+// PATCH, POST and DELETE calls that change tasks. Retries follow the
+// method: a POST is only resent when it was throttled or never reached the
+// server, so a task isn't created twice, and a DELETE that finds the task
+// gone on a retry succeeds, as an earlier attempt deleted it.
+func (c *Client) Send(ctx context.Context, logger *slog.Logger, method string, requestUrl string, token string, body []byte) ([]byte, error) {
+	return c.do(ctx, logger, retryPolicyFor(method), func(ctx context.Context) (*http.Request, error) {
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, requestUrl, reader)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Add("Authorization", "Bearer "+token)
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		return req, nil
+	})
+}
+
+// Post sends a form and returns the response body. Token endpoints report
+// errors in the body, so non-2xx bodies are returned along with the error.
+func (c *Client) Post(ctx context.Context, logger *slog.Logger, requestUrl string, values url.Values) ([]byte, error) {
+	encoded := values.Encode()
+	return c.Do(ctx, logger, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, requestUrl, strings.NewReader(encoded))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req, nil
+	})
+}
+
+// Do sends the request built by newRequest, retrying as configured.
+// newRequest is called once per attempt so request bodies can be re-read.
+// A non-2xx response that isn't retried becomes a *ResponseError.
+func (c *Client) Do(ctx context.Context, logger *slog.Logger, newRequest func(ctx context.Context) (*http.Request, error)) ([]byte, error) {
+	return c.do(ctx, logger, retryPolicy{retry: retryable}, newRequest)
+}
+
+func (c *Client) do(ctx context.Context, logger *slog.Logger, policy retryPolicy, newRequest func(ctx context.Context) (*http.Request, error)) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		body, retryAfter, sent, err := c.attempt(ctx, logger, newRequest)
+		if err == nil {
+			return body, nil
+		}
+		if attempt > 0 && policy.notFoundIsDone && isNotFound(err) {
+			logger.InfoContext(ctx, "Retried request found nothing left to do", slog.Int("attempt", attempt+1))
+			return nil, nil
+		}
+		if attempt >= c.config.MaxRetries || ctx.Err() != nil || !policy.retry(err, sent) {
+			return body, err
+		}
+
+		delay := c.backoff(attempt)
+		if retryAfter > 0 {
+			delay = retryAfter
+		}
+		logger.WarnContext(ctx, "Retrying request",
+			slog.Int("attempt", attempt+1),
+			slog.Duration("delay", delay),
+			slog.Any("error", err))
+
+		if err := c.sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// attempt sends the request once. sent reports whether any of it was
+// written to a connection, after which the server may have acted on it.
+func (c *Client) attempt(ctx context.Context, logger *slog.Logger, newRequest func(ctx context.Context) (*http.Request, error)) (body []byte, retryAfter time.Duration, sent bool, err error) {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, 0, false, fmt.Errorf("wait for rate limiter: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.config.Timeout)
+	defer cancel()
+
+	var wrote atomic.Bool
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		WroteHeaders: func() { wrote.Store(true) },
+	})
+
+	req, err := newRequest(ctx)
+	if err != nil {
+		return nil, 0, false, err
+	}
+
+	response, err := c.http.Do(req)
+	if err != nil {
+		return nil, 0, wrote.Load(), err
+	}
+
+	defer func() {
+		err := response.Body.Close()
+		if err != nil {
+			logger.ErrorContext(ctx, "Error closing response body", slog.Any("error", err))
+		}
+	}()
+
+	body, err = io.ReadAll(response.Body)
+	if err != nil {
+		return nil, 0, true, err
+	}
+
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		c.limiter.Succeeded()
+		return body, 0, true, nil
+	}
+
+	retryAfter = parseRetryAfter(response.Header.Get("Retry-After"), time.Now())
+	if response.StatusCode == http.StatusTooManyRequests || retryAfter > 0 {
+		c.limiter.Throttled(retryAfter)
+	}
+
+	respErr := &ResponseError{StatusCode: response.StatusCode, Code: http.StatusText(response.StatusCode)}
+	var graphErr *ResponseError
+	if errors.As(GetResponseError(body), &graphErr) {
+		respErr.Code = graphErr.Code
+		respErr.Message = graphErr.Message
+		respErr.RequestID = graphErr.RequestID
+	}
+	if id := response.Header.Get("request-id"); id != "" {
+		respErr.RequestID = id
+	}
+
+	return body, retryAfter, true, respErr
+}
+
+// backoff returns the jittered delay before retry attempt+1: a random value
+// between half and all of BaseBackoff*2^attempt, capped at MaxBackoff.
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.config.BaseBackoff << min(attempt, 30)
+	if delay <= 0 || delay > c.config.MaxBackoff {
+		delay = c.config.MaxBackoff
+	}
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// retryPolicy decides which failed attempts of a request are sent again.
+type retryPolicy struct {
+	// retry reports whether err is worth another attempt; sent is whether
+	// the failed attempt reached a connection.
+	retry func(err error, sent bool) bool
+	// notFoundIsDone treats a 404 on a retry as success, for requests whose
+	// earlier attempt may have removed the resource.
+	notFoundIsDone bool
+}
+
+// retryPolicyFor returns the policy Send applies to method.
+func retryPolicyFor(method string) retryPolicy {
+	switch method {
+	case http.MethodPost:
+		return retryPolicy{retry: retryableUnsent}
+	case http.MethodDelete:
+		return retryPolicy{retry: retryable, notFoundIsDone: true}
+	}
+	return retryPolicy{retry: retryable}
+}
+
+// retryable reports whether err is worth another attempt: throttling, server
+// errors and transport failures are.
+func retryable(err error, sent bool) bool {
+	var respErr *ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.Retryable()
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// retryableUnsent is retryable for requests that mustn't run twice: only
+// throttling, which Graph rejects before acting, and transport failures
+// before anything was sent are retried.
+func retryableUnsent(err error, sent bool) bool {
+	var respErr *ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.StatusCode == http.StatusTooManyRequests
+	}
+	var urlErr *url.Error
+	return !sent && errors.As(err, &urlErr)
+}
+
+func isNotFound(err error) bool {
+	var respErr *ResponseError
+	return errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound
+}
+
+// parseRetryAfter reads delay-seconds or an HTTP date; zero means absent.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("wait before retry: %w", ctx.Err())
+	case <-timer.C:
+		return nil
+	}
+}
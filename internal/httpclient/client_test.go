@@ -0,0 +1,255 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uchr/ToDoInfo/internal/clock"
+)
+
+// newTestClient returns a client whose waits are recorded instead of slept.
+// Its limiter runs on a fixed clock that each recorded wait advances.
+func newTestClient(cfg *Config) (*Client, *[]time.Duration) {
+	clk := clock.NewFixed(time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC))
+	limiter := NewLimiter(DefaultLimiterConfig())
+	limiter.clock = clk
+
+	client := New(cfg.WithLimiter(limiter))
+	var sleeps []time.Duration
+	client.sleep = func(ctx context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		clk.Advance(d)
+		return nil
+	}
+	return client, &sleeps
+}
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestClientRetriesServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"value":[]}`))
+	}))
+	defer server.Close()
+
+	client, sleeps := newTestClient(DefaultConfig().WithBackoff(100*time.Millisecond, time.Second))
+	body, err := client.Get(t.Context(), testLogger, server.URL, "token")
+	require.NoError(t, err)
+	assert.Equal(t, `{"value":[]}`, string(body))
+	assert.Equal(t, int32(3), calls.Load())
+
+	require.Len(t, *sleeps, 2)
+	assert.GreaterOrEqual(t, (*sleeps)[0], 50*time.Millisecond)
+	assert.LessOrEqual(t, (*sleeps)[0], 100*time.Millisecond)
+	assert.GreaterOrEqual(t, (*sleeps)[1], 100*time.Millisecond)
+	assert.LessOrEqual(t, (*sleeps)[1], 200*time.Millisecond)
+}
+
+func TestClientHonoursRetryAfter(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	client, sleeps := newTestClient(DefaultConfig())
+	_, err := client.Get(t.Context(), testLogger, server.URL, "token")
+	require.NoError(t, err)
+	assert.Equal(t, []time.Duration{7 * time.Second}, *sleeps)
+
+	stats := client.Stats()
+	assert.Equal(t, 1, stats.Throttled)
+	assert.Less(t, stats.Rate, DefaultLimiterConfig().Rate)
+}
+
+func TestClientReturnsTypedErrors(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("request-id", "req-123")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":{"code":"ErrorItemNotFound","message":"The list was not found."}}`))
+	}))
+	defer server.Close()
+
+	client, sleeps := newTestClient(DefaultConfig())
+	_, err := client.Get(t.Context(), testLogger, server.URL, "token")
+
+	var respErr *ResponseError
+	require.True(t, errors.As(err, &respErr))
+	assert.Equal(t, &ResponseError{
+		StatusCode: http.StatusNotFound,
+		Code:       "ErrorItemNotFound",
+		Message:    "The list was not found.",
+		RequestID:  "req-123",
+	}, respErr)
+	assert.Equal(t, int32(1), calls.Load(), "4xx other than 429 must not be retried")
+	assert.Empty(t, *sleeps)
+}
+
+func TestClientGivesUpAfterMaxRetries(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client, _ := newTestClient(DefaultConfig().WithMaxRetries(2))
+	_, err := client.Get(t.Context(), testLogger, server.URL, "token")
+
+	var respErr *ResponseError
+	require.True(t, errors.As(err, &respErr))
+	assert.Equal(t, http.StatusBadGateway, respErr.StatusCode)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestClientTimesOutSlowAttempts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	client, _ := newTestClient(DefaultConfig().WithTimeout(20 * time.Millisecond).WithMaxRetries(0))
+	_, err := client.Get(t.Context(), testLogger, server.URL, "token")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestClientSendsJSONBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPatch, r.Method)
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(r.Body)
+		assert.JSONEq(t, `{"status":"completed"}`, string(body))
+		w.Write([]byte(`{"id":"1"}`))
+	}))
+	defer server.Close()
+
+	client, _ := newTestClient(DefaultConfig())
+	body, err := client.Send(t.Context(), testLogger, http.MethodPatch, server.URL, "token", []byte(`{"status":"completed"}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":"1"}`, string(body))
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestSendRetriesUnsentPost(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":"1"}`))
+	}))
+	defer server.Close()
+
+	client, sleeps := newTestClient(DefaultConfig())
+	var dials atomic.Int32
+	client.http.Transport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if dials.Add(1) == 1 {
+			return nil, errors.New("connection refused")
+		}
+		return http.DefaultTransport.RoundTrip(req)
+	})
+
+	body, err := client.Send(t.Context(), testLogger, http.MethodPost, server.URL, "token", []byte(`{"title":"a"}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":"1"}`, string(body))
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Len(t, *sleeps, 1)
+}
+
+func TestSendDoesNotResendPost(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client, _ := newTestClient(DefaultConfig())
+	_, err := client.Send(t.Context(), testLogger, http.MethodPost, server.URL, "token", []byte(`{"title":"a"}`))
+
+	var respErr *ResponseError
+	require.True(t, errors.As(err, &respErr))
+	assert.Equal(t, http.StatusServiceUnavailable, respErr.StatusCode)
+	assert.Equal(t, int32(1), calls.Load(), "the server may have created the task")
+}
+
+func TestSendRetriesThrottledPost(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	client, _ := newTestClient(DefaultConfig())
+	_, err := client.Send(t.Context(), testLogger, http.MethodPost, server.URL, "token", []byte(`{"title":"a"}`))
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestSendTreatsNotFoundOnRetriedDeleteAsDone(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client, _ := newTestClient(DefaultConfig())
+	_, err := client.Send(t.Context(), testLogger, http.MethodDelete, server.URL, "token", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), calls.Load())
+
+	// A 404 on the first attempt still fails.
+	_, err = client.Send(t.Context(), testLogger, http.MethodDelete, server.URL, "token", nil)
+	var respErr *ResponseError
+	require.True(t, errors.As(err, &respErr))
+	assert.Equal(t, http.StatusNotFound, respErr.StatusCode)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Duration(0), parseRetryAfter("", now))
+	assert.Equal(t, 3*time.Second, parseRetryAfter("3", now))
+	assert.Equal(t, 90*time.Second, parseRetryAfter("Wed, 14 Oct 2026 12:01:30 GMT", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("Wed, 14 Oct 2026 11:00:00 GMT", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("soon", now))
+}
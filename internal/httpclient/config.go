@@ -0,0 +1,60 @@
+package httpclient
+
+import "time"
+
+// Config holds the timeout and retry settings of a Client
+type Config struct {
+	// Timeout bounds a single attempt, including reading the body.
+	Timeout time.Duration
+	// MaxRetries is how many times a throttled or failed request is retried.
+	MaxRetries int
+	// BaseBackoff is the delay before the first retry; it doubles per attempt.
+	BaseBackoff time.Duration
+	// MaxBackoff caps the computed delay. A longer Retry-After is still honoured.
+	MaxBackoff time.Duration
+	// Limiter paces requests. Nil shares one process-wide Limiter.
+	Limiter *Limiter
+}
+
+// DefaultConfig returns the default HTTP client configuration
+func DefaultConfig() *Config {
+	return &Config{
+		Timeout:     30 * time.Second,
+		MaxRetries:  4,
+		BaseBackoff: 500 * time.Millisecond,
+		MaxBackoff:  30 * time.Second,
+	}
+}
+
+// WithTimeout sets the per-attempt timeout. Non-positive values keep the default.
+func (c *Config) WithTimeout(timeout time.Duration) *Config {
+	if timeout > 0 {
+		c.Timeout = timeout
+	}
+	return c
+}
+
+// WithMaxRetries sets the retry count. Negative values keep the default; zero disables retries.
+func (c *Config) WithMaxRetries(retries int) *Config {
+	if retries >= 0 {
+		c.MaxRetries = retries
+	}
+	return c
+}
+
+// WithBackoff sets the first and the largest computed retry delay. Non-positive values keep the defaults.
+func (c *Config) WithBackoff(base, max time.Duration) *Config {
+	if base > 0 {
+		c.BaseBackoff = base
+	}
+	if max > 0 {
+		c.MaxBackoff = max
+	}
+	return c
+}
+
+// WithLimiter gives the client its own Limiter instead of the shared one.
+func (c *Config) WithLimiter(limiter *Limiter) *Config {
+	c.Limiter = limiter
+	return c
+}
@@ -1,15 +1,47 @@
 package httpclient
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 const (
 	InvalidAuthenticationTokenCode = "InvalidAuthenticationToken"
+	SyncStateNotFoundCode          = "syncStateNotFound"
+	ResyncRequiredCode             = "resyncRequired"
 )
 
+// ResponseError is a Graph error response. StatusCode is zero when the error
+// was found in the body of an otherwise successful response.
 type ResponseError struct {
-	Code string
+	StatusCode int
+	Code       string
+	Message    string
+	// RequestID identifies the request in Graph logs, for support tickets.
+	RequestID string
 }
 
 func (e *ResponseError) Error() string {
-	return fmt.Sprintf("response error '%s'", e.Code)
+	var sb strings.Builder
+	sb.WriteString("response error")
+	if e.StatusCode != 0 {
+		fmt.Fprintf(&sb, " %d", e.StatusCode)
+	}
+	fmt.Fprintf(&sb, " '%s'", e.Code)
+	if e.Message != "" {
+		fmt.Fprintf(&sb, ": %s", e.Message)
+	}
+	if e.RequestID != "" {
+		fmt.Fprintf(&sb, " (request-id %s)", e.RequestID)
+	}
+	return sb.String()
+}
+
+// Retryable reports whether the request may succeed if sent again.
+func (e *ResponseError) Retryable() bool {
+	return isRetryableStatus(e.StatusCode)
+}
+
+func isRetryableStatus(status int) bool {
+	return status == 429 || (status >= 500 && status != 501 && status != 505)
 }
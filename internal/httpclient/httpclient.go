@@ -3,21 +3,22 @@ package httpclient
 import (
 	"context"
 	"encoding/json"
-	"io"
 	"log/slog"
-	"net/http"
 	"net/url"
-	"strings"
 )
 
-func GetResponseError(data []byte) error {
-	type errorInfo struct {
-		Code string `json:"code"`
-	}
-	type errorResponse struct {
-		Error errorInfo `json:"error"`
-	}
+// errorResponse is the body Graph sends with a failed request.
+type errorResponse struct {
+	Error struct {
+		Code       string `json:"code"`
+		Message    string `json:"message"`
+		InnerError struct {
+			RequestID string `json:"request-id"`
+		} `json:"innerError"`
+	} `json:"error"`
+}
 
+func GetResponseError(data []byte) error {
 	errInfo := errorResponse{}
 	err := json.Unmarshal(data, &errInfo)
 	if err != nil {
@@ -27,7 +28,11 @@ func GetResponseError(data []byte) error {
 		return nil
 	}
 
-	return &ResponseError{errInfo.Error.Code}
+	return &ResponseError{
+		Code:      errInfo.Error.Code,
+		Message:   errInfo.Error.Message,
+		RequestID: errInfo.Error.InnerError.RequestID,
+	}
 }
 
 func GetAuthError(data []byte) error {
@@ -45,62 +50,17 @@ func GetAuthError(data []byte) error {
 		return nil
 	}
 
-	return &ResponseError{errInfo.ErrorDescription}
+	return &ResponseError{Code: errInfo.Error, Message: errInfo.ErrorDescription}
 }
 
-func Post(ctx context.Context, logger *slog.Logger, requestUrl string, values url.Values) ([]byte, error) {
-	req, err := http.NewRequestWithContext(ctx, "POST", requestUrl, strings.NewReader(values.Encode()))
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-
-	response, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-
-	defer func() {
-		err := response.Body.Close()
-		if err != nil {
-			logger.ErrorContext(ctx, "Error closing response body", slog.Any("error", err))
-		}
-	}()
+var defaultClient = New(DefaultConfig())
 
-	body, err := io.ReadAll(response.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	return body, nil
+// Post sends a form with the default client.
+func Post(ctx context.Context, logger *slog.Logger, requestUrl string, values url.Values) ([]byte, error) {
+	return defaultClient.Post(ctx, logger, requestUrl, values)
 }
 
+// GetRequest sends an authorised GET with the default client.
 func GetRequest(ctx context.Context, logger *slog.Logger, requestUrl string, token string) ([]byte, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", requestUrl, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	var bearer = "Bearer " + token
-	req.Header.Add("Authorization", bearer)
-
-	response, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-
-	defer func() {
-		err := response.Body.Close()
-		if err != nil {
-			logger.ErrorContext(ctx, "Error closing response body", slog.Any("error", err))
-		}
-	}()
-
-	body, err := io.ReadAll(response.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	return body, nil
+	return defaultClient.Get(ctx, logger, requestUrl, token)
 }
@@ -0,0 +1,159 @@
+package httpclient
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/uchr/ToDoInfo/internal/clock"
+)
+
+// LimiterConfig holds the request-rate settings of a Limiter
+type LimiterConfig struct {
+	// Rate is the starting rate in requests per second.
+	Rate float64
+	// MinRate and MaxRate bound the rate as it adapts to throttling.
+	MinRate float64
+	MaxRate float64
+	// Burst is how many requests may go out back to back after an idle period.
+	Burst int
+	// Increase is added to the rate after every successful request.
+	Increase float64
+}
+
+// DefaultLimiterConfig starts at the 4 requests per second Microsoft To Do
+// tolerates and lets the rate adapt between 0.5 and 8.
+func DefaultLimiterConfig() LimiterConfig {
+	return LimiterConfig{
+		Rate:     4,
+		MinRate:  0.5,
+		MaxRate:  8,
+		Burst:    4,
+		Increase: 0.05,
+	}
+}
+
+// LimiterStats is a snapshot of a Limiter for diagnostics.
+type LimiterStats struct {
+	// Rate is the current allowed rate in requests per second.
+	Rate float64
+	// Waiting is the number of requests queued for a token.
+	Waiting int
+	// Throttled counts the 429 and Retry-After responses seen so far.
+	Throttled int
+	// PausedUntil is set while a Retry-After pause is in effect.
+	PausedUntil time.Time
+}
+
+// Limiter is a token bucket whose rate adapts to Graph throttling: it halves
+// on every 429 or Retry-After, pauses all callers for the requested time, and
+// creeps back up while requests succeed.
+type Limiter struct {
+	config LimiterConfig
+	clock  clock.Clock
+
+	mu          sync.Mutex
+	rate        float64
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+	waiting     int
+	throttled   int
+}
+
+// NewLimiter creates a Limiter with a full bucket.
+func NewLimiter(cfg LimiterConfig) *Limiter {
+	return &Limiter{
+		config: cfg,
+		clock:  clock.Real(),
+		rate:   cfg.Rate,
+		tokens: float64(cfg.Burst),
+	}
+}
+
+// sharedLimiter paces every Client that isn't given its own Limiter, so all
+// Graph calls made by the process share one budget.
+var sharedLimiter = NewLimiter(DefaultLimiterConfig())
+
+// Wait blocks until a request may be sent or ctx is done.
+func (l *Limiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	l.waiting++
+	l.mu.Unlock()
+	defer func() {
+		l.mu.Lock()
+		l.waiting--
+		l.mu.Unlock()
+	}()
+
+	for {
+		l.mu.Lock()
+		delay := l.reserve()
+		l.mu.Unlock()
+		if delay <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve takes a token and returns zero, or returns how long to wait for one.
+func (l *Limiter) reserve() time.Duration {
+	now := l.clock.Now()
+	if now.Before(l.pausedUntil) {
+		return l.pausedUntil.Sub(now)
+	}
+
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		l.tokens = min(l.tokens, float64(l.config.Burst))
+	}
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	return time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+}
+
+// Throttled halves the rate and, when retryAfter is positive, holds every
+// caller until it has passed.
+func (l *Limiter) Throttled(retryAfter time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.throttled++
+	l.rate = max(l.rate/2, l.config.MinRate)
+	l.tokens = 0
+	if until := l.clock.Now().Add(retryAfter); retryAfter > 0 && until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+}
+
+// Succeeded raises the rate a little, up to MaxRate.
+func (l *Limiter) Succeeded() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.rate = min(l.rate+l.config.Increase, l.config.MaxRate)
+}
+
+// Stats returns the current rate, queue depth and throttling count.
+func (l *Limiter) Stats() LimiterStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := LimiterStats{Rate: l.rate, Waiting: l.waiting, Throttled: l.throttled}
+	if l.clock.Now().Before(l.pausedUntil) {
+		stats.PausedUntil = l.pausedUntil
+	}
+	return stats
+}
@@ -0,0 +1,56 @@
+package httpclient
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uchr/ToDoInfo/internal/clock"
+)
+
+func TestLimiterAdaptsToThrottling(t *testing.T) {
+	clk := clock.NewFixed(time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC))
+	limiter := NewLimiter(LimiterConfig{Rate: 4, MinRate: 1, MaxRate: 5, Burst: 2, Increase: 0.5})
+	limiter.clock = clk
+
+	// The burst is available straight away; the next token takes 1/rate.
+	assert.Zero(t, limiter.reserve())
+	assert.Zero(t, limiter.reserve())
+	assert.Equal(t, 250*time.Millisecond, limiter.reserve())
+
+	limiter.Throttled(3 * time.Second)
+	assert.Equal(t, 3*time.Second, limiter.reserve(), "Retry-After pauses every caller")
+	stats := limiter.Stats()
+	assert.Equal(t, 2.0, stats.Rate)
+	assert.Equal(t, 1, stats.Throttled)
+	assert.Equal(t, clk.Now().Add(3*time.Second), stats.PausedUntil)
+
+	clk.Advance(3 * time.Second)
+	assert.Zero(t, limiter.reserve())
+
+	limiter.Throttled(0)
+	limiter.Throttled(0)
+	assert.Equal(t, 1.0, limiter.Stats().Rate, "rate never drops below MinRate")
+
+	for range 20 {
+		limiter.Succeeded()
+	}
+	assert.Equal(t, 5.0, limiter.Stats().Rate, "rate never exceeds MaxRate")
+}
+
+func TestLimiterWaitReportsQueueDepth(t *testing.T) {
+	limiter := NewLimiter(LimiterConfig{Rate: 1, MinRate: 1, MaxRate: 1, Burst: 1})
+	require.NoError(t, limiter.Wait(t.Context()))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error)
+	go func() { done <- limiter.Wait(ctx) }()
+
+	assert.Eventually(t, func() bool { return limiter.Stats().Waiting == 1 }, time.Second, time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	assert.Equal(t, 0, limiter.Stats().Waiting)
+}
@@ -7,8 +7,11 @@ import (
 	"sync"
 	"time"
 
-	"github.com/uchr/ToDoInfo/internal/auth"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/uchr/ToDoInfo/internal/clock"
 	"github.com/uchr/ToDoInfo/internal/storage"
+	"github.com/uchr/ToDoInfo/internal/todo"
 	"github.com/uchr/ToDoInfo/internal/todoclient"
 	"github.com/uchr/ToDoInfo/internal/todometrics"
 )
@@ -22,29 +25,86 @@ type StatsData struct {
 	SortedTasks []todometrics.TaskRottennessInfo
 	Champion    *todometrics.TaskRottennessInfo
 	TimeSeries  []storage.TimeSeriesPoint
+	// Throughput is nil unless completed tasks are fetched.
+	Throughput *todometrics.Throughput
+	// Policy is the rottenness ladder the tasks were classified with.
+	Policy todometrics.RottennessPolicy
+	// AgeMode describes how ages were counted, e.g. "calendar days".
+	AgeMode string
+	// RecurringTasks is only filled when recurring tasks are reported separately.
+	RecurringTasks []todometrics.TaskRottennessInfo
+	// MissingLists names the lists that failed to fetch; the stats cover the rest.
+	MissingLists []storage.MissingList
+	// ExcludedLists holds the lists the list filter left out.
+	ExcludedLists []todoclient.ExcludedList
+	// TaskLists holds the open tasks the stats were computed from.
+	TaskLists []todo.TaskList
+}
+
+// TokenSource supplies Graph access tokens. *auth.AuthClient implements it;
+// tests use a fixed token.
+type TokenSource interface {
+	// HasCredential reports whether authentication has completed.
+	HasCredential() bool
+	GetAccessToken(ctx context.Context) (string, error)
+}
+
+// Store keeps the snapshots and the sync state between refreshes. The
+// storage backends implement it.
+type Store interface {
+	storage.StatsStorage
+	storage.DeltaStateStorage
 }
 
 // Collector periodically fetches tasks from Microsoft Graph and caches stats.
+// After the first refresh only changes are downloaded: the Graph delta links
+// and the materialised task set are kept in storage between refreshes.
 type Collector struct {
-	authClient *auth.AuthClient
+	authClient TokenSource
+	parser     *todoclient.TodoParser
+	store      Store
 	logger     *slog.Logger
 	interval   time.Duration
+	clock      clock.Clock
+	metricsOpt []todometrics.Option
+	// retention prunes old snapshots after each refresh; nil keeps them all.
+	retention *storage.RetentionPolicy
+	// refreshing lets concurrent Refresh calls share the one in progress.
+	refreshing singleflight.Group
 
 	mu             sync.RWMutex
 	cached         *StatsData
 	lastRefreshAt  time.Time
 	lastRefreshErr error
+	// invalidated is set after a write so the next EnsureFresh refreshes.
+	invalidated bool
+	// generation counts Invalidate calls, so a refresh that started before
+	// a write doesn't clear the invalidation the write set.
+	generation int
 }
 
-// NewCollector creates a new Collector.
-func NewCollector(authClient *auth.AuthClient, logger *slog.Logger, interval time.Duration) *Collector {
+// NewCollector creates a new Collector. store is shared with the caller,
+// which closes it; clk stamps snapshots and ages tasks; metricsOpts are
+// applied to every todometrics.New call, e.g. to set the rottenness policy.
+func NewCollector(authClient TokenSource, parser *todoclient.TodoParser, store Store, logger *slog.Logger, interval time.Duration, clk clock.Clock, metricsOpts ...todometrics.Option) *Collector {
 	return &Collector{
 		authClient: authClient,
+		parser:     parser,
+		store:      store,
 		logger:     logger,
 		interval:   interval,
+		clock:      clk,
+		metricsOpt: append([]todometrics.Option{todometrics.WithClock(clk)}, metricsOpts...),
 	}
 }
 
+// WithRetention makes every refresh prune the stored snapshots with policy.
+// Call it before Run.
+func (c *Collector) WithRetention(policy storage.RetentionPolicy) *Collector {
+	c.retention = &policy
+	return c
+}
+
 // Run performs an immediate fetch, then refreshes on a ticker until ctx is cancelled.
 // Skips refresh attempts when the auth client is not yet authenticated.
 func (c *Collector) Run(ctx context.Context) error {
@@ -76,16 +136,32 @@ func (c *Collector) Run(ctx context.Context) error {
 }
 
 // Refresh fetches tasks, computes metrics, stores a snapshot, and updates the cache.
-func (c *Collector) Refresh(ctx context.Context) (retErr error) {
+// A call made while another is running waits for it and returns its result,
+// since two syncs would advance the same delta links.
+func (c *Collector) Refresh(ctx context.Context) error {
+	_, err, _ := c.refreshing.Do("refresh", func() (any, error) {
+		return nil, c.refresh(ctx)
+	})
+	return err
+}
+
+func (c *Collector) refresh(ctx context.Context) (retErr error) {
 	c.logger.Info("refreshing task data")
 
+	c.mu.RLock()
+	generation := c.generation
+	c.mu.RUnlock()
+
 	var fresh *StatsData
 	defer func() {
 		c.mu.Lock()
-		c.lastRefreshAt = time.Now()
+		c.lastRefreshAt = c.clock.Now()
 		c.lastRefreshErr = retErr
 		if fresh != nil {
 			c.cached = fresh
+			if c.generation == generation {
+				c.invalidated = false
+			}
 		}
 		c.mu.Unlock()
 	}()
@@ -95,19 +171,36 @@ func (c *Collector) Refresh(ctx context.Context) (retErr error) {
 		return fmt.Errorf("get access token: %w", err)
 	}
 
-	parser := todoclient.New()
-	taskLists, err := parser.GetTasks(ctx, c.logger, token)
+	// Incremental sync: apply Graph deltas on top of the stored task set.
+	deltaState, err := c.store.LoadDeltaState(ctx)
+	if err != nil {
+		c.logger.Warn("failed to load delta state, running full sync", slog.Any("error", err))
+		deltaState = todoclient.NewDeltaState()
+	}
+
+	result, err := c.parser.SyncTasks(ctx, c.logger, token, deltaState)
 	if err != nil {
 		return fmt.Errorf("get tasks: %w", err)
 	}
+	taskLists := result.TaskLists
+	missingLists := storage.NewMissingLists(result.Failed)
+	for _, missing := range missingLists {
+		c.logger.Warn("task list missing from refresh", slog.String("list", missing.Name), slog.String("error", missing.Error))
+	}
+
+	if err := c.store.SaveDeltaState(ctx, deltaState); err != nil {
+		c.logger.Warn("failed to save delta state", slog.Any("error", err))
+	}
 
-	metrics := todometrics.New(taskLists)
+	metrics := todometrics.New(taskLists, c.metricsOpt...)
 	sortedTasks := metrics.GetSortedTasks()
 	listAges := metrics.GetListAges()
 
 	totalAge := 0
 	for _, t := range sortedTasks {
-		totalAge += t.Age
+		if !t.ExcludedFromTotal {
+			totalAge += t.Age
+		}
 	}
 
 	var champion *todometrics.TaskRottennessInfo
@@ -115,59 +208,85 @@ func (c *Collector) Refresh(ctx context.Context) (retErr error) {
 		champion = &top[0]
 	}
 
-	// Store snapshot
-	dbPath, err := storage.DefaultDBPath()
-	if err != nil {
-		return fmt.Errorf("get db path: %w", err)
+	var throughput *todometrics.Throughput
+	if window := c.parser.CompletedWindow(); window > 0 {
+		t := metrics.GetThroughput(window)
+		throughput = &t
 	}
-	store, err := storage.NewSQLiteStorage(dbPath)
-	if err != nil {
-		return fmt.Errorf("create storage: %w", err)
-	}
-	defer store.Close()
 
+	// Store snapshot
 	snapshot := storage.StatsSnapshot{
-		Timestamp: time.Now(),
+		Timestamp: metrics.Now(),
 		GlobalStats: storage.GlobalStats{
 			TotalAge:  totalAge,
 			TaskCount: len(sortedTasks),
 		},
-		ListAges:  listAges,
-		TaskLists: taskLists,
+		ListAges:     listAges,
+		TaskLists:    taskLists,
+		Partial:      result.Partial(),
+		MissingLists: missingLists,
 	}
-	if err := store.Store(ctx, snapshot); err != nil {
+	if err := c.store.Store(ctx, snapshot); err != nil {
 		c.logger.Warn("failed to store snapshot", slog.Any("error", err))
 	}
+	if c.retention != nil {
+		c.prune(ctx, metrics.Now())
+	}
 
 	// Fetch time-series for chart data
-	timeSeries, err := store.GetTimeSeriesData(ctx, 90)
+	timeSeries, err := c.store.GetTimeSeriesData(ctx, metrics.Now(), 90)
 	if err != nil {
 		c.logger.Warn("failed to load time series", slog.Any("error", err))
 	}
 
 	fresh = &StatsData{
-		FetchedAt:   time.Now(),
-		TotalTasks:  len(sortedTasks),
-		TotalAge:    totalAge,
-		ListAges:    listAges,
-		SortedTasks: sortedTasks,
-		Champion:    champion,
-		TimeSeries:  timeSeries,
+		FetchedAt:      metrics.Now(),
+		TotalTasks:     len(sortedTasks),
+		TotalAge:       totalAge,
+		ListAges:       listAges,
+		SortedTasks:    sortedTasks,
+		Champion:       champion,
+		TimeSeries:     timeSeries,
+		Throughput:     throughput,
+		Policy:         metrics.Policy(),
+		AgeMode:        metrics.AgeMode(),
+		RecurringTasks: metrics.GetRecurringTasks(),
+		MissingLists:   missingLists,
+		ExcludedLists:  result.Excluded,
+		TaskLists:      taskLists,
 	}
 
-	c.logger.Info("refresh complete", slog.Int("tasks", len(sortedTasks)), slog.Int("totalAge", totalAge))
+	httpStats := c.parser.HTTPStats()
+	c.logger.Info("refresh complete", slog.Int("tasks", len(sortedTasks)), slog.Int("totalAge", totalAge),
+		slog.Int("missingLists", len(missingLists)),
+		slog.Float64("graphRate", httpStats.Rate), slog.Int("graphThrottled", httpStats.Throttled))
 	return nil
 }
 
-// EnsureFresh runs Refresh if the last successful refresh is older than maxAge,
-// otherwise returns nil immediately. Errors from Refresh propagate to the caller.
+// prune applies the retention policy. Failures are only logged: the
+// snapshot is stored and the refresh still counts.
+func (c *Collector) prune(ctx context.Context, now time.Time) {
+	result, err := c.store.Prune(ctx, *c.retention, now)
+	if err != nil {
+		c.logger.Warn("failed to prune snapshots", slog.Any("error", err))
+		return
+	}
+	if result.SnapshotsDeleted > 0 || result.BlobsDropped > 0 {
+		c.logger.Info("pruned snapshots", slog.Int("deleted", result.SnapshotsDeleted),
+			slog.Int("blobsDropped", result.BlobsDropped), slog.Int64("bytesFreed", result.BytesFreed))
+	}
+}
+
+// EnsureFresh runs Refresh if the last successful refresh is older than maxAge
+// or the cache was invalidated, otherwise returns nil immediately. Errors from Refresh propagate to the caller.
 func (c *Collector) EnsureFresh(ctx context.Context, maxAge time.Duration) error {
 	c.mu.RLock()
-	age := time.Since(c.lastRefreshAt)
+	age := c.clock.Now().Sub(c.lastRefreshAt)
 	hadSuccess := !c.lastRefreshAt.IsZero() && c.lastRefreshErr == nil
+	invalidated := c.invalidated
 	c.mu.RUnlock()
 
-	if hadSuccess && age < maxAge {
+	if hadSuccess && !invalidated && age < maxAge {
 		return nil
 	}
 	return c.Refresh(ctx)
@@ -186,3 +305,64 @@ func (c *Collector) GetLatest() *StatsData {
 	defer c.mu.RUnlock()
 	return c.cached
 }
+
+// Invalidate marks the cached stats as stale, so the next EnsureFresh
+// refreshes regardless of their age. The cache is still served until then.
+func (c *Collector) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.invalidated = true
+	c.generation++
+}
+
+// Parser returns the client the collector fetches with, for building write requests.
+func (c *Collector) Parser() *todoclient.TodoParser {
+	return c.parser
+}
+
+// Apply sends write requests to Graph and invalidates the cache, even when a
+// request fails part way, since earlier requests may have changed tasks. It
+// returns how many requests succeeded.
+func (c *Collector) Apply(ctx context.Context, requests ...todoclient.WriteRequest) (int, error) {
+	token, err := c.authClient.GetAccessToken(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("get access token: %w", err)
+	}
+
+	responses, err := c.parser.Apply(ctx, c.logger, token, requests...)
+	c.Invalidate()
+	if err != nil {
+		return len(responses), fmt.Errorf("apply changes: %w", err)
+	}
+	return len(responses), nil
+}
+
+// GetTask fetches a task from Graph with the checklist items and linked
+// resources the cached lists lack, which copying a task needs.
+func (c *Collector) GetTask(ctx context.Context, listID, taskID string) (todo.Task, error) {
+	token, err := c.authClient.GetAccessToken(ctx)
+	if err != nil {
+		return todo.Task{}, fmt.Errorf("get access token: %w", err)
+	}
+
+	task, err := c.parser.GetTask(ctx, c.logger, token, listID, taskID)
+	if err != nil {
+		return todo.Task{}, fmt.Errorf("get task: %w", err)
+	}
+	return task, nil
+}
+
+// CreateTask creates task in listID and invalidates the cache.
+func (c *Collector) CreateTask(ctx context.Context, listID string, task todoclient.NewTask) (todo.Task, error) {
+	token, err := c.authClient.GetAccessToken(ctx)
+	if err != nil {
+		return todo.Task{}, fmt.Errorf("get access token: %w", err)
+	}
+
+	created, err := c.parser.CreateTask(ctx, c.logger, token, listID, task)
+	if err != nil {
+		return todo.Task{}, fmt.Errorf("create task: %w", err)
+	}
+	c.Invalidate()
+	return created, nil
+}
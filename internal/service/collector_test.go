@@ -0,0 +1,297 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uchr/ToDoInfo/internal/clock"
+	"github.com/uchr/ToDoInfo/internal/graphfake"
+	"github.com/uchr/ToDoInfo/internal/storage"
+	"github.com/uchr/ToDoInfo/internal/todo"
+	"github.com/uchr/ToDoInfo/internal/todoclient"
+)
+
+var testNow = time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+
+type staticToken string
+
+func (s staticToken) HasCredential() bool { return true }
+
+func (s staticToken) GetAccessToken(context.Context) (string, error) { return string(s), nil }
+
+// hookedToken runs hook before handing out the token, to act in the middle
+// of a refresh.
+type hookedToken struct {
+	staticToken
+	hook func()
+}
+
+func (h hookedToken) GetAccessToken(ctx context.Context) (string, error) {
+	h.hook()
+	return h.staticToken.GetAccessToken(ctx)
+}
+
+// newTestCollector returns a collector reading from server and storing its
+// snapshots in a temporary database.
+func newTestCollector(t *testing.T, server *graphfake.Server) (*Collector, *clock.Fixed) {
+	t.Helper()
+	store, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "stats.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+
+	clk := clock.NewFixed(testNow)
+	parser := todoclient.New(todoclient.DefaultConfig().
+		WithBaseURL(server.URL()).
+		WithHTTPClient(server.HTTPClient()).
+		WithClock(clk))
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewCollector(staticToken(graphfake.Token), parser, store, logger, time.Hour, clk), clk
+}
+
+func latestSnapshot(t *testing.T, collector *Collector) *storage.StatsSnapshot {
+	t.Helper()
+	snapshot, err := collector.store.GetLatest(t.Context())
+	require.NoError(t, err)
+	require.NotNil(t, snapshot)
+	return snapshot
+}
+
+func TestCollectorRefresh(t *testing.T) {
+	server := graphfake.New(t, "basic")
+	server.SetPageSize(2)
+	collector, _ := newTestCollector(t, server)
+
+	require.NoError(t, collector.Refresh(t.Context()))
+
+	data := collector.GetLatest()
+	require.NotNil(t, data)
+	assert.Equal(t, testNow, data.FetchedAt)
+	assert.Equal(t, 6, data.TotalTasks)
+	require.NotNil(t, data.Champion)
+	assert.Equal(t, "Fix the fence", data.Champion.TaskName)
+	assert.Equal(t, 60, data.Champion.Age)
+	assert.Empty(t, data.MissingLists)
+	require.Len(t, data.ExcludedLists, 1)
+	assert.Equal(t, "Tasks", data.ExcludedLists[0].Name)
+
+	snapshot := latestSnapshot(t, collector)
+	assert.Equal(t, 6, snapshot.GlobalStats.TaskCount)
+	assert.Equal(t, data.TotalAge, snapshot.GlobalStats.TotalAge)
+	assert.False(t, snapshot.Partial)
+	assert.Len(t, data.TimeSeries, 1)
+}
+
+func TestCollectorRefreshAppliesChanges(t *testing.T) {
+	server := graphfake.New(t, "basic")
+	collector, clk := newTestCollector(t, server)
+	require.NoError(t, collector.Refresh(t.Context()))
+	requests := server.Requests()
+
+	server.RemoveTask("home", "h-1")
+	server.PutTask("work", todo.Task{
+		ID:              "w-5",
+		Status:          todo.StatusNotStarted,
+		Title:           "Prepare demo",
+		CreatedDateTime: testNow.Add(-2 * time.Hour),
+	})
+	clk.Advance(time.Hour)
+	require.NoError(t, collector.Refresh(t.Context()))
+
+	data := collector.GetLatest()
+	assert.Equal(t, 6, data.TotalTasks)
+	assert.Equal(t, "Write quarterly report", data.Champion.TaskName)
+	// The second refresh only asks for deltas: one request per list plus the lists.
+	assert.Equal(t, 4, server.Requests()-requests)
+}
+
+func TestCollectorRefreshStoresPartialSnapshot(t *testing.T) {
+	server := graphfake.New(t, "basic")
+	server.FailList("family", http.StatusForbidden)
+	collector, _ := newTestCollector(t, server)
+
+	require.NoError(t, collector.Refresh(t.Context()))
+
+	data := collector.GetLatest()
+	assert.Equal(t, 5, data.TotalTasks)
+	require.Len(t, data.MissingLists, 1)
+	assert.Equal(t, "Family", data.MissingLists[0].Name)
+
+	snapshot := latestSnapshot(t, collector)
+	assert.True(t, snapshot.Partial)
+	assert.Equal(t, data.MissingLists, snapshot.MissingLists)
+}
+
+func TestCollectorRefreshRejectedToken(t *testing.T) {
+	server := graphfake.New(t, "basic")
+	server.SetToken("rotated")
+	collector, _ := newTestCollector(t, server)
+
+	err := collector.Refresh(t.Context())
+	assert.ErrorContains(t, err, "InvalidAuthenticationToken")
+	assert.Equal(t, err, collector.LastRefreshErr())
+	assert.Nil(t, collector.GetLatest())
+}
+
+func TestCollectorApplyInvalidatesCache(t *testing.T) {
+	server := graphfake.New(t, "basic")
+	collector, _ := newTestCollector(t, server)
+	require.NoError(t, collector.EnsureFresh(t.Context(), time.Hour))
+	requests := server.Requests()
+
+	require.NoError(t, collector.EnsureFresh(t.Context(), time.Hour))
+	assert.Equal(t, requests, server.Requests(), "a fresh cache is served as is")
+
+	applied, err := collector.Apply(t.Context(), collector.Parser().CompleteRequest("home", "h-1"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, applied)
+	task, _ := server.Task("home", "h-1")
+	assert.Equal(t, todo.StatusCompleted, task.Status)
+	assert.Equal(t, 6, collector.GetLatest().TotalTasks, "the cache is kept until the next refresh")
+
+	require.NoError(t, collector.EnsureFresh(t.Context(), time.Hour))
+	data := collector.GetLatest()
+	assert.Equal(t, 5, data.TotalTasks)
+	assert.Equal(t, "Write quarterly report", data.Champion.TaskName)
+
+	requests = server.Requests()
+	require.NoError(t, collector.EnsureFresh(t.Context(), time.Hour))
+	assert.Equal(t, requests, server.Requests(), "a refresh clears the invalidation")
+}
+
+func TestCollectorRefreshKeepsInvalidationFromDuringRefresh(t *testing.T) {
+	server := graphfake.New(t, "basic")
+	collector, _ := newTestCollector(t, server)
+	collector.authClient = hookedToken{staticToken(graphfake.Token), collector.Invalidate}
+	require.NoError(t, collector.Refresh(t.Context()))
+
+	collector.authClient = staticToken(graphfake.Token)
+	requests := server.Requests()
+	require.NoError(t, collector.EnsureFresh(t.Context(), time.Hour))
+	assert.Greater(t, server.Requests(), requests, "a write during the refresh may be missing from it")
+
+	requests = server.Requests()
+	require.NoError(t, collector.EnsureFresh(t.Context(), time.Hour))
+	assert.Equal(t, requests, server.Requests())
+}
+
+// blockingToken holds every token request until release is closed, then
+// fails it.
+type blockingToken struct {
+	calls   *atomic.Int32
+	release chan struct{}
+}
+
+func (b blockingToken) HasCredential() bool { return true }
+
+func (b blockingToken) GetAccessToken(context.Context) (string, error) {
+	b.calls.Add(1)
+	<-b.release
+	return "", errors.New("token unavailable")
+}
+
+func TestCollectorRefreshJoinsRunningRefresh(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		source := blockingToken{calls: &atomic.Int32{}, release: make(chan struct{})}
+		logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+		collector := NewCollector(source, nil, nil, logger, time.Hour, clock.NewFixed(testNow))
+
+		errs := make(chan error, 2)
+		go func() { errs <- collector.Refresh(t.Context()) }()
+		go func() { errs <- collector.EnsureFresh(t.Context(), time.Hour) }()
+		synctest.Wait()
+		assert.Equal(t, int32(1), source.calls.Load(), "the second caller waits for the running refresh")
+
+		close(source.release)
+		for range 2 {
+			assert.ErrorContains(t, <-errs, "token unavailable")
+		}
+		assert.Equal(t, int32(1), source.calls.Load())
+	})
+}
+
+func TestCollectorCreateTask(t *testing.T) {
+	server := graphfake.New(t, "basic")
+	collector, _ := newTestCollector(t, server)
+	require.NoError(t, collector.Refresh(t.Context()))
+
+	created, err := collector.CreateTask(t.Context(), "home", todoclient.NewTask{Title: "Water the plants", Importance: todo.ImportanceHigh})
+	require.NoError(t, err)
+	assert.Equal(t, "Water the plants", created.Title)
+
+	require.NoError(t, collector.EnsureFresh(t.Context(), time.Hour))
+	assert.Equal(t, 7, collector.GetLatest().TotalTasks)
+}
+
+func TestCollectorRefreshPrunesSnapshots(t *testing.T) {
+	server := graphfake.New(t, "basic")
+	collector, _ := newTestCollector(t, server)
+	collector.WithRetention(storage.RetentionPolicy{KeepAllDays: 1, BlobDays: 1})
+
+	// Two snapshots from the same week a month ago.
+	store := collector.store
+	lists := []todo.TaskList{{ID: "work", Name: "Work"}}
+	for _, at := range []time.Time{testNow.AddDate(0, -1, 0), testNow.AddDate(0, -1, 0).Add(time.Hour)} {
+		require.NoError(t, store.Store(t.Context(), storage.StatsSnapshot{Timestamp: at, TaskLists: lists}))
+	}
+
+	require.NoError(t, collector.Refresh(t.Context()))
+
+	history, err := store.GetHistory(t.Context(), testNow.AddDate(-1, 0, 0), testNow.Add(time.Minute))
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, testNow.AddDate(0, -1, 0).Add(time.Hour), history[0].Timestamp)
+	assert.Nil(t, history[0].TaskLists)
+	assert.NotNil(t, history[1].TaskLists)
+}
+
+func TestCollectorMoveKeepsChecklist(t *testing.T) {
+	server := graphfake.New(t, "basic")
+	server.PutTask("work", todo.Task{
+		ID:              "w-5",
+		Status:          todo.StatusNotStarted,
+		Title:           "Pack for the trip",
+		CreatedDateTime: testNow.Add(-2 * time.Hour),
+		ChecklistItems:  []todo.ChecklistItem{{ID: "c-1", DisplayName: "Passport"}},
+		LinkedResources: []todo.LinkedResource{{ID: "l-1", WebURL: "https://example.com/trip"}},
+	})
+	collector, _ := newTestCollector(t, server)
+	require.NoError(t, collector.Refresh(t.Context()))
+
+	ref, ok := todo.FindTask(collector.GetLatest().TaskLists, "work", "w-5")
+	require.True(t, ok)
+	assert.Empty(t, ref.Task.ChecklistItems, "delta syncs don't carry checklists")
+
+	task, err := collector.GetTask(t.Context(), "work", "w-5")
+	require.NoError(t, err)
+	require.Len(t, task.ChecklistItems, 1)
+	require.Len(t, task.LinkedResources, 1)
+
+	applied, err := collector.Apply(t.Context(), collector.Parser().MoveRequests("work", task, "home")...)
+	require.NoError(t, err)
+	assert.Equal(t, 2, applied)
+
+	_, ok = server.Task("work", "w-5")
+	assert.False(t, ok)
+	var moved *todo.Task
+	for _, task := range server.Tasks("home") {
+		if task.Title == "Pack for the trip" {
+			moved = &task
+		}
+	}
+	require.NotNil(t, moved)
+	require.Len(t, moved.ChecklistItems, 1)
+	assert.Equal(t, "Passport", moved.ChecklistItems[0].DisplayName)
+	require.Len(t, moved.LinkedResources, 1)
+	assert.Equal(t, "https://example.com/trip", moved.LinkedResources[0].WebURL)
+}
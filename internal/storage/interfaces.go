@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/uchr/ToDoInfo/internal/todo"
+	"github.com/uchr/ToDoInfo/internal/todoclient"
 	"github.com/uchr/ToDoInfo/internal/todometrics"
 )
 
@@ -20,12 +22,68 @@ func DefaultDBPath() (string, error) {
 	return filepath.Join(home, ".todoinfo", "data", "stats.db"), nil
 }
 
+// New opens the database named by dsn and runs migrations: a postgres:// or
+// postgresql:// URL selects PostgreSQL, anything else is a SQLite path, and
+// an empty dsn is DefaultDBPath.
+func New(dsn string) (Backend, error) {
+	if isPostgresDSN(dsn) {
+		return NewPostgresStorage(dsn)
+	}
+	dbPath, err := sqlitePath(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return NewSQLiteStorage(dbPath)
+}
+
+// Open opens the database named by dsn like New, without migrating it.
+func Open(dsn string) (Backend, error) {
+	if isPostgresDSN(dsn) {
+		return OpenPostgresStorage(dsn)
+	}
+	dbPath, err := sqlitePath(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return OpenSQLiteStorage(dbPath)
+}
+
+func isPostgresDSN(dsn string) bool {
+	return strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://")
+}
+
+func sqlitePath(dsn string) (string, error) {
+	if dsn != "" {
+		return dsn, nil
+	}
+	return DefaultDBPath()
+}
+
 // StatsSnapshot represents a point-in-time statistics snapshot
 type StatsSnapshot struct {
 	Timestamp   time.Time            `json:"timestamp"`
 	GlobalStats GlobalStats          `json:"global_stats"`
 	ListAges    todometrics.ListAges `json:"list_ages"`
 	TaskLists   []todo.TaskList      `json:"task_lists,omitempty"`
+	// Partial is set when some lists could not be fetched; they are listed in MissingLists.
+	Partial      bool          `json:"partial,omitempty"`
+	MissingLists []MissingList `json:"missing_lists,omitempty"`
+}
+
+// MissingList identifies a list left out of a partial snapshot and why
+type MissingList struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Error string `json:"error"`
+}
+
+// NewMissingLists converts the failures of a fetch into MissingList entries.
+func NewMissingLists(failed []todoclient.ListError) []MissingList {
+	var missing []MissingList
+	for _, f := range failed {
+		missing = append(missing, MissingList{ID: f.ListID, Name: f.ListName, Error: f.Err.Error()})
+	}
+	return missing
 }
 
 // GlobalStats represents global statistics
@@ -41,6 +99,56 @@ type TimeSeriesPoint struct {
 	TaskCount int       `json:"task_count"`
 }
 
+// TaskEventKind names a change in a task's lifecycle
+type TaskEventKind string
+
+const (
+	TaskAppeared   TaskEventKind = "appeared"
+	TaskMoved      TaskEventKind = "moved"
+	TaskRenamed    TaskEventKind = "renamed"
+	TaskDueChanged TaskEventKind = "due_changed"
+	TaskCompleted  TaskEventKind = "completed"
+	// TaskRemoved means the task vanished without showing up as completed:
+	// it was deleted, or completed while completed tasks weren't fetched.
+	TaskRemoved    TaskEventKind = "removed"
+	TaskReappeared TaskEventKind = "reappeared"
+)
+
+// TaskRecord is the tracked lifecycle of one task, keyed by its Graph ID
+type TaskRecord struct {
+	TaskID   string
+	ListID   string
+	ListName string
+	Title    string
+	// Due is nil when the task has no due date.
+	Due *time.Time
+	// CreatedAt is the Graph creation time; FirstSeen and LastSeen are the
+	// first and last snapshots holding the task open.
+	CreatedAt time.Time
+	FirstSeen time.Time
+	LastSeen  time.Time
+	// GoneAt is the first snapshot without the task; GoneReason is
+	// TaskCompleted or TaskRemoved. Both are unset while the task is open.
+	GoneAt     *time.Time
+	GoneReason TaskEventKind
+}
+
+// TaskEvent is a change seen between two snapshots. For moves, renames and
+// due date changes OldValue and NewValue hold the list name, title or RFC 3339
+// due date before and after; an empty due date means none.
+type TaskEvent struct {
+	Time     time.Time
+	Kind     TaskEventKind
+	OldValue string
+	NewValue string
+}
+
+// TaskTimeline is a task's record with its events, oldest first
+type TaskTimeline struct {
+	TaskRecord
+	Events []TaskEvent
+}
+
 // StatsStorage defines the interface for storing and retrieving statistics
 type StatsStorage interface {
 	// Store saves a statistics snapshot
@@ -49,12 +157,48 @@ type StatsStorage interface {
 	// GetLatest retrieves the most recent statistics snapshot
 	GetLatest(ctx context.Context) (*StatsSnapshot, error)
 
+	// GetAt retrieves the newest snapshot taken at or before the given time
+	GetAt(ctx context.Context, at time.Time) (*StatsSnapshot, error)
+
 	// GetHistory retrieves statistics history for a given time period
 	GetHistory(ctx context.Context, from, to time.Time) ([]StatsSnapshot, error)
 
-	// GetTimeSeriesData retrieves time series data for graphing
-	GetTimeSeriesData(ctx context.Context, days int) ([]TimeSeriesPoint, error)
+	// GetTimeSeriesData retrieves time series data for graphing, for the days up to asOf
+	GetTimeSeriesData(ctx context.Context, asOf time.Time, days int) ([]TimeSeriesPoint, error)
+
+	// GetTaskTimeline retrieves the lifecycle of a task by its Graph ID, or nil if it was never seen
+	GetTaskTimeline(ctx context.Context, taskID string) (*TaskTimeline, error)
+
+	// FindTaskRecords retrieves the tracked tasks whose title contains query, ignoring case, newest first
+	FindTaskRecords(ctx context.Context, query string) ([]TaskRecord, error)
+
+	// Prune thins out snapshots older than the retention policy allows, as of now
+	Prune(ctx context.Context, policy RetentionPolicy, now time.Time) (PruneResult, error)
 
 	// Close releases any resources held by the storage
 	Close() error
 }
+
+// DeltaStateStorage persists the incremental sync state between refreshes
+type DeltaStateStorage interface {
+	// LoadDeltaState returns the stored sync state, or an empty state if none was saved
+	LoadDeltaState(ctx context.Context) (*todoclient.DeltaState, error)
+
+	// SaveDeltaState replaces the stored sync state
+	SaveDeltaState(ctx context.Context, state *todoclient.DeltaState) error
+}
+
+// Backend is a database holding the statistics and the sync state
+type Backend interface {
+	StatsStorage
+	DeltaStateStorage
+
+	// Migrate applies the pending schema migrations and returns them
+	Migrate(ctx context.Context) ([]MigrationStatus, error)
+
+	// MigrationStatus lists the known and applied schema migrations
+	MigrationStatus(ctx context.Context) ([]MigrationStatus, error)
+
+	// Vacuum gives the space freed by Prune back and returns how many bytes it freed
+	Vacuum(ctx context.Context) (int64, error)
+}
@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+)
+
+// migration is one step of the schema. Each runs in its own transaction
+// together with its schema_migrations row, so a failed step leaves the
+// database at the previous version.
+type migration struct {
+	version int
+	name    string
+	up      func(ctx context.Context, tx *sqlTx) error
+}
+
+func execMigration(ddl string) func(context.Context, *sqlTx) error {
+	return func(ctx context.Context, tx *sqlTx) error {
+		_, err := tx.ExecContext(ctx, ddl)
+		return err
+	}
+}
+
+// MigrationStatus describes a schema migration and whether it was applied.
+type MigrationStatus struct {
+	Version int
+	Name    string
+	// AppliedAt is nil for a pending migration.
+	AppliedAt *time.Time
+}
+
+// SchemaTooNewError is returned for a database migrated by a newer build,
+// which this one can't safely write to.
+type SchemaTooNewError struct {
+	Version, Latest int
+}
+
+func (e *SchemaTooNewError) Error() string {
+	return fmt.Sprintf("database schema version %d is newer than the latest known version %d, upgrade todoinfo", e.Version, e.Latest)
+}
+
+// MigrationStatus lists every known migration, and any applied by a newer
+// build, in version order. It only reads: a database without
+// schema_migrations is unversioned and has every migration pending.
+func (s *sqlStorage) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	var n int
+	if err := s.db.QueryRowContext(ctx, s.dialect.tableCountQuery, "schema_migrations").Scan(&n); err != nil {
+		return nil, fmt.Errorf("look up schema_migrations: %w", err)
+	}
+	applied := map[int]MigrationStatus{}
+	if n > 0 {
+		var err error
+		if applied, err = s.readMigrations(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	var statuses []MigrationStatus
+	for _, m := range s.dialect.migrations {
+		status := MigrationStatus{Version: m.version, Name: m.name}
+		if done, ok := applied[m.version]; ok {
+			status.AppliedAt = done.AppliedAt
+			delete(applied, m.version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, version := range slices.Sorted(maps.Keys(applied)) {
+		statuses = append(statuses, applied[version])
+	}
+	return statuses, nil
+}
+
+// Migrate applies the pending migrations in order and returns them. It
+// stops at the first failure; the migrations before it stay applied.
+func (s *sqlStorage) Migrate(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := s.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for version := range applied {
+		if version > s.dialect.latestVersion() {
+			return nil, &SchemaTooNewError{Version: version, Latest: s.dialect.latestVersion()}
+		}
+	}
+
+	var done []MigrationStatus
+	for _, m := range s.dialect.migrations {
+		if _, ok := applied[m.version]; ok {
+			continue
+		}
+		status, err := s.apply(ctx, m)
+		if err != nil {
+			return done, fmt.Errorf("migration %d (%s): %w", m.version, m.name, err)
+		}
+		if status != nil {
+			done = append(done, *status)
+		}
+	}
+	return done, nil
+}
+
+// apply runs m and records it, or returns nil if another process applied it first.
+func (s *sqlStorage) apply(ctx context.Context, m migration) (*MigrationStatus, error) {
+	tx, err := s.beginWrite(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var n int
+	if err := tx.QueryRowContext(ctx,
+		`SELECT count(*) FROM schema_migrations WHERE version = ?`, m.version).Scan(&n); err != nil {
+		return nil, fmt.Errorf("query schema_migrations: %w", err)
+	}
+	if n > 0 {
+		return nil, nil
+	}
+
+	if err := m.up(ctx, tx); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+		m.version, m.name, now.Format(time.RFC3339)); err != nil {
+		return nil, fmt.Errorf("record migration: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	return &MigrationStatus{Version: m.version, Name: m.name, AppliedAt: &now}, nil
+}
+
+// appliedMigrations reads schema_migrations, creating it on first use.
+func (s *sqlStorage) appliedMigrations(ctx context.Context) (map[int]MigrationStatus, error) {
+	// Under the writer lock, as Postgres fails concurrent CREATE TABLE IF NOT EXISTS.
+	tx, err := s.beginWrite(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, s.dialect.migrationsDDL); err != nil {
+		return nil, fmt.Errorf("create schema_migrations: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	return s.readMigrations(ctx)
+}
+
+// readMigrations reads the applied migrations from schema_migrations.
+func (s *sqlStorage) readMigrations(ctx context.Context) (map[int]MigrationStatus, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT version, name, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("query schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]MigrationStatus)
+	for rows.Next() {
+		var (
+			status MigrationStatus
+			tsStr  string
+		)
+		if err := rows.Scan(&status.Version, &status.Name, &tsStr); err != nil {
+			return nil, fmt.Errorf("scan schema_migrations: %w", err)
+		}
+		ts, err := time.Parse(time.RFC3339, tsStr)
+		if err != nil {
+			return nil, fmt.Errorf("parse timestamp %q: %w", tsStr, err)
+		}
+		status.AppliedAt = &ts
+		applied[status.Version] = status
+	}
+	return applied, rows.Err()
+}
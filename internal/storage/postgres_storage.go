@@ -0,0 +1,213 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+const (
+	// postgresMaxConns caps the connections each instance keeps open.
+	postgresMaxConns = 8
+	// postgresConnMaxIdleTime closes connections left idle between refreshes.
+	postgresConnMaxIdleTime = 5 * time.Minute
+)
+
+// PostgresStorage implements StatsStorage using a PostgreSQL database, which
+// several bot instances can share. Open it once and share it; it is safe for
+// concurrent use.
+type PostgresStorage struct {
+	sqlStorage
+}
+
+// NewPostgresStorage connects to the PostgreSQL database at dsn and runs migrations.
+func NewPostgresStorage(dsn string) (*PostgresStorage, error) {
+	s, err := OpenPostgresStorage(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.Migrate(context.Background()); err != nil {
+		s.Close()
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+	return s, nil
+}
+
+// OpenPostgresStorage connects to the PostgreSQL database at dsn without
+// migrating it, for inspecting the schema with MigrationStatus.
+func OpenPostgresStorage(dsn string) (*PostgresStorage, error) {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open postgres db: %w", err)
+	}
+	db.SetMaxOpenConns(postgresMaxConns)
+	db.SetMaxIdleConns(postgresMaxConns)
+	db.SetConnMaxIdleTime(postgresConnMaxIdleTime)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("connect to postgres: %w", err)
+	}
+
+	return &PostgresStorage{sqlStorage{
+		db:      &sqlDB{DB: db, rebind: postgresRebind},
+		dialect: postgresDialect,
+	}}, nil
+}
+
+// postgresWriteLock is the advisory lock key writers hold for the rest of
+// their transaction, so that instances sharing the database don't track the
+// same tasks twice or run a migration together.
+const postgresWriteLock = 0x746f646f696e666f // "todoinfo"
+
+var postgresDialect = dialect{
+	migrations:      postgresMigrations,
+	migrationsDDL:   postgresMigrationsDDL,
+	tableCountQuery: `SELECT count(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = ?`,
+	lock: func(ctx context.Context, tx *sqlTx) error {
+		_, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(?)`, int64(postgresWriteLock))
+		return err
+	},
+	usedBytesQuery: postgresSizeQuery,
+	fileBytesQuery: postgresSizeQuery,
+	vacuum:         "VACUUM FULL " + strings.Join(postgresTables, ", "),
+}
+
+// postgresTables are the tables the migrations create. VACUUM FULL rewrites
+// each table it names under an exclusive lock, so it is limited to these
+// rather than every table of a database the bot may share; unqualified, they
+// resolve to the current schema postgresSizeQuery measures.
+var postgresTables = []string{
+	"snapshots", "list_ages", "payloads",
+	"delta_links", "delta_lists", "delta_tasks",
+	"tasks", "task_events", "schema_migrations",
+}
+
+// postgresSizeQuery sums the tables of the current schema with their indexes
+// and TOAST data. Postgres reuses the space of deleted rows without telling
+// how much of it is free, so the used and file sizes are the same.
+const postgresSizeQuery = `
+SELECT coalesce(sum(pg_total_relation_size(c.oid)), 0)::bigint
+FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE n.nspname = current_schema() AND c.relkind = 'r'`
+
+// postgresRebind numbers the ? placeholders of query as $1, $2, ...
+// The queries of this package have no ? inside string literals.
+func postgresRebind(query string) string {
+	if !strings.Contains(query, "?") {
+		return query
+	}
+	var sb strings.Builder
+	n := 0
+	for _, r := range query {
+		if r != '?' {
+			sb.WriteRune(r)
+			continue
+		}
+		n++
+		sb.WriteString("$" + strconv.Itoa(n))
+	}
+	return sb.String()
+}
+
+// postgresMigrations mirror sqliteMigrations version by version, so both
+// report the same schema versions. Timestamps are RFC 3339 text in both;
+// the C collation keeps their order chronological.
+var postgresMigrations = []migration{
+	{1, "create snapshots and list ages", execMigration(`
+CREATE TABLE snapshots (
+    id              BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    timestamp       TEXT COLLATE "C" NOT NULL,
+    total_age       INTEGER NOT NULL,
+    task_count      INTEGER NOT NULL,
+    task_lists_json TEXT
+);
+
+CREATE TABLE list_ages (
+    id          BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    snapshot_id BIGINT NOT NULL REFERENCES snapshots(id),
+    title       TEXT NOT NULL,
+    age         INTEGER NOT NULL,
+    task_count  INTEGER NOT NULL
+);
+
+CREATE INDEX idx_snapshots_timestamp ON snapshots(timestamp);
+CREATE INDEX idx_list_ages_snapshot   ON list_ages(snapshot_id);
+`)},
+	{2, "add partial snapshot columns", execMigration(`
+ALTER TABLE snapshots
+    ADD COLUMN partial            BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN missing_lists_json TEXT;
+`)},
+	{3, "create delta state", execMigration(`
+CREATE TABLE delta_links (
+    scope TEXT PRIMARY KEY,
+    link  TEXT NOT NULL
+);
+
+CREATE TABLE delta_lists (
+    list_id             TEXT PRIMARY KEY,
+    name                TEXT NOT NULL,
+    wellknown_list_name TEXT NOT NULL,
+    is_shared           BOOLEAN NOT NULL
+);
+
+CREATE TABLE delta_tasks (
+    list_id   TEXT NOT NULL REFERENCES delta_lists(list_id),
+    task_id   TEXT NOT NULL,
+    task_json TEXT NOT NULL,
+    PRIMARY KEY (list_id, task_id)
+);
+`)},
+	{4, "create task history", execMigration(`
+CREATE TABLE tasks (
+    task_id     TEXT PRIMARY KEY,
+    list_id     TEXT NOT NULL,
+    list_name   TEXT NOT NULL,
+    title       TEXT NOT NULL,
+    due         TEXT COLLATE "C",
+    created_at  TEXT COLLATE "C" NOT NULL,
+    first_seen  TEXT COLLATE "C" NOT NULL,
+    last_seen   TEXT COLLATE "C" NOT NULL,
+    gone_at     TEXT COLLATE "C",
+    gone_reason TEXT
+);
+
+CREATE TABLE task_events (
+    id        BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    task_id   TEXT NOT NULL REFERENCES tasks(task_id),
+    timestamp TEXT COLLATE "C" NOT NULL,
+    kind      TEXT NOT NULL,
+    old_value TEXT NOT NULL,
+    new_value TEXT NOT NULL
+);
+
+CREATE INDEX idx_tasks_open       ON tasks(gone_at);
+CREATE INDEX idx_task_events_task ON task_events(task_id, timestamp);
+`)},
+	{5, "share compressed task list payloads", execMigration(`
+CREATE TABLE payloads (
+    hash     TEXT PRIMARY KEY,
+    encoding TEXT NOT NULL,
+    data     BYTEA NOT NULL
+);
+
+ALTER TABLE snapshots ADD COLUMN task_lists_hash TEXT REFERENCES payloads(hash);
+
+CREATE INDEX idx_snapshots_task_lists_hash ON snapshots(task_lists_hash);
+`)},
+	{6, "add excluded list age column", execMigration(`
+ALTER TABLE list_ages ADD COLUMN excluded BOOLEAN NOT NULL DEFAULT false;
+`)},
+}
+
+const postgresMigrationsDDL = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version    INTEGER PRIMARY KEY,
+    name       TEXT NOT NULL,
+    applied_at TEXT NOT NULL
+);`
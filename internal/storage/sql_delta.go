@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/uchr/ToDoInfo/internal/todo"
+	"github.com/uchr/ToDoInfo/internal/todoclient"
+)
+
+// listsDeltaScope is the delta_links scope of the task list collection.
+// Per-list task delta links use the list ID prefixed with tasksDeltaScopePrefix.
+const (
+	listsDeltaScope       = "lists"
+	tasksDeltaScopePrefix = "tasks:"
+)
+
+// LoadDeltaState returns the stored sync state, or an empty state if none was saved.
+func (s *sqlStorage) LoadDeltaState(ctx context.Context) (*todoclient.DeltaState, error) {
+	state := todoclient.NewDeltaState()
+
+	links := make(map[string]string)
+	linkRows, err := s.db.QueryContext(ctx, `SELECT scope, link FROM delta_links`)
+	if err != nil {
+		return nil, fmt.Errorf("query delta links: %w", err)
+	}
+	defer linkRows.Close()
+	for linkRows.Next() {
+		var scope, link string
+		if err := linkRows.Scan(&scope, &link); err != nil {
+			return nil, fmt.Errorf("scan delta link: %w", err)
+		}
+		links[scope] = link
+	}
+	if err := linkRows.Err(); err != nil {
+		return nil, err
+	}
+	state.ListsDeltaLink = links[listsDeltaScope]
+
+	listRows, err := s.db.QueryContext(ctx,
+		`SELECT list_id, name, wellknown_list_name, is_shared FROM delta_lists`)
+	if err != nil {
+		return nil, fmt.Errorf("query delta lists: %w", err)
+	}
+	defer listRows.Close()
+	for listRows.Next() {
+		list := todoclient.NewListDeltaState("")
+		if err := listRows.Scan(&list.ID, &list.Name, &list.WellknownListName, &list.IsShared); err != nil {
+			return nil, fmt.Errorf("scan delta list: %w", err)
+		}
+		list.DeltaLink = links[tasksDeltaScopePrefix+list.ID]
+		state.Lists[list.ID] = list
+	}
+	if err := listRows.Err(); err != nil {
+		return nil, err
+	}
+
+	taskRows, err := s.db.QueryContext(ctx, `SELECT list_id, task_json FROM delta_tasks`)
+	if err != nil {
+		return nil, fmt.Errorf("query delta tasks: %w", err)
+	}
+	defer taskRows.Close()
+	for taskRows.Next() {
+		var listID, taskJSON string
+		if err := taskRows.Scan(&listID, &taskJSON); err != nil {
+			return nil, fmt.Errorf("scan delta task: %w", err)
+		}
+		list, ok := state.Lists[listID]
+		if !ok {
+			continue
+		}
+		var task todo.Task
+		if err := json.Unmarshal([]byte(taskJSON), &task); err != nil {
+			return nil, fmt.Errorf("unmarshal delta task: %w", err)
+		}
+		if task.Status == todo.StatusCompleted {
+			list.CompletedTasks[task.ID] = task
+		} else {
+			list.Tasks[task.ID] = task
+		}
+	}
+
+	return state, taskRows.Err()
+}
+
+// SaveDeltaState replaces the stored sync state.
+func (s *sqlStorage) SaveDeltaState(ctx context.Context, state *todoclient.DeltaState) error {
+	tx, err := s.beginWrite(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"delta_tasks", "delta_lists", "delta_links"} {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+table); err != nil {
+			return fmt.Errorf("clear %s: %w", table, err)
+		}
+	}
+
+	linkStmt, err := tx.PrepareContext(ctx, `INSERT INTO delta_links (scope, link) VALUES (?, ?)`)
+	if err != nil {
+		return fmt.Errorf("prepare delta_links insert: %w", err)
+	}
+	defer linkStmt.Close()
+
+	listStmt, err := tx.PrepareContext(ctx,
+		`INSERT INTO delta_lists (list_id, name, wellknown_list_name, is_shared) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("prepare delta_lists insert: %w", err)
+	}
+	defer listStmt.Close()
+
+	taskStmt, err := tx.PrepareContext(ctx,
+		`INSERT INTO delta_tasks (list_id, task_id, task_json) VALUES (?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("prepare delta_tasks insert: %w", err)
+	}
+	defer taskStmt.Close()
+
+	if state.ListsDeltaLink != "" {
+		if _, err := linkStmt.ExecContext(ctx, listsDeltaScope, state.ListsDeltaLink); err != nil {
+			return fmt.Errorf("insert delta link: %w", err)
+		}
+	}
+
+	for _, list := range state.Lists {
+		if _, err := listStmt.ExecContext(ctx, list.ID, list.Name, list.WellknownListName, list.IsShared); err != nil {
+			return fmt.Errorf("insert delta list: %w", err)
+		}
+		if list.DeltaLink != "" {
+			if _, err := linkStmt.ExecContext(ctx, tasksDeltaScopePrefix+list.ID, list.DeltaLink); err != nil {
+				return fmt.Errorf("insert delta link: %w", err)
+			}
+		}
+		for _, tasks := range []map[string]todo.Task{list.Tasks, list.CompletedTasks} {
+			for taskID, task := range tasks {
+				taskJSON, err := json.Marshal(&task)
+				if err != nil {
+					return fmt.Errorf("marshal delta task: %w", err)
+				}
+				if _, err := taskStmt.ExecContext(ctx, list.ID, taskID, string(taskJSON)); err != nil {
+					return fmt.Errorf("insert delta task: %w", err)
+				}
+			}
+		}
+	}
+
+	return tx.Commit()
+}
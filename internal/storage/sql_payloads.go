@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+)
+
+// Task lists are stored once per distinct content: a snapshot points at a
+// gzip-compressed payload by the SHA-256 of its JSON, so the snapshots
+// between two changes share one row. Snapshots written before that keep
+// their JSON in task_lists_json.
+const payloadGzip = "gzip"
+
+// storePayload stores data unless a payload with the same content exists,
+// and returns its hash.
+func storePayload(ctx context.Context, tx *sqlTx, data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	var n int
+	if err := tx.QueryRowContext(ctx, `SELECT count(*) FROM payloads WHERE hash = ?`, hash).Scan(&n); err != nil {
+		return "", fmt.Errorf("query payload: %w", err)
+	}
+	if n > 0 {
+		return hash, nil
+	}
+
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	if _, err := zw.Write(data); err != nil {
+		return "", fmt.Errorf("compress payload: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return "", fmt.Errorf("compress payload: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO payloads (hash, encoding, data) VALUES (?, ?, ?)`,
+		hash, payloadGzip, compressed.Bytes()); err != nil {
+		return "", fmt.Errorf("insert payload: %w", err)
+	}
+	return hash, nil
+}
+
+// deleteOrphanPayloads removes the payloads no snapshot points at.
+func deleteOrphanPayloads(ctx context.Context, tx *sqlTx) error {
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM payloads WHERE hash NOT IN
+		 (SELECT task_lists_hash FROM snapshots WHERE task_lists_hash IS NOT NULL)`); err != nil {
+		return fmt.Errorf("delete unused payloads: %w", err)
+	}
+	return nil
+}
+
+// taskListsColumn holds the task lists of a snapshot row in either format:
+// JSON inline, or the encoding and data of its shared payload.
+type taskListsColumn struct {
+	json     sql.NullString
+	encoding sql.NullString
+	data     []byte
+}
+
+// bytes returns the task lists JSON, or nil if the snapshot has none.
+func (c taskListsColumn) bytes() ([]byte, error) {
+	if c.json.Valid && c.json.String != "" {
+		return []byte(c.json.String), nil
+	}
+	if !c.encoding.Valid {
+		return nil, nil
+	}
+	if c.encoding.String != payloadGzip {
+		return nil, fmt.Errorf("unknown payload encoding %q", c.encoding.String)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(c.data))
+	if err != nil {
+		return nil, fmt.Errorf("decompress task lists: %w", err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("decompress task lists: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RetentionPolicy decides which snapshots Prune keeps: every snapshot from
+// the last KeepAllDays, then the last one of each day for DailyMonths, then
+// the last one of each week. Pruned snapshots lose their list ages as well;
+// the task history in tasks and task_events is kept whole.
+type RetentionPolicy struct {
+	KeepAllDays int
+	// DailyMonths of zero thins snapshots to one per week right after KeepAllDays.
+	DailyMonths int
+	// BlobDays drops the task lists of snapshots older than this many days,
+	// keeping their totals; zero keeps every blob. The latest snapshot always
+	// keeps its task lists for offline use, and a payload shared with a
+	// newer snapshot stays stored for that one.
+	BlobDays int
+}
+
+// DefaultRetentionPolicy keeps two weeks in full, six months of daily
+// snapshots and task lists for 90 days.
+func DefaultRetentionPolicy() RetentionPolicy {
+	return RetentionPolicy{KeepAllDays: 14, DailyMonths: 6, BlobDays: 90}
+}
+
+// PruneResult reports what Prune removed.
+type PruneResult struct {
+	SnapshotsDeleted int
+	BlobsDropped     int
+	// BytesFreed is the space released inside the database file. SQLite reuses
+	// it for new rows; Vacuum gives it back to the file system. Postgres
+	// only frees space once it vacuums, so this is usually zero there.
+	BytesFreed int64
+}
+
+// Prune thins out snapshots older than policy allows, as of now.
+func (s *sqlStorage) Prune(ctx context.Context, policy RetentionPolicy, now time.Time) (PruneResult, error) {
+	var result PruneResult
+	usedBefore, err := s.usedBytes(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	tx, err := s.beginWrite(ctx)
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT id, timestamp FROM snapshots ORDER BY timestamp DESC, id DESC`)
+	if err != nil {
+		return result, fmt.Errorf("query snapshots: %w", err)
+	}
+	keepAllFrom := now.AddDate(0, 0, -policy.KeepAllDays)
+	dailyFrom := keepAllFrom.AddDate(0, -policy.DailyMonths, 0)
+	kept := make(map[string]bool)
+	var doomed []int64
+	for rows.Next() {
+		var (
+			id    int64
+			tsStr string
+		)
+		if err := rows.Scan(&id, &tsStr); err != nil {
+			rows.Close()
+			return result, fmt.Errorf("scan snapshot: %w", err)
+		}
+		ts, err := time.Parse(time.RFC3339, tsStr)
+		if err != nil {
+			rows.Close()
+			return result, fmt.Errorf("parse timestamp %q: %w", tsStr, err)
+		}
+
+		// Newest first, so the first snapshot of each bucket is the one kept.
+		var bucket string
+		switch {
+		case !ts.Before(keepAllFrom):
+			continue
+		case !ts.Before(dailyFrom):
+			bucket = ts.UTC().Format("day 2006-01-02")
+		default:
+			year, week := ts.UTC().ISOWeek()
+			bucket = fmt.Sprintf("week %d-%02d", year, week)
+		}
+		if kept[bucket] {
+			doomed = append(doomed, id)
+		}
+		kept[bucket] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return result, err
+	}
+
+	for _, id := range doomed {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM list_ages WHERE snapshot_id = ?`, id); err != nil {
+			return result, fmt.Errorf("delete list ages: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM snapshots WHERE id = ?`, id); err != nil {
+			return result, fmt.Errorf("delete snapshot: %w", err)
+		}
+	}
+	result.SnapshotsDeleted = len(doomed)
+
+	if policy.BlobDays > 0 {
+		res, err := tx.ExecContext(ctx,
+			`UPDATE snapshots SET task_lists_json = NULL, task_lists_hash = NULL
+			 WHERE (task_lists_json IS NOT NULL OR task_lists_hash IS NOT NULL) AND timestamp < ?
+			   AND id <> (SELECT id FROM snapshots ORDER BY timestamp DESC, id DESC LIMIT 1)`,
+			now.AddDate(0, 0, -policy.BlobDays).UTC().Format(time.RFC3339))
+		if err != nil {
+			return result, fmt.Errorf("drop task lists: %w", err)
+		}
+		dropped, _ := res.RowsAffected()
+		result.BlobsDropped = int(dropped)
+	}
+	if err := deleteOrphanPayloads(ctx, tx); err != nil {
+		return result, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return result, fmt.Errorf("commit: %w", err)
+	}
+
+	usedAfter, err := s.usedBytes(ctx)
+	if err != nil {
+		return result, err
+	}
+	result.BytesFreed = max(usedBefore-usedAfter, 0)
+	return result, nil
+}
+
+// Vacuum rebuilds the database without its free space and returns how many
+// bytes it shrank by.
+func (s *sqlStorage) Vacuum(ctx context.Context) (int64, error) {
+	before, err := s.fileBytes(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := s.db.ExecContext(ctx, s.dialect.vacuum); err != nil {
+		return 0, fmt.Errorf("vacuum: %w", err)
+	}
+	after, err := s.fileBytes(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return max(before-after, 0), nil
+}
+
+// usedBytes is the size of the pages holding data, free pages excluded.
+func (s *sqlStorage) usedBytes(ctx context.Context) (int64, error) {
+	var used int64
+	err := s.db.QueryRowContext(ctx, s.dialect.usedBytesQuery).Scan(&used)
+	if err != nil {
+		return 0, fmt.Errorf("query database size: %w", err)
+	}
+	return used, nil
+}
+
+// fileBytes is the size of the database, free pages included.
+func (s *sqlStorage) fileBytes(ctx context.Context) (int64, error) {
+	var size int64
+	err := s.db.QueryRowContext(ctx, s.dialect.fileBytesQuery).Scan(&size)
+	if err != nil {
+		return 0, fmt.Errorf("query database size: %w", err)
+	}
+	return size, nil
+}
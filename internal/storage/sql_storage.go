@@ -0,0 +1,405 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/uchr/ToDoInfo/internal/todo"
+	"github.com/uchr/ToDoInfo/internal/todometrics"
+)
+
+// sqlStorage implements Backend on top of database/sql. Queries are written
+// for SQLite with ? placeholders; the dialect adapts them and supplies the
+// schema and the few statements that differ between databases.
+type sqlStorage struct {
+	db      *sqlDB
+	dialect dialect
+}
+
+// dialect holds what differs between the databases sqlStorage runs on.
+type dialect struct {
+	// rebind rewrites the ? placeholders of a query.
+	rebind     func(query string) string
+	migrations []migration
+	// migrationsDDL creates the schema_migrations table.
+	migrationsDDL string
+	// tableCountQuery counts the tables named by its argument, so the
+	// schema can be inspected without creating schema_migrations.
+	tableCountQuery string
+	// lock serialises writers from several processes for the rest of tx;
+	// nil when the database does so by itself.
+	lock func(ctx context.Context, tx *sqlTx) error
+	// usedBytesQuery and fileBytesQuery return the size of the stored data,
+	// without and with free space.
+	usedBytesQuery, fileBytesQuery string
+	// vacuum rebuilds the storage to give free space back.
+	vacuum string
+}
+
+func (d dialect) latestVersion() int {
+	return d.migrations[len(d.migrations)-1].version
+}
+
+// sqlDB rebinds the queries it runs for its dialect.
+type sqlDB struct {
+	*sql.DB
+	rebind func(string) string
+}
+
+func (db *sqlDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return db.DB.ExecContext(ctx, db.rebind(query), args...)
+}
+
+func (db *sqlDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return db.DB.QueryContext(ctx, db.rebind(query), args...)
+}
+
+func (db *sqlDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return db.DB.QueryRowContext(ctx, db.rebind(query), args...)
+}
+
+func (db *sqlDB) Exec(query string, args ...any) (sql.Result, error) {
+	return db.ExecContext(context.Background(), query, args...)
+}
+
+func (db *sqlDB) QueryRow(query string, args ...any) *sql.Row {
+	return db.QueryRowContext(context.Background(), query, args...)
+}
+
+func (db *sqlDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sqlTx, error) {
+	tx, err := db.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlTx{Tx: tx, rebind: db.rebind}, nil
+}
+
+// sqlTx rebinds the queries it runs for its dialect.
+type sqlTx struct {
+	*sql.Tx
+	rebind func(string) string
+}
+
+func (tx *sqlTx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return tx.Tx.ExecContext(ctx, tx.rebind(query), args...)
+}
+
+func (tx *sqlTx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return tx.Tx.QueryContext(ctx, tx.rebind(query), args...)
+}
+
+func (tx *sqlTx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return tx.Tx.QueryRowContext(ctx, tx.rebind(query), args...)
+}
+
+func (tx *sqlTx) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return tx.Tx.PrepareContext(ctx, tx.rebind(query))
+}
+
+// beginWrite starts a transaction holding the dialect's writer lock.
+func (s *sqlStorage) beginWrite(ctx context.Context) (*sqlTx, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	if s.dialect.lock != nil {
+		if err := s.dialect.lock(ctx, tx); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("lock: %w", err)
+		}
+	}
+	return tx, nil
+}
+
+// Store saves a statistics snapshot and updates the per-task history from
+// its task lists.
+func (s *sqlStorage) Store(ctx context.Context, snapshot StatsSnapshot) error {
+	tx, err := s.beginWrite(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Serialize task lists to JSON, stored once per distinct content.
+	var taskListsHash sql.NullString
+	if len(snapshot.TaskLists) > 0 {
+		taskListsJSON, err := json.Marshal(snapshot.TaskLists)
+		if err != nil {
+			return fmt.Errorf("marshal task lists: %w", err)
+		}
+		if taskListsHash.String, err = storePayload(ctx, tx, taskListsJSON); err != nil {
+			return err
+		}
+		taskListsHash.Valid = true
+	}
+
+	var missingListsJSON []byte
+	if len(snapshot.MissingLists) > 0 {
+		missingListsJSON, err = json.Marshal(snapshot.MissingLists)
+		if err != nil {
+			return fmt.Errorf("marshal missing lists: %w", err)
+		}
+	}
+
+	var snapshotID int64
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO snapshots (timestamp, total_age, task_count, task_lists_hash, partial, missing_lists_json)
+		 VALUES (?, ?, ?, ?, ?, ?) RETURNING id`,
+		snapshot.Timestamp.UTC().Format(time.RFC3339),
+		snapshot.GlobalStats.TotalAge,
+		snapshot.GlobalStats.TaskCount,
+		taskListsHash,
+		snapshot.Partial,
+		nullString(string(missingListsJSON)),
+	).Scan(&snapshotID)
+	if err != nil {
+		return fmt.Errorf("insert snapshot: %w", err)
+	}
+
+	// Insert list ages.
+	stmt, err := tx.PrepareContext(ctx,
+		`INSERT INTO list_ages (snapshot_id, title, age, task_count, excluded) VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("prepare list_ages insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, la := range snapshot.ListAges.Ages {
+		if _, err := stmt.ExecContext(ctx, snapshotID, la.Title, la.Age, la.TaskCount, la.Excluded); err != nil {
+			return fmt.Errorf("insert list age: %w", err)
+		}
+	}
+
+	if err := trackTasks(ctx, tx, snapshot); err != nil {
+		return fmt.Errorf("track tasks: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// GetLatest retrieves the most recent statistics snapshot.
+func (s *sqlStorage) GetLatest(ctx context.Context) (*StatsSnapshot, error) {
+	row := s.db.QueryRowContext(ctx,
+		`SELECT `+snapshotColumns+` FROM `+snapshotTables+` ORDER BY s.timestamp DESC LIMIT 1`)
+
+	snap, err := s.scanSnapshot(ctx, row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// GetAt retrieves the newest snapshot taken at or before at, or nil if there is none.
+func (s *sqlStorage) GetAt(ctx context.Context, at time.Time) (*StatsSnapshot, error) {
+	row := s.db.QueryRowContext(ctx,
+		`SELECT `+snapshotColumns+` FROM `+snapshotTables+`
+		 WHERE s.timestamp <= ? ORDER BY s.timestamp DESC LIMIT 1`,
+		at.UTC().Format(time.RFC3339))
+
+	snap, err := s.scanSnapshot(ctx, row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// GetHistory retrieves statistics history for a given time period.
+func (s *sqlStorage) GetHistory(ctx context.Context, from, to time.Time) ([]StatsSnapshot, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+snapshotColumns+` FROM `+snapshotTables+`
+		 WHERE s.timestamp > ? AND s.timestamp < ?
+		 ORDER BY s.timestamp ASC`,
+		from.UTC().Format(time.RFC3339),
+		to.UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query history: %w", err)
+	}
+	defer rows.Close()
+
+	// Read every row before loading list ages: holding this connection while
+	// taking another per snapshot could exhaust the pool under concurrent readers.
+	var found []snapshotRow
+	for rows.Next() {
+		r, err := scanSnapshotRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	snapshots := make([]StatsSnapshot, 0, len(found))
+	for _, r := range found {
+		snap, err := s.buildSnapshot(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, *snap)
+	}
+	return snapshots, nil
+}
+
+// GetTimeSeriesData retrieves time series data for graphing, covering the
+// given number of days up to and including asOf.
+func (s *sqlStorage) GetTimeSeriesData(ctx context.Context, asOf time.Time, days int) ([]TimeSeriesPoint, error) {
+	cutoff := asOf.AddDate(0, 0, -days).UTC().Format(time.RFC3339)
+	until := asOf.UTC().Format(time.RFC3339)
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT substr(timestamp, 1, 10) AS d, MAX(total_age), MAX(task_count)
+		 FROM snapshots
+		 WHERE timestamp > ? AND timestamp <= ?
+		 GROUP BY d
+		 ORDER BY d ASC`,
+		cutoff, until,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query time series: %w", err)
+	}
+	defer rows.Close()
+
+	var points []TimeSeriesPoint
+	for rows.Next() {
+		var dateStr string
+		var maxAge, taskCount int
+		if err := rows.Scan(&dateStr, &maxAge, &taskCount); err != nil {
+			return nil, fmt.Errorf("scan time series row: %w", err)
+		}
+		d, err := time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			return nil, fmt.Errorf("parse date %q: %w", dateStr, err)
+		}
+		points = append(points, TimeSeriesPoint{
+			Date:      d,
+			MaxAge:    maxAge,
+			TaskCount: taskCount,
+		})
+	}
+	return points, rows.Err()
+}
+
+// Close releases the database connection.
+func (s *sqlStorage) Close() error {
+	return s.db.Close()
+}
+
+// snapshotColumns are the columns scanSnapshot reads from snapshotTables.
+const (
+	snapshotColumns = `s.id, s.timestamp, s.total_age, s.task_count, s.task_lists_json, p.encoding, p.data, s.partial, s.missing_lists_json`
+	snapshotTables  = `snapshots s LEFT JOIN payloads p ON p.hash = s.task_lists_hash`
+)
+
+// snapshotRow holds the columns of a snapshot; buildSnapshot adds its list ages.
+type snapshotRow struct {
+	id          int64
+	tsStr       string
+	totalAge    int
+	taskCount   int
+	taskLists   taskListsColumn
+	partial     bool
+	missingJSON sql.NullString
+}
+
+// scanSnapshot scans a single snapshot from a Row. sql.ErrNoRows is
+// returned unwrapped.
+func (s *sqlStorage) scanSnapshot(ctx context.Context, row *sql.Row) (*StatsSnapshot, error) {
+	r, err := scanSnapshotRow(row)
+	if err != nil {
+		return nil, err
+	}
+	return s.buildSnapshot(ctx, r)
+}
+
+func scanSnapshotRow(row interface{ Scan(...any) error }) (snapshotRow, error) {
+	var r snapshotRow
+	if err := row.Scan(&r.id, &r.tsStr, &r.totalAge, &r.taskCount, &r.taskLists.json, &r.taskLists.encoding, &r.taskLists.data, &r.partial, &r.missingJSON); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return r, err
+		}
+		return r, fmt.Errorf("scan snapshot row: %w", err)
+	}
+	return r, nil
+}
+
+func (s *sqlStorage) buildSnapshot(ctx context.Context, r snapshotRow) (*StatsSnapshot, error) {
+	snapshot, err := decodeSnapshotTasks(r.tsStr, r.taskLists, r.missingJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	// Load list ages for this snapshot.
+	laRows, err := s.db.QueryContext(ctx,
+		`SELECT title, age, task_count, excluded FROM list_ages WHERE snapshot_id = ? ORDER BY id`, r.id)
+	if err != nil {
+		return nil, fmt.Errorf("query list ages: %w", err)
+	}
+	defer laRows.Close()
+
+	var listAges todometrics.ListAges
+	for laRows.Next() {
+		var la todometrics.ListAge
+		if err := laRows.Scan(&la.Title, &la.Age, &la.TaskCount, &la.Excluded); err != nil {
+			return nil, fmt.Errorf("scan list age: %w", err)
+		}
+		listAges.Ages = append(listAges.Ages, la)
+	}
+	if err := laRows.Err(); err != nil {
+		return nil, err
+	}
+	listAges.TotalAge = r.totalAge
+
+	snapshot.GlobalStats = GlobalStats{
+		TotalAge:  r.totalAge,
+		TaskCount: r.taskCount,
+	}
+	snapshot.ListAges = listAges
+	snapshot.Partial = r.partial
+	return &snapshot, nil
+}
+
+// decodeSnapshotTasks parses the timestamp, task lists and missing lists of a snapshot row.
+func decodeSnapshotTasks(tsStr string, taskListsColumn taskListsColumn, missingJSON sql.NullString) (StatsSnapshot, error) {
+	ts, err := time.Parse(time.RFC3339, tsStr)
+	if err != nil {
+		return StatsSnapshot{}, fmt.Errorf("parse timestamp %q: %w", tsStr, err)
+	}
+
+	// Deserialize task lists.
+	var taskLists []todo.TaskList
+	taskListsJSON, err := taskListsColumn.bytes()
+	if err != nil {
+		return StatsSnapshot{}, err
+	}
+	if taskListsJSON != nil {
+		if err := json.Unmarshal(taskListsJSON, &taskLists); err != nil {
+			return StatsSnapshot{}, fmt.Errorf("unmarshal task lists: %w", err)
+		}
+	}
+
+	var missingLists []MissingList
+	if missingJSON.Valid && missingJSON.String != "" {
+		if err := json.Unmarshal([]byte(missingJSON.String), &missingLists); err != nil {
+			return StatsSnapshot{}, fmt.Errorf("unmarshal missing lists: %w", err)
+		}
+	}
+
+	return StatsSnapshot{
+		Timestamp:    ts,
+		TaskLists:    taskLists,
+		MissingLists: missingLists,
+	}, nil
+}
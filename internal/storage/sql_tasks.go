@@ -0,0 +1,342 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/uchr/ToDoInfo/internal/todo"
+)
+
+// trackedTask is the part of a tasks row compared between snapshots.
+type trackedTask struct {
+	listID   string
+	listName string
+	title    string
+	due      string
+}
+
+// trackTasks updates the tasks table and records task_events from a
+// snapshot. Snapshots without task data are ignored. A task only counts as
+// gone if its list is in the snapshot, so lists that failed to fetch or are
+// no longer selected don't make their tasks vanish.
+func trackTasks(ctx context.Context, tx *sqlTx, snapshot StatsSnapshot) error {
+	if snapshot.TaskLists == nil {
+		return nil
+	}
+	at := snapshot.Timestamp.UTC().Format(time.RFC3339)
+
+	open, err := loadOpenTasks(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool)
+	fetchedLists := make(map[string]bool)
+	completed := make(map[string]bool)
+	for _, list := range snapshot.TaskLists {
+		fetchedLists[list.ID] = true
+		for _, task := range list.CompletedTasks {
+			completed[task.ID] = true
+		}
+		for _, task := range list.Tasks {
+			if task.ID == "" {
+				continue
+			}
+			seen[task.ID] = true
+			current := trackedTask{listID: list.ID, listName: list.Name, title: task.Title, due: dueString(task.DueDateTime)}
+			previous, ok := open[task.ID]
+			if !ok {
+				err = insertOrReviveTask(ctx, tx, at, task, current)
+			} else {
+				err = updateTask(ctx, tx, at, task.ID, previous, current)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for _, missing := range snapshot.MissingLists {
+		delete(fetchedLists, missing.ID)
+	}
+
+	for taskID, previous := range open {
+		if seen[taskID] || !fetchedLists[previous.listID] {
+			continue
+		}
+		reason := TaskRemoved
+		if completed[taskID] {
+			reason = TaskCompleted
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE tasks SET gone_at = ?, gone_reason = ? WHERE task_id = ?`, at, reason, taskID); err != nil {
+			return fmt.Errorf("mark task gone: %w", err)
+		}
+		if err := insertTaskEvent(ctx, tx, taskID, at, reason, "", ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillTaskHistory replays the stored snapshots, oldest first, into the
+// freshly created tasks and task_events tables, so history starts with the
+// first snapshot rather than with the upgrade.
+func backfillTaskHistory(ctx context.Context, tx *sqlTx) error {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT id FROM snapshots WHERE task_lists_json IS NOT NULL ORDER BY timestamp ASC, id ASC`)
+	if err != nil {
+		return fmt.Errorf("query snapshots: %w", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan snapshot id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Load one blob at a time; a long history doesn't fit in memory at once.
+	for _, id := range ids {
+		var (
+			tsStr     string
+			lists     taskListsColumn
+			missingJS sql.NullString
+		)
+		if err := tx.QueryRowContext(ctx,
+			`SELECT timestamp, task_lists_json, missing_lists_json FROM snapshots WHERE id = ?`, id).
+			Scan(&tsStr, &lists.json, &missingJS); err != nil {
+			return fmt.Errorf("query snapshot %d: %w", id, err)
+		}
+		snapshot, err := decodeSnapshotTasks(tsStr, lists, missingJS)
+		if err != nil {
+			return fmt.Errorf("snapshot %d: %w", id, err)
+		}
+		if err := trackTasks(ctx, tx, snapshot); err != nil {
+			return fmt.Errorf("snapshot %d: %w", id, err)
+		}
+	}
+	return nil
+}
+
+func loadOpenTasks(ctx context.Context, tx *sqlTx) (map[string]trackedTask, error) {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT task_id, list_id, list_name, title, due FROM tasks WHERE gone_at IS NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("query open tasks: %w", err)
+	}
+	defer rows.Close()
+
+	open := make(map[string]trackedTask)
+	for rows.Next() {
+		var (
+			taskID string
+			task   trackedTask
+			due    sql.NullString
+		)
+		if err := rows.Scan(&taskID, &task.listID, &task.listName, &task.title, &due); err != nil {
+			return nil, fmt.Errorf("scan open task: %w", err)
+		}
+		task.due = due.String
+		open[taskID] = task
+	}
+	return open, rows.Err()
+}
+
+// insertOrReviveTask records a task that isn't open in the tasks table:
+// either new, or back after it was completed or removed.
+func insertOrReviveTask(ctx context.Context, tx *sqlTx, at string, task todo.Task, current trackedTask) error {
+	var (
+		previous trackedTask
+		due      sql.NullString
+	)
+	err := tx.QueryRowContext(ctx,
+		`SELECT list_id, list_name, title, due FROM tasks WHERE task_id = ?`, task.ID).
+		Scan(&previous.listID, &previous.listName, &previous.title, &due)
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO tasks (task_id, list_id, list_name, title, due, created_at, first_seen, last_seen)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			task.ID, current.listID, current.listName, current.title, nullString(current.due),
+			task.CreatedDateTime.UTC().Format(time.RFC3339), at, at); err != nil {
+			return fmt.Errorf("insert task: %w", err)
+		}
+		return insertTaskEvent(ctx, tx, task.ID, at, TaskAppeared, "", current.listName)
+	}
+	if err != nil {
+		return fmt.Errorf("query task: %w", err)
+	}
+	previous.due = due.String
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE tasks SET gone_at = NULL, gone_reason = NULL WHERE task_id = ?`, task.ID); err != nil {
+		return fmt.Errorf("revive task: %w", err)
+	}
+	if err := insertTaskEvent(ctx, tx, task.ID, at, TaskReappeared, "", current.listName); err != nil {
+		return err
+	}
+	return updateTask(ctx, tx, at, task.ID, previous, current)
+}
+
+// updateTask records what changed since the last snapshot and bumps last_seen.
+func updateTask(ctx context.Context, tx *sqlTx, at, taskID string, previous, current trackedTask) error {
+	if previous.listID != current.listID {
+		if err := insertTaskEvent(ctx, tx, taskID, at, TaskMoved, previous.listName, current.listName); err != nil {
+			return err
+		}
+	}
+	if previous.title != current.title {
+		if err := insertTaskEvent(ctx, tx, taskID, at, TaskRenamed, previous.title, current.title); err != nil {
+			return err
+		}
+	}
+	if previous.due != current.due {
+		if err := insertTaskEvent(ctx, tx, taskID, at, TaskDueChanged, previous.due, current.due); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE tasks SET list_id = ?, list_name = ?, title = ?, due = ?, last_seen = ? WHERE task_id = ?`,
+		current.listID, current.listName, current.title, nullString(current.due), at, taskID); err != nil {
+		return fmt.Errorf("update task: %w", err)
+	}
+	return nil
+}
+
+func insertTaskEvent(ctx context.Context, tx *sqlTx, taskID, at string, kind TaskEventKind, oldValue, newValue string) error {
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO task_events (task_id, timestamp, kind, old_value, new_value) VALUES (?, ?, ?, ?, ?)`,
+		taskID, at, kind, oldValue, newValue); err != nil {
+		return fmt.Errorf("insert task event: %w", err)
+	}
+	return nil
+}
+
+func dueString(due *todo.DateTimeTimeZone) string {
+	if due == nil {
+		return ""
+	}
+	return due.Time.UTC().Format(time.RFC3339)
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+const taskRecordColumns = `task_id, list_id, list_name, title, due, created_at, first_seen, last_seen, gone_at, gone_reason`
+
+// GetTaskTimeline retrieves the lifecycle of a task by its Graph ID, or nil if it was never seen.
+func (s *sqlStorage) GetTaskTimeline(ctx context.Context, taskID string) (*TaskTimeline, error) {
+	row := s.db.QueryRowContext(ctx,
+		`SELECT `+taskRecordColumns+` FROM tasks WHERE task_id = ?`, taskID)
+	record, err := scanTaskRecord(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT timestamp, kind, old_value, new_value FROM task_events
+		 WHERE task_id = ? ORDER BY timestamp ASC, id ASC`, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("query task events: %w", err)
+	}
+	defer rows.Close()
+
+	timeline := &TaskTimeline{TaskRecord: *record}
+	for rows.Next() {
+		var (
+			event TaskEvent
+			tsStr string
+		)
+		if err := rows.Scan(&tsStr, &event.Kind, &event.OldValue, &event.NewValue); err != nil {
+			return nil, fmt.Errorf("scan task event: %w", err)
+		}
+		if event.Time, err = time.Parse(time.RFC3339, tsStr); err != nil {
+			return nil, fmt.Errorf("parse timestamp %q: %w", tsStr, err)
+		}
+		timeline.Events = append(timeline.Events, event)
+	}
+	return timeline, rows.Err()
+}
+
+// FindTaskRecords retrieves the tracked tasks whose title contains query,
+// ignoring case, most recently seen first.
+func (s *sqlStorage) FindTaskRecords(ctx context.Context, query string) ([]TaskRecord, error) {
+	pattern := "%" + likeEscaper.Replace(strings.ToLower(query)) + "%"
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+taskRecordColumns+` FROM tasks
+		 WHERE lower(title) LIKE ? ESCAPE '\'
+		 ORDER BY last_seen DESC, first_seen DESC`, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("query task records: %w", err)
+	}
+	defer rows.Close()
+
+	var records []TaskRecord
+	for rows.Next() {
+		record, err := scanTaskRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, *record)
+	}
+	return records, rows.Err()
+}
+
+// likeEscaper makes a string match itself in a LIKE pattern.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// scanTaskRecord scans taskRecordColumns from a Row or Rows.
+func scanTaskRecord(row interface{ Scan(...any) error }) (*TaskRecord, error) {
+	var (
+		record                         TaskRecord
+		due, goneAt, goneReason        sql.NullString
+		createdAt, firstSeen, lastSeen string
+	)
+	if err := row.Scan(&record.TaskID, &record.ListID, &record.ListName, &record.Title, &due,
+		&createdAt, &firstSeen, &lastSeen, &goneAt, &goneReason); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("scan task record: %w", err)
+	}
+
+	var err error
+	for _, field := range []struct {
+		value string
+		dst   *time.Time
+	}{{createdAt, &record.CreatedAt}, {firstSeen, &record.FirstSeen}, {lastSeen, &record.LastSeen}} {
+		if *field.dst, err = time.Parse(time.RFC3339, field.value); err != nil {
+			return nil, fmt.Errorf("parse timestamp %q: %w", field.value, err)
+		}
+	}
+	if due.Valid {
+		t, err := time.Parse(time.RFC3339, due.String)
+		if err != nil {
+			return nil, fmt.Errorf("parse due %q: %w", due.String, err)
+		}
+		record.Due = &t
+	}
+	if goneAt.Valid {
+		t, err := time.Parse(time.RFC3339, goneAt.String)
+		if err != nil {
+			return nil, fmt.Errorf("parse timestamp %q: %w", goneAt.String, err)
+		}
+		record.GoneAt = &t
+		record.GoneReason = TaskEventKind(goneReason.String)
+	}
+	return &record, nil
+}
@@ -3,52 +3,92 @@ package storage
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
-	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 	"time"
 
-	"github.com/uchr/ToDoInfo/internal/todo"
-	"github.com/uchr/ToDoInfo/internal/todometrics"
-
 	_ "modernc.org/sqlite"
 )
 
-// SQLiteStorage implements StatsStorage using a SQLite database.
+const (
+	// sqliteBusyTimeout bounds how long a writer waits for another one.
+	sqliteBusyTimeout = 10 * time.Second
+	// sqliteMaxConns caps the pool: readers share it, writers take turns.
+	sqliteMaxConns = 4
+)
+
+// SQLiteStorage implements StatsStorage using a SQLite database. Open it
+// once and share it; it is safe for concurrent use.
 type SQLiteStorage struct {
-	db *sql.DB
+	sqlStorage
 }
 
 // NewSQLiteStorage opens (or creates) a SQLite database at dbPath and runs migrations.
 func NewSQLiteStorage(dbPath string) (*SQLiteStorage, error) {
+	s, err := OpenSQLiteStorage(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.Migrate(context.Background()); err != nil {
+		s.Close()
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+	return s, nil
+}
+
+// OpenSQLiteStorage opens (or creates) a SQLite database at dbPath without
+// migrating it, for inspecting the schema with MigrationStatus.
+func OpenSQLiteStorage(dbPath string) (*SQLiteStorage, error) {
 	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
 		return nil, fmt.Errorf("create data directory: %w", err)
 	}
 
-	db, err := sql.Open("sqlite", dbPath)
+	// Every pooled connection waits for the write lock instead of failing
+	// with SQLITE_BUSY, and WAL mode lets readers run alongside the writer.
+	// Transactions take the write lock up front, as they all write: a read
+	// transaction upgraded later fails at once when another writer got in first.
+	params := url.Values{
+		"_pragma": {fmt.Sprintf("busy_timeout(%d)", sqliteBusyTimeout.Milliseconds()), "journal_mode(WAL)"},
+		"_txlock": {"immediate"},
+	}
+	db, err := sql.Open("sqlite", dbPath+"?"+params.Encode())
 	if err != nil {
 		return nil, fmt.Errorf("open sqlite db: %w", err)
 	}
+	db.SetMaxOpenConns(sqliteMaxConns)
+	db.SetMaxIdleConns(sqliteMaxConns)
 
-	// Enable WAL mode for better concurrent read performance.
-	if _, err := db.Exec("PRAGMA journal_mode=WAL"); err != nil {
+	// Opening is lazy; connect now so a bad path fails here.
+	if err := db.Ping(); err != nil {
 		db.Close()
-		return nil, fmt.Errorf("set WAL mode: %w", err)
+		return nil, fmt.Errorf("open sqlite db: %w", err)
 	}
 
-	s := &SQLiteStorage{db: db}
-	if err := s.migrate(); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("migrate: %w", err)
-	}
+	return &SQLiteStorage{sqlStorage{
+		db:      &sqlDB{DB: db, rebind: func(query string) string { return query }},
+		dialect: sqliteDialect,
+	}}, nil
+}
 
-	return s, nil
+// sqliteDialect runs the queries as written. SQLite serialises writers on
+// its own, so it needs no lock.
+var sqliteDialect = dialect{
+	migrations:      sqliteMigrations,
+	migrationsDDL:   sqliteMigrationsDDL,
+	tableCountQuery: sqliteTableCountQuery,
+	usedBytesQuery:  `SELECT (page_count - freelist_count) * page_size FROM pragma_page_count(), pragma_freelist_count(), pragma_page_size()`,
+	fileBytesQuery:  `SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()`,
+	vacuum:          `VACUUM`,
 }
 
-func (s *SQLiteStorage) migrate() error {
-	const ddl = `
+// sqliteMigrations are applied in order and never edited once released;
+// change the schema by appending a new one, to postgresMigrations as well.
+// The first four date from before versioning and are idempotent, so
+// databases created back then adopt them without harm.
+var sqliteMigrations = []migration{
+	{1, "create snapshots and list ages", execMigration(`
 CREATE TABLE IF NOT EXISTS snapshots (
     id              INTEGER PRIMARY KEY AUTOINCREMENT,
     timestamp       DATETIME NOT NULL,
@@ -67,218 +107,141 @@ CREATE TABLE IF NOT EXISTS list_ages (
 
 CREATE INDEX IF NOT EXISTS idx_snapshots_timestamp ON snapshots(timestamp);
 CREATE INDEX IF NOT EXISTS idx_list_ages_snapshot   ON list_ages(snapshot_id);
-`
-	_, err := s.db.Exec(ddl)
-	return err
-}
-
-// Store saves a statistics snapshot.
-func (s *SQLiteStorage) Store(ctx context.Context, snapshot StatsSnapshot) error {
-	tx, err := s.db.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("begin tx: %w", err)
-	}
-	defer tx.Rollback()
-
-	// Serialize task lists to JSON.
-	var taskListsJSON []byte
-	if len(snapshot.TaskLists) > 0 {
-		taskListsJSON, err = json.Marshal(snapshot.TaskLists)
-		if err != nil {
-			return fmt.Errorf("marshal task lists: %w", err)
+`)},
+	{2, "add partial snapshot columns", func(ctx context.Context, tx *sqlTx) error {
+		if err := addColumnIfMissing(ctx, tx, "snapshots", "partial", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+			return err
 		}
-	}
-
-	res, err := tx.ExecContext(ctx,
-		`INSERT INTO snapshots (timestamp, total_age, task_count, task_lists_json)
-		 VALUES (?, ?, ?, ?)`,
-		snapshot.Timestamp.UTC().Format(time.RFC3339),
-		snapshot.GlobalStats.TotalAge,
-		snapshot.GlobalStats.TaskCount,
-		taskListsJSON,
-	)
-	if err != nil {
-		return fmt.Errorf("insert snapshot: %w", err)
-	}
+		return addColumnIfMissing(ctx, tx, "snapshots", "missing_lists_json", "TEXT")
+	}},
+	{3, "create delta state", execMigration(`
+CREATE TABLE IF NOT EXISTS delta_links (
+    scope TEXT PRIMARY KEY,
+    link  TEXT NOT NULL
+);
 
-	snapshotID, err := res.LastInsertId()
-	if err != nil {
-		return fmt.Errorf("last insert id: %w", err)
-	}
+CREATE TABLE IF NOT EXISTS delta_lists (
+    list_id             TEXT PRIMARY KEY,
+    name                TEXT NOT NULL,
+    wellknown_list_name TEXT NOT NULL,
+    is_shared           INTEGER NOT NULL
+);
 
-	// Insert list ages.
-	stmt, err := tx.PrepareContext(ctx,
-		`INSERT INTO list_ages (snapshot_id, title, age, task_count) VALUES (?, ?, ?, ?)`)
-	if err != nil {
-		return fmt.Errorf("prepare list_ages insert: %w", err)
-	}
-	defer stmt.Close()
+CREATE TABLE IF NOT EXISTS delta_tasks (
+    list_id   TEXT NOT NULL REFERENCES delta_lists(list_id),
+    task_id   TEXT NOT NULL,
+    task_json TEXT NOT NULL,
+    PRIMARY KEY (list_id, task_id)
+);
+`)},
+	{4, "create task history", migrateTaskHistory},
+	{5, "share compressed task list payloads", execMigration(`
+CREATE TABLE payloads (
+    hash     TEXT PRIMARY KEY,
+    encoding TEXT NOT NULL,
+    data     BLOB NOT NULL
+);
 
-	for _, la := range snapshot.ListAges.Ages {
-		if _, err := stmt.ExecContext(ctx, snapshotID, la.Title, la.Age, la.TaskCount); err != nil {
-			return fmt.Errorf("insert list age: %w", err)
-		}
-	}
+ALTER TABLE snapshots ADD COLUMN task_lists_hash TEXT REFERENCES payloads(hash);
 
-	return tx.Commit()
+CREATE INDEX idx_snapshots_task_lists_hash ON snapshots(task_lists_hash);
+`)},
+	{6, "add excluded list age column", execMigration(`
+ALTER TABLE list_ages ADD COLUMN excluded INTEGER NOT NULL DEFAULT 0;
+`)},
 }
 
-// GetLatest retrieves the most recent statistics snapshot.
-func (s *SQLiteStorage) GetLatest(ctx context.Context) (*StatsSnapshot, error) {
-	row := s.db.QueryRowContext(ctx,
-		`SELECT id, timestamp, total_age, task_count, task_lists_json
-		 FROM snapshots ORDER BY timestamp DESC LIMIT 1`)
+const taskHistoryDDL = `
+CREATE TABLE tasks (
+    task_id     TEXT PRIMARY KEY,
+    list_id     TEXT NOT NULL,
+    list_name   TEXT NOT NULL,
+    title       TEXT NOT NULL,
+    due         DATETIME,
+    created_at  DATETIME NOT NULL,
+    first_seen  DATETIME NOT NULL,
+    last_seen   DATETIME NOT NULL,
+    gone_at     DATETIME,
+    gone_reason TEXT
+);
 
-	snap, err := s.scanSnapshot(ctx, row)
-	if errors.Is(err, sql.ErrNoRows) {
-		return nil, nil
-	}
-	if err != nil {
-		return nil, err
-	}
-	return snap, nil
-}
+CREATE TABLE task_events (
+    id        INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id   TEXT NOT NULL REFERENCES tasks(task_id),
+    timestamp DATETIME NOT NULL,
+    kind      TEXT NOT NULL,
+    old_value TEXT NOT NULL,
+    new_value TEXT NOT NULL
+);
 
-// GetHistory retrieves statistics history for a given time period.
-func (s *SQLiteStorage) GetHistory(ctx context.Context, from, to time.Time) ([]StatsSnapshot, error) {
-	rows, err := s.db.QueryContext(ctx,
-		`SELECT id, timestamp, total_age, task_count, task_lists_json
-		 FROM snapshots
-		 WHERE timestamp > ? AND timestamp < ?
-		 ORDER BY timestamp ASC`,
-		from.UTC().Format(time.RFC3339),
-		to.UTC().Format(time.RFC3339),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("query history: %w", err)
-	}
-	defer rows.Close()
+CREATE INDEX idx_tasks_open       ON tasks(gone_at);
+CREATE INDEX idx_task_events_task ON task_events(task_id, timestamp);
+`
 
-	var snapshots []StatsSnapshot
-	for rows.Next() {
-		snap, err := s.scanSnapshotFromRows(ctx, rows)
-		if err != nil {
-			return nil, err
-		}
-		snapshots = append(snapshots, *snap)
+// migrateTaskHistory creates the task history and fills it from the
+// snapshots already stored. Databases that got the tables before
+// versioning keep them as they are.
+func migrateTaskHistory(ctx context.Context, tx *sqlTx) error {
+	exists, err := tableExists(ctx, tx, "tasks")
+	if err != nil || exists {
+		return err
 	}
-	return snapshots, rows.Err()
-}
-
-// GetTimeSeriesData retrieves time series data for graphing.
-func (s *SQLiteStorage) GetTimeSeriesData(ctx context.Context, days int) ([]TimeSeriesPoint, error) {
-	cutoff := time.Now().AddDate(0, 0, -days).UTC().Format(time.RFC3339)
-
-	rows, err := s.db.QueryContext(ctx,
-		`SELECT date(timestamp) AS d, MAX(total_age), MAX(task_count)
-		 FROM snapshots
-		 WHERE timestamp > ?
-		 GROUP BY d
-		 ORDER BY d ASC`,
-		cutoff,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("query time series: %w", err)
+	if _, err := tx.ExecContext(ctx, taskHistoryDDL); err != nil {
+		return err
 	}
-	defer rows.Close()
-
-	var points []TimeSeriesPoint
-	for rows.Next() {
-		var dateStr string
-		var maxAge, taskCount int
-		if err := rows.Scan(&dateStr, &maxAge, &taskCount); err != nil {
-			return nil, fmt.Errorf("scan time series row: %w", err)
-		}
-		d, err := time.Parse("2006-01-02", dateStr)
-		if err != nil {
-			return nil, fmt.Errorf("parse date %q: %w", dateStr, err)
-		}
-		points = append(points, TimeSeriesPoint{
-			Date:      d,
-			MaxAge:    maxAge,
-			TaskCount: taskCount,
-		})
+	if err := backfillTaskHistory(ctx, tx); err != nil {
+		return fmt.Errorf("backfill task history: %w", err)
 	}
-	return points, rows.Err()
+	return nil
 }
 
-// Close releases the database connection.
-func (s *SQLiteStorage) Close() error {
-	return s.db.Close()
-}
-
-// scanSnapshot scans a single snapshot row (from QueryRow).
-func (s *SQLiteStorage) scanSnapshot(ctx context.Context, row *sql.Row) (*StatsSnapshot, error) {
-	var (
-		id           int64
-		tsStr        string
-		totalAge     int
-		taskCount    int
-		taskListJSON sql.NullString
-	)
-	if err := row.Scan(&id, &tsStr, &totalAge, &taskCount, &taskListJSON); err != nil {
-		return nil, err
-	}
-	return s.buildSnapshot(ctx, id, tsStr, totalAge, taskCount, taskListJSON)
-}
+const sqliteMigrationsDDL = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version    INTEGER PRIMARY KEY,
+    name       TEXT NOT NULL,
+    applied_at DATETIME NOT NULL
+);`
 
-// scanSnapshotFromRows scans a single snapshot from an active Rows cursor.
-func (s *SQLiteStorage) scanSnapshotFromRows(ctx context.Context, rows *sql.Rows) (*StatsSnapshot, error) {
-	var (
-		id           int64
-		tsStr        string
-		totalAge     int
-		taskCount    int
-		taskListJSON sql.NullString
-	)
-	if err := rows.Scan(&id, &tsStr, &totalAge, &taskCount, &taskListJSON); err != nil {
-		return nil, fmt.Errorf("scan snapshot row: %w", err)
-	}
-	return s.buildSnapshot(ctx, id, tsStr, totalAge, taskCount, taskListJSON)
-}
+const sqliteTableCountQuery = `SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ?`
 
-func (s *SQLiteStorage) buildSnapshot(ctx context.Context, id int64, tsStr string, totalAge, taskCount int, taskListJSON sql.NullString) (*StatsSnapshot, error) {
-	ts, err := time.Parse(time.RFC3339, tsStr)
+// tableExists reports whether the database has table.
+func tableExists(ctx context.Context, tx *sqlTx, table string) (bool, error) {
+	var n int
+	err := tx.QueryRowContext(ctx, sqliteTableCountQuery, table).Scan(&n)
 	if err != nil {
-		return nil, fmt.Errorf("parse timestamp %q: %w", tsStr, err)
+		return false, fmt.Errorf("look up table %s: %w", table, err)
 	}
+	return n > 0, nil
+}
 
-	// Load list ages for this snapshot.
-	laRows, err := s.db.QueryContext(ctx,
-		`SELECT title, age, task_count FROM list_ages WHERE snapshot_id = ?`, id)
+// addColumnIfMissing adds column to table unless it already exists.
+func addColumnIfMissing(ctx context.Context, tx *sqlTx, table, column, definition string) error {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("PRAGMA table_info(%s)", table))
 	if err != nil {
-		return nil, fmt.Errorf("query list ages: %w", err)
+		return fmt.Errorf("table info %s: %w", table, err)
 	}
-	defer laRows.Close()
+	defer rows.Close()
 
-	var listAges todometrics.ListAges
-	for laRows.Next() {
-		var la todometrics.ListAge
-		if err := laRows.Scan(&la.Title, &la.Age, &la.TaskCount); err != nil {
-			return nil, fmt.Errorf("scan list age: %w", err)
+	for rows.Next() {
+		var (
+			cid          int
+			name, ctype  string
+			notNull, pk  int
+			defaultValue sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &ctype, &notNull, &defaultValue, &pk); err != nil {
+			return fmt.Errorf("scan table info %s: %w", table, err)
+		}
+		if name == column {
+			return nil
 		}
-		listAges.Ages = append(listAges.Ages, la)
 	}
-	if err := laRows.Err(); err != nil {
-		return nil, err
+	if err := rows.Err(); err != nil {
+		return err
 	}
-	listAges.TotalAge = totalAge
 
-	// Deserialize task lists.
-	var taskLists []todo.TaskList
-	if taskListJSON.Valid && taskListJSON.String != "" {
-		if err := json.Unmarshal([]byte(taskListJSON.String), &taskLists); err != nil {
-			return nil, fmt.Errorf("unmarshal task lists: %w", err)
-		}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("add column %s.%s: %w", table, column, err)
 	}
-
-	return &StatsSnapshot{
-		Timestamp: ts,
-		GlobalStats: GlobalStats{
-			TotalAge:  totalAge,
-			TaskCount: taskCount,
-		},
-		ListAges:  listAges,
-		TaskLists: taskLists,
-	}, nil
+	return nil
 }
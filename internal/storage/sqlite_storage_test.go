@@ -1,19 +1,30 @@
 package storage
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/uchr/ToDoInfo/internal/todo"
+	"github.com/uchr/ToDoInfo/internal/todoclient"
 	"github.com/uchr/ToDoInfo/internal/todometrics"
 )
 
 func newTestSQLiteStorage(t *testing.T) *SQLiteStorage {
 	t.Helper()
-	dir := t.TempDir()
-	dbPath := filepath.Join(dir, "test.db")
+	return newTestSQLiteStorageAt(t, filepath.Join(t.TempDir(), "test.db"))
+}
+
+func newTestSQLiteStorageAt(t *testing.T, dbPath string) *SQLiteStorage {
+	t.Helper()
 	s, err := NewSQLiteStorage(dbPath)
 	if err != nil {
 		t.Fatalf("NewSQLiteStorage: %v", err)
@@ -154,7 +165,7 @@ func TestSQLiteStorage_GetTimeSeriesData(t *testing.T) {
 		ListAges:    todometrics.ListAges{TotalAge: 30},
 	})
 
-	points, err := s.GetTimeSeriesData(ctx, 7)
+	points, err := s.GetTimeSeriesData(ctx, now, 7)
 	if err != nil {
 		t.Fatalf("GetTimeSeriesData: %v", err)
 	}
@@ -173,6 +184,45 @@ func TestSQLiteStorage_GetTimeSeriesData(t *testing.T) {
 	if points[1].TaskCount != 5 {
 		t.Errorf("today TaskCount = %d, want 5", points[1].TaskCount)
 	}
+
+	// As of yesterday, today's snapshots don't exist yet.
+	points, err = s.GetTimeSeriesData(ctx, now.Add(-23*time.Hour), 7)
+	if err != nil {
+		t.Fatalf("GetTimeSeriesData as of yesterday: %v", err)
+	}
+	if len(points) != 1 || points[0].MaxAge != 30 {
+		t.Errorf("points as of yesterday = %+v, want only yesterday's", points)
+	}
+}
+
+func TestSQLiteStorage_GetAt(t *testing.T) {
+	s := newTestSQLiteStorage(t)
+	ctx := t.Context()
+
+	monday := time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)
+	for i, age := range []int{10, 20, 30} {
+		s.Store(ctx, StatsSnapshot{
+			Timestamp:   monday.AddDate(0, 0, i),
+			GlobalStats: GlobalStats{TotalAge: age, TaskCount: 1},
+			ListAges:    todometrics.ListAges{TotalAge: age},
+		})
+	}
+
+	snap, err := s.GetAt(ctx, monday.AddDate(0, 0, 1).Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GetAt: %v", err)
+	}
+	if snap == nil || snap.GlobalStats.TotalAge != 20 {
+		t.Fatalf("GetAt Tuesday = %+v, want TotalAge 20", snap)
+	}
+
+	snap, err = s.GetAt(ctx, monday.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("GetAt before first snapshot: %v", err)
+	}
+	if snap != nil {
+		t.Errorf("GetAt before first snapshot = %+v, want nil", snap)
+	}
 }
 
 func TestSQLiteStorage_TaskListsRoundTrip(t *testing.T) {
@@ -235,3 +285,754 @@ func TestSQLiteStorage_MigrationOnFreshDB(t *testing.T) {
 		t.Fatalf("GetLatest on fresh DB: err=%v, latest=%v", err, latest)
 	}
 }
+
+func TestSQLiteStorage_PartialSnapshotRoundTrip(t *testing.T) {
+	s := newTestSQLiteStorage(t)
+	ctx := t.Context()
+
+	snap := StatsSnapshot{
+		Timestamp:   time.Now().Truncate(time.Second),
+		GlobalStats: GlobalStats{TotalAge: 3, TaskCount: 1},
+		TaskLists:   []todo.TaskList{{Name: "Work", Tasks: []todo.Task{{Title: "Fix bug"}}}},
+		Partial:     true,
+		MissingLists: []MissingList{
+			{ID: "shared-1", Name: "Family", Error: "response error [403] 'accessDenied': no access"},
+		},
+	}
+	if err := s.Store(ctx, snap); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+
+	latest, err := s.GetLatest(ctx)
+	if err != nil {
+		t.Fatalf("GetLatest: %v", err)
+	}
+	if !latest.Partial {
+		t.Error("Partial = false, want true")
+	}
+	if len(latest.MissingLists) != 1 || latest.MissingLists[0] != snap.MissingLists[0] {
+		t.Errorf("MissingLists = %+v, want %+v", latest.MissingLists, snap.MissingLists)
+	}
+}
+
+func TestSQLiteStorage_MigrationAddsPartialColumns(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "old.db")
+
+	// A database created before snapshots had the partial columns.
+	old, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	_, err = old.Exec(`
+CREATE TABLE snapshots (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    timestamp       DATETIME NOT NULL,
+    total_age       INTEGER NOT NULL,
+    task_count      INTEGER NOT NULL,
+    task_lists_json TEXT
+);
+INSERT INTO snapshots (timestamp, total_age, task_count) VALUES ('2026-10-01T08:00:00Z', 5, 2);`)
+	old.Close()
+	if err != nil {
+		t.Fatalf("create old schema: %v", err)
+	}
+
+	s, err := NewSQLiteStorage(dbPath)
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage on old DB: %v", err)
+	}
+	defer s.Close()
+
+	latest, err := s.GetLatest(t.Context())
+	if err != nil {
+		t.Fatalf("GetLatest: %v", err)
+	}
+	if latest.GlobalStats.TotalAge != 5 || latest.Partial || latest.MissingLists != nil {
+		t.Errorf("old snapshot = %+v, want total age 5 and not partial", latest)
+	}
+
+	// Opening again must not try to add the columns twice.
+	s2, err := NewSQLiteStorage(dbPath)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	s2.Close()
+}
+
+func TestSQLiteStorage_DeltaStateRoundTrip(t *testing.T) {
+	s := newTestSQLiteStorage(t)
+	ctx := t.Context()
+
+	empty, err := s.LoadDeltaState(ctx)
+	if err != nil {
+		t.Fatalf("LoadDeltaState on empty DB: %v", err)
+	}
+	if empty.ListsDeltaLink != "" || len(empty.Lists) != 0 {
+		t.Fatalf("expected empty state, got %+v", empty)
+	}
+
+	created := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	state := todoclient.NewDeltaState()
+	state.ListsDeltaLink = "https://graph.example/lists/delta?token=1"
+	state.Lists["list-1"] = &todoclient.ListDeltaState{
+		ID:                "list-1",
+		Name:              "Work",
+		WellknownListName: "none",
+		DeltaLink:         "https://graph.example/list-1/delta?token=2",
+		Tasks: map[string]todo.Task{
+			"task-1": {ID: "task-1", Status: "notStarted", Title: "Fix bug", CreatedDateTime: created, LastModifiedDateTime: created},
+		},
+	}
+	state.Lists["list-2"] = &todoclient.ListDeltaState{
+		ID:       "list-2",
+		Name:     "Shared",
+		IsShared: true,
+		Tasks:    map[string]todo.Task{},
+	}
+
+	if err := s.SaveDeltaState(ctx, state); err != nil {
+		t.Fatalf("SaveDeltaState: %v", err)
+	}
+	// Saving twice must replace rather than duplicate rows.
+	if err := s.SaveDeltaState(ctx, state); err != nil {
+		t.Fatalf("SaveDeltaState again: %v", err)
+	}
+
+	loaded, err := s.LoadDeltaState(ctx)
+	if err != nil {
+		t.Fatalf("LoadDeltaState: %v", err)
+	}
+	if loaded.ListsDeltaLink != state.ListsDeltaLink {
+		t.Errorf("ListsDeltaLink = %q, want %q", loaded.ListsDeltaLink, state.ListsDeltaLink)
+	}
+	if len(loaded.Lists) != 2 {
+		t.Fatalf("Lists count = %d, want 2", len(loaded.Lists))
+	}
+	work := loaded.Lists["list-1"]
+	if work.DeltaLink != state.Lists["list-1"].DeltaLink {
+		t.Errorf("list DeltaLink = %q, want %q", work.DeltaLink, state.Lists["list-1"].DeltaLink)
+	}
+	task, ok := work.Tasks["task-1"]
+	if !ok {
+		t.Fatal("task-1 missing after round trip")
+	}
+	if task.Title != "Fix bug" || !task.CreatedDateTime.Equal(created) {
+		t.Errorf("task = %+v", task)
+	}
+	if !loaded.Lists["list-2"].IsShared {
+		t.Error("list-2 IsShared lost")
+	}
+}
+
+// historySnapshots walks a task through its lifecycle: it appears in Work,
+// is renamed and given a due date, moves to Home, survives Home failing to
+// fetch, is completed, reappears and is finally deleted.
+func historySnapshots() []StatsSnapshot {
+	base := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	created := base.AddDate(0, 0, -3)
+	due := &todo.DateTimeTimeZone{Time: time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC), TimeZone: "UTC"}
+	task := func(title string, due *todo.DateTimeTimeZone) todo.Task {
+		return todo.Task{ID: "task-1", Title: title, Status: "notStarted", CreatedDateTime: created, DueDateTime: due}
+	}
+	work := func(tasks ...todo.Task) todo.TaskList {
+		return todo.TaskList{ID: "work", Name: "Work", Tasks: tasks}
+	}
+	home := func(tasks ...todo.Task) todo.TaskList {
+		return todo.TaskList{ID: "home", Name: "Home", Tasks: tasks}
+	}
+	snapshot := func(day int, lists ...todo.TaskList) StatsSnapshot {
+		return StatsSnapshot{Timestamp: base.AddDate(0, 0, day), TaskLists: lists}
+	}
+
+	failed := snapshot(4, work())
+	failed.Partial = true
+	failed.MissingLists = []MissingList{{ID: "home", Name: "Home", Error: "timeout"}}
+	completed := snapshot(5, work(), home())
+	completed.TaskLists[1].CompletedTasks = []todo.Task{task("Write report", due)}
+
+	return []StatsSnapshot{
+		snapshot(0, work(task("Write repor", nil)), home()),
+		snapshot(1, work(task("Write report", nil)), home()),
+		snapshot(2, work(task("Write report", due)), home()),
+		snapshot(3, work(), home(task("Write report", due))),
+		failed,
+		completed,
+		snapshot(6, work(), home(task("Write report", due))),
+		snapshot(7, work(), home()),
+	}
+}
+
+func TestSQLiteStorage_TaskTimeline(t *testing.T) {
+	s := newTestSQLiteStorage(t)
+	ctx := t.Context()
+
+	snapshots := historySnapshots()
+	for _, snap := range snapshots {
+		if err := s.Store(ctx, snap); err != nil {
+			t.Fatalf("Store: %v", err)
+		}
+	}
+
+	timeline, err := s.GetTaskTimeline(ctx, "task-1")
+	if err != nil {
+		t.Fatalf("GetTaskTimeline: %v", err)
+	}
+	if timeline == nil {
+		t.Fatal("GetTaskTimeline returned nil")
+	}
+	checkTaskTimeline(t, timeline, snapshots)
+
+	missing, err := s.GetTaskTimeline(ctx, "unknown")
+	if err != nil || missing != nil {
+		t.Errorf("GetTaskTimeline(unknown) = %+v, %v, want nil", missing, err)
+	}
+
+	records, err := s.FindTaskRecords(ctx, "REPORT")
+	if err != nil {
+		t.Fatalf("FindTaskRecords: %v", err)
+	}
+	if len(records) != 1 || records[0].TaskID != "task-1" {
+		t.Errorf("FindTaskRecords = %+v, want task-1", records)
+	}
+	if records, _ := s.FindTaskRecords(ctx, "groceries"); len(records) != 0 {
+		t.Errorf("FindTaskRecords(groceries) = %+v, want none", records)
+	}
+}
+
+func TestSQLiteStorage_MigrationBackfillsTaskHistory(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "old.db")
+	// Snapshots stored before tasks were tracked.
+	s := newLegacySQLiteStorage(t, dbPath, 3)
+	snapshots := historySnapshots()
+	for _, snap := range snapshots {
+		storeLegacySnapshot(t, s, snap)
+	}
+	s.Close()
+
+	var err error
+
+	s, err = NewSQLiteStorage(dbPath)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer s.Close()
+
+	timeline, err := s.GetTaskTimeline(t.Context(), "task-1")
+	if err != nil {
+		t.Fatalf("GetTaskTimeline: %v", err)
+	}
+	if timeline == nil {
+		t.Fatal("history wasn't backfilled")
+	}
+	checkTaskTimeline(t, timeline, snapshots)
+}
+
+func checkTaskTimeline(t *testing.T, timeline *TaskTimeline, snapshots []StatsSnapshot) {
+	t.Helper()
+
+	want := []TaskEvent{
+		{Kind: TaskAppeared, NewValue: "Work"},
+		{Kind: TaskRenamed, OldValue: "Write repor", NewValue: "Write report"},
+		{Kind: TaskDueChanged, NewValue: "2026-10-10T00:00:00Z"},
+		{Kind: TaskMoved, OldValue: "Work", NewValue: "Home"},
+		{Kind: TaskCompleted},
+		{Kind: TaskReappeared, NewValue: "Home"},
+		{Kind: TaskRemoved},
+	}
+	wantDays := []int{0, 1, 2, 3, 5, 6, 7}
+	if len(timeline.Events) != len(want) {
+		t.Fatalf("events = %+v, want %d", timeline.Events, len(want))
+	}
+	for i, event := range timeline.Events {
+		w := want[i]
+		w.Time = snapshots[wantDays[i]].Timestamp
+		if event != w {
+			t.Errorf("event %d = %+v, want %+v", i, event, w)
+		}
+	}
+
+	record := timeline.TaskRecord
+	if record.ListName != "Home" || record.Title != "Write report" || record.Due == nil {
+		t.Errorf("record = %+v", record)
+	}
+	if !record.FirstSeen.Equal(snapshots[0].Timestamp) || !record.LastSeen.Equal(snapshots[6].Timestamp) {
+		t.Errorf("seen %v to %v, want %v to %v",
+			record.FirstSeen, record.LastSeen, snapshots[0].Timestamp, snapshots[6].Timestamp)
+	}
+	if record.GoneAt == nil || !record.GoneAt.Equal(snapshots[7].Timestamp) || record.GoneReason != TaskRemoved {
+		t.Errorf("gone at %v (%s), want %v (removed)", record.GoneAt, record.GoneReason, snapshots[7].Timestamp)
+	}
+}
+
+func TestSQLiteStorage_MigrateUnversionedFixture(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "stats.db")
+	fixture, err := os.ReadFile("testdata/unversioned.sql")
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	old, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	_, err = old.Exec(string(fixture))
+	old.Close()
+	if err != nil {
+		t.Fatalf("load fixture: %v", err)
+	}
+
+	s, err := OpenSQLiteStorage(dbPath)
+	if err != nil {
+		t.Fatalf("OpenSQLiteStorage: %v", err)
+	}
+	defer s.Close()
+	ctx := t.Context()
+
+	statuses, err := s.MigrationStatus(ctx)
+	if err != nil {
+		t.Fatalf("MigrationStatus: %v", err)
+	}
+	if len(statuses) != len(sqliteMigrations) {
+		t.Fatalf("statuses = %+v, want %d", statuses, len(sqliteMigrations))
+	}
+	for _, status := range statuses {
+		if status.AppliedAt != nil {
+			t.Errorf("migration %d applied before Migrate", status.Version)
+		}
+	}
+	var n int
+	if err := s.db.QueryRow(`SELECT count(*) FROM sqlite_master WHERE name = 'schema_migrations'`).Scan(&n); err != nil || n != 0 {
+		t.Errorf("MigrationStatus created schema_migrations (count %d, %v)", n, err)
+	}
+
+	applied, err := s.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if len(applied) != len(sqliteMigrations) {
+		t.Errorf("applied %d migrations, want %d", len(applied), len(sqliteMigrations))
+	}
+	statuses, _ = s.MigrationStatus(ctx)
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			t.Errorf("migration %d still pending", status.Version)
+		}
+	}
+	if again, err := s.Migrate(ctx); err != nil || len(again) != 0 {
+		t.Errorf("second Migrate = %+v, %v, want nothing applied", again, err)
+	}
+
+	// The data survives and the upgraded schema takes new snapshots.
+	latest, err := s.GetLatest(ctx)
+	if err != nil {
+		t.Fatalf("GetLatest: %v", err)
+	}
+	if latest.GlobalStats.TotalAge != 12 || !latest.Partial || len(latest.TaskLists) != 1 || len(latest.ListAges.Ages) != 1 {
+		t.Errorf("fixture snapshot = %+v", latest)
+	}
+	delta, err := s.LoadDeltaState(ctx)
+	if err != nil || delta.ListsDeltaLink == "" {
+		t.Errorf("LoadDeltaState = %+v, %v, want the fixture link", delta, err)
+	}
+	timeline, err := s.GetTaskTimeline(ctx, "task-1")
+	if err != nil || timeline == nil || len(timeline.Events) != 1 {
+		t.Fatalf("GetTaskTimeline = %+v, %v, want the fixture event only", timeline, err)
+	}
+
+	next := *latest
+	next.Timestamp = latest.Timestamp.Add(24 * time.Hour)
+	next.TaskLists[0].Tasks[0].Title = "Write the report"
+	if err := s.Store(ctx, next); err != nil {
+		t.Fatalf("Store after migrating: %v", err)
+	}
+	timeline, _ = s.GetTaskTimeline(ctx, "task-1")
+	if len(timeline.Events) != 2 || timeline.Events[1].Kind != TaskRenamed {
+		t.Errorf("events = %+v, want a rename after the fixture event", timeline.Events)
+	}
+}
+
+func TestSQLiteStorage_MigrationRollsBack(t *testing.T) {
+	s := newTestSQLiteStorage(t)
+	ctx := t.Context()
+
+	s.dialect.migrations = append(slices.Clone(sqliteMigrations), migration{sqliteDialect.latestVersion() + 1, "half done", func(ctx context.Context, tx *sqlTx) error {
+		if _, err := tx.ExecContext(ctx, `CREATE TABLE half_done (id INTEGER)`); err != nil {
+			return err
+		}
+		return errors.New("boom")
+	}})
+
+	if _, err := s.Migrate(ctx); err == nil {
+		t.Fatal("Migrate succeeded, want the error of the last migration")
+	}
+	var n int
+	if err := s.db.QueryRow(`SELECT count(*) FROM sqlite_master WHERE name = 'half_done'`).Scan(&n); err != nil || n != 0 {
+		t.Errorf("half_done table left behind (count %d, %v)", n, err)
+	}
+	statuses, _ := s.MigrationStatus(ctx)
+	if last := statuses[len(statuses)-1]; last.AppliedAt != nil {
+		t.Errorf("failed migration recorded as applied: %+v", last)
+	}
+}
+
+func TestSQLiteStorage_MigrateRefusesNewerSchema(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "stats.db")
+	s, err := NewSQLiteStorage(dbPath)
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage: %v", err)
+	}
+	_, err = s.db.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, 'from the future', '2027-01-01T00:00:00Z')`,
+		sqliteDialect.latestVersion()+1)
+	s.Close()
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	_, err = NewSQLiteStorage(dbPath)
+	var tooNew *SchemaTooNewError
+	if !errors.As(err, &tooNew) || tooNew.Version != sqliteDialect.latestVersion()+1 {
+		t.Fatalf("NewSQLiteStorage = %v, want SchemaTooNewError", err)
+	}
+}
+
+func TestSQLiteStorage_Prune(t *testing.T) {
+	s := newTestSQLiteStorage(t)
+	ctx := t.Context()
+
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	lists := []todo.TaskList{{ID: "work", Name: "Work", Tasks: []todo.Task{{ID: "task-1", Title: "Write report", CreatedDateTime: now.AddDate(-1, 0, 0)}}}}
+	// Four snapshots a day, every day for 80 days.
+	for day := range 80 {
+		for hour := 0; hour < 24; hour += 6 {
+			snap := StatsSnapshot{
+				Timestamp:   now.AddDate(0, 0, -day).Add(-time.Duration(hour) * time.Hour),
+				GlobalStats: GlobalStats{TotalAge: day, TaskCount: 1},
+				ListAges:    todometrics.ListAges{TotalAge: day, Ages: []todometrics.ListAge{{Title: "Work", Age: day, TaskCount: 1}}},
+				TaskLists:   lists,
+			}
+			if err := s.Store(ctx, snap); err != nil {
+				t.Fatalf("Store: %v", err)
+			}
+		}
+	}
+
+	policy := RetentionPolicy{KeepAllDays: 3, DailyMonths: 1, BlobDays: 10}
+	result, err := s.Prune(ctx, policy, now)
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+
+	var snapshots, blobs, listAges int
+	if err := s.db.QueryRow(`SELECT count(*), count(task_lists_hash) FROM snapshots`).Scan(&snapshots, &blobs); err != nil {
+		t.Fatalf("count snapshots: %v", err)
+	}
+	if err := s.db.QueryRow(`SELECT count(*) FROM list_ages`).Scan(&listAges); err != nil {
+		t.Fatalf("count list ages: %v", err)
+	}
+	if result.SnapshotsDeleted != 320-snapshots {
+		t.Errorf("SnapshotsDeleted = %d, but %d of 320 snapshots are left", result.SnapshotsDeleted, snapshots)
+	}
+	if listAges != snapshots {
+		t.Errorf("%d list ages left for %d snapshots", listAges, snapshots)
+	}
+	// 3 days in full (12 or 13 snapshots), about a month of daily ones and
+	// 6 or 7 weekly ones for the remaining six weeks.
+	if snapshots < 40 || snapshots > 55 {
+		t.Errorf("%d snapshots left, want about 50", snapshots)
+	}
+	if result.BytesFreed <= 0 {
+		t.Errorf("BytesFreed = %d, want some", result.BytesFreed)
+	}
+
+	// Only the last 10 days keep their task lists.
+	history, err := s.GetHistory(ctx, now.AddDate(0, 0, -100), now)
+	if err != nil {
+		t.Fatalf("GetHistory: %v", err)
+	}
+	old := 0
+	for _, snap := range history {
+		recent := !snap.Timestamp.Before(now.AddDate(0, 0, -policy.BlobDays))
+		if !recent {
+			old++
+		}
+		if recent != (snap.TaskLists != nil) {
+			t.Errorf("snapshot of %v has task lists %v", snap.Timestamp, snap.TaskLists != nil)
+		}
+		if snap.GlobalStats.TaskCount != 1 {
+			t.Errorf("snapshot of %v lost its totals", snap.Timestamp)
+		}
+	}
+	if result.BlobsDropped != old || blobs != snapshots-old {
+		t.Errorf("BlobsDropped = %d and %d blobs left, want %d dropped from the older snapshots", result.BlobsDropped, blobs, old)
+	}
+	var payloads int
+	if err := s.db.QueryRow(`SELECT count(*) FROM payloads`).Scan(&payloads); err != nil || payloads != 1 {
+		t.Errorf("%d payloads left (%v), want the one shared by the recent snapshots", payloads, err)
+	}
+
+	// The policy is stable: pruning again removes nothing.
+	again, err := s.Prune(ctx, policy, now)
+	if err != nil {
+		t.Fatalf("second Prune: %v", err)
+	}
+	if again.SnapshotsDeleted != 0 || again.BlobsDropped != 0 {
+		t.Errorf("second Prune = %+v, want nothing removed", again)
+	}
+
+	if _, err := s.Vacuum(ctx); err != nil {
+		t.Fatalf("Vacuum: %v", err)
+	}
+	latest, err := s.GetLatest(ctx)
+	if err != nil || latest == nil || !latest.Timestamp.Equal(now) || latest.TaskLists == nil {
+		t.Errorf("GetLatest after pruning = %+v, %v", latest, err)
+	}
+}
+
+// TestSQLiteStorage_ConcurrentChartsAndRefreshes runs chart queries on a
+// shared handle while refreshes write through it and through a second
+// handle, as the stats command does next to a running bot.
+func TestSQLiteStorage_ConcurrentChartsAndRefreshes(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "stats.db")
+	s := newTestSQLiteStorageAt(t, dbPath)
+	other := newTestSQLiteStorageAt(t, dbPath)
+	ctx := t.Context()
+
+	var mode string
+	if err := s.db.QueryRow(`PRAGMA journal_mode`).Scan(&mode); err != nil || mode != "wal" {
+		t.Fatalf("journal_mode = %q (%v), want wal", mode, err)
+	}
+
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	refresh := func(store *SQLiteStorage, i int) error {
+		at := now.Add(-time.Duration(i) * time.Hour)
+		snap := StatsSnapshot{
+			Timestamp:   at,
+			GlobalStats: GlobalStats{TotalAge: i, TaskCount: 1},
+			ListAges:    todometrics.ListAges{TotalAge: i, Ages: []todometrics.ListAge{{Title: "Work", Age: i, TaskCount: 1}}},
+			TaskLists:   []todo.TaskList{{ID: "work", Name: "Work", Tasks: []todo.Task{{ID: fmt.Sprintf("task-%d", i), Title: "Write report", CreatedDateTime: at}}}},
+		}
+		if err := store.Store(ctx, snap); err != nil {
+			return fmt.Errorf("Store: %w", err)
+		}
+		if err := store.SaveDeltaState(ctx, todoclient.NewDeltaState()); err != nil {
+			return fmt.Errorf("SaveDeltaState: %w", err)
+		}
+		if _, err := store.Prune(ctx, DefaultRetentionPolicy(), now); err != nil {
+			return fmt.Errorf("Prune: %w", err)
+		}
+		return nil
+	}
+
+	const refreshes = 10
+	done := make(chan struct{})
+	var writers, readers sync.WaitGroup
+	for w, store := range []*SQLiteStorage{s, other} {
+		writers.Go(func() {
+			for i := w; i < refreshes; i += 2 {
+				if err := refresh(store, i); err != nil {
+					t.Errorf("refresh %d: %v", i, err)
+					return
+				}
+			}
+		})
+	}
+	for range 2 * sqliteMaxConns {
+		readers.Go(func() {
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if _, err := s.GetHistory(ctx, now.AddDate(0, 0, -90), now.Add(time.Second)); err != nil {
+					t.Errorf("GetHistory: %v", err)
+					return
+				}
+				if _, err := s.GetTimeSeriesData(ctx, now, 90); err != nil {
+					t.Errorf("GetTimeSeriesData: %v", err)
+					return
+				}
+			}
+		})
+	}
+	writers.Wait()
+	close(done)
+	readers.Wait()
+
+	history, err := s.GetHistory(ctx, now.AddDate(0, 0, -90), now.Add(time.Second))
+	if err != nil {
+		t.Fatalf("GetHistory: %v", err)
+	}
+	if len(history) != refreshes {
+		t.Errorf("%d snapshots stored, want %d", len(history), refreshes)
+	}
+}
+
+// newLegacySQLiteStorage opens a database migrated up to version only.
+func newLegacySQLiteStorage(tb testing.TB, dbPath string, version int) *SQLiteStorage {
+	tb.Helper()
+	s, err := OpenSQLiteStorage(dbPath)
+	if err != nil {
+		tb.Fatalf("OpenSQLiteStorage: %v", err)
+	}
+	if _, err := s.appliedMigrations(tb.Context()); err != nil {
+		tb.Fatalf("create schema_migrations: %v", err)
+	}
+	for _, m := range sqliteMigrations[:version] {
+		if _, err := s.apply(tb.Context(), m); err != nil {
+			tb.Fatalf("migration %d: %v", m.version, err)
+		}
+	}
+	return s
+}
+
+// storeLegacySnapshot stores snap the way Store did before task lists were
+// shared: as JSON in the snapshot row, without list ages or task history.
+func storeLegacySnapshot(tb testing.TB, s *SQLiteStorage, snap StatsSnapshot) {
+	tb.Helper()
+	lists, err := json.Marshal(snap.TaskLists)
+	if err != nil {
+		tb.Fatalf("marshal task lists: %v", err)
+	}
+	var missing []byte
+	if len(snap.MissingLists) > 0 {
+		if missing, err = json.Marshal(snap.MissingLists); err != nil {
+			tb.Fatalf("marshal missing lists: %v", err)
+		}
+	}
+	if _, err := s.db.Exec(
+		`INSERT INTO snapshots (timestamp, total_age, task_count, task_lists_json, partial, missing_lists_json) VALUES (?, ?, ?, ?, ?, ?)`,
+		snap.Timestamp.UTC().Format(time.RFC3339), snap.GlobalStats.TotalAge, snap.GlobalStats.TaskCount,
+		string(lists), snap.Partial, missing); err != nil {
+		tb.Fatalf("insert legacy snapshot: %v", err)
+	}
+}
+
+func TestSQLiteStorage_TaskListsSharedBetweenSnapshots(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "stats.db")
+	base := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	lists := []todo.TaskList{{ID: "work", Name: "Work", Tasks: []todo.Task{
+		{ID: "task-1", Title: "Write report", Status: "notStarted", CreatedDateTime: base, LastModifiedDateTime: base},
+	}}}
+
+	// One snapshot from before payloads, then three sharing one payload.
+	s := newLegacySQLiteStorage(t, dbPath, 4)
+	storeLegacySnapshot(t, s, StatsSnapshot{Timestamp: base, TaskLists: lists})
+	s.Close()
+	s, err := NewSQLiteStorage(dbPath)
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage: %v", err)
+	}
+	defer s.Close()
+	ctx := t.Context()
+
+	for i := 1; i <= 3; i++ {
+		snap := StatsSnapshot{Timestamp: base.Add(time.Duration(i) * time.Hour), TaskLists: lists}
+		if err := s.Store(ctx, snap); err != nil {
+			t.Fatalf("Store: %v", err)
+		}
+	}
+
+	var payloads int
+	if err := s.db.QueryRow(`SELECT count(*) FROM payloads`).Scan(&payloads); err != nil || payloads != 1 {
+		t.Errorf("%d payloads (%v), want 1", payloads, err)
+	}
+
+	history, err := s.GetHistory(ctx, base.Add(-time.Hour), base.Add(4*time.Hour))
+	if err != nil {
+		t.Fatalf("GetHistory: %v", err)
+	}
+	if len(history) != 4 {
+		t.Fatalf("got %d snapshots, want 4", len(history))
+	}
+	for _, snap := range history {
+		if len(snap.TaskLists) != 1 || len(snap.TaskLists[0].Tasks) != 1 || snap.TaskLists[0].Tasks[0].Title != "Write report" {
+			t.Errorf("snapshot of %v has task lists %+v", snap.Timestamp, snap.TaskLists)
+		}
+	}
+}
+
+// BenchmarkTaskListStorage stores a simulated 90-day history, a snapshot
+// every 4 hours of 200 tasks with a few changes a day, and reports the size
+// of the database with the task lists as JSON per snapshot and as shared
+// compressed payloads.
+func BenchmarkTaskListStorage(b *testing.B) {
+	history := simulatedHistory(90, 6, 200)
+	for _, format := range []string{"json", "payloads"} {
+		b.Run(format, func(b *testing.B) {
+			var size int64
+			for b.Loop() {
+				dbPath := filepath.Join(b.TempDir(), "stats.db")
+				var s *SQLiteStorage
+				if format == "json" {
+					s = newLegacySQLiteStorage(b, dbPath, len(sqliteMigrations))
+				} else {
+					var err error
+					if s, err = NewSQLiteStorage(dbPath); err != nil {
+						b.Fatalf("NewSQLiteStorage: %v", err)
+					}
+				}
+				for _, snap := range history {
+					if format == "json" {
+						storeLegacySnapshot(b, s, snap)
+					} else if err := s.Store(b.Context(), snap); err != nil {
+						b.Fatalf("Store: %v", err)
+					}
+				}
+				var err error
+				if size, err = s.usedBytes(b.Context()); err != nil {
+					b.Fatalf("usedBytes: %v", err)
+				}
+				s.Close()
+			}
+			b.ReportMetric(float64(size)/(1<<20), "MiB")
+		})
+	}
+}
+
+// simulatedHistory returns perDay snapshots a day for days of tasks spread
+// over 8 lists. Every day one task is added, one completed and one renamed.
+func simulatedHistory(days, perDay, tasks int) []StatsSnapshot {
+	start := time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)
+	open := make([]todo.Task, tasks)
+	next := 0
+	newTask := func(at time.Time) todo.Task {
+		next++
+		return todo.Task{
+			ID:                   fmt.Sprintf("AAMkADg3NzM0NjE4LTUyODItNDkzNi1iMGIyLTVkNzg5ZGE0NjFlNQBGAAAAAAC%06d", next),
+			Title:                fmt.Sprintf("Task number %d with a reasonably descriptive title", next),
+			Status:               "notStarted",
+			Importance:           "normal",
+			CreatedDateTime:      at,
+			LastModifiedDateTime: at,
+			Body:                 todo.TaskBody{Content: "Some notes about what needs to be done and why.", ContentType: "text"},
+		}
+	}
+	for i := range open {
+		open[i] = newTask(start.AddDate(0, 0, -i))
+	}
+
+	var history []StatsSnapshot
+	for day := range days {
+		at := start.AddDate(0, 0, day)
+		open = append(open[1:], newTask(at))
+		renamed := &open[(day*7)%len(open)]
+		renamed.Title += " (edited)"
+		renamed.LastModifiedDateTime = at
+
+		lists := make([]todo.TaskList, 8)
+		for i := range lists {
+			lists[i] = todo.TaskList{ID: fmt.Sprintf("list-%d", i), Name: fmt.Sprintf("List %d", i)}
+		}
+		for i, task := range open {
+			lists[i%8].Tasks = append(lists[i%8].Tasks, task)
+		}
+		for n := range perDay {
+			history = append(history, StatsSnapshot{
+				Timestamp:   at.Add(time.Duration(n*24/perDay) * time.Hour),
+				GlobalStats: GlobalStats{TotalAge: day, TaskCount: len(open)},
+				TaskLists:   lists,
+			})
+		}
+	}
+	return history
+}
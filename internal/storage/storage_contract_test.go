@@ -0,0 +1,410 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/uchr/ToDoInfo/internal/todo"
+	"github.com/uchr/ToDoInfo/internal/todoclient"
+	"github.com/uchr/ToDoInfo/internal/todometrics"
+)
+
+// contractBackends open an empty, migrated database of each backend. The
+// PostgreSQL one needs TODOINFO_TEST_POSTGRES_DSN, a postgres:// URL of a
+// database the tests may create schemas in.
+var contractBackends = []struct {
+	name string
+	open func(t *testing.T) Backend
+}{
+	{"sqlite", func(t *testing.T) Backend { return newTestSQLiteStorage(t) }},
+	{"postgres", newTestPostgresStorage},
+}
+
+// newTestPostgresStorage migrates a schema of its own, dropped after the test.
+func newTestPostgresStorage(t *testing.T) Backend {
+	t.Helper()
+	dsn := os.Getenv("TODOINFO_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TODOINFO_TEST_POSTGRES_DSN is not set")
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatalf("parse TODOINFO_TEST_POSTGRES_DSN: %v", err)
+	}
+
+	admin, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatalf("open postgres: %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+	schema := fmt.Sprintf("todoinfo_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`); err != nil {
+			t.Errorf("drop schema: %v", err)
+		}
+	})
+
+	query := u.Query()
+	query.Set("search_path", schema)
+	u.RawQuery = query.Encode()
+	s, err := NewPostgresStorage(u.String())
+	if err != nil {
+		t.Fatalf("NewPostgresStorage: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// sqlOf exposes the tables behind a backend.
+func sqlOf(b Backend) *sqlStorage {
+	switch s := b.(type) {
+	case *SQLiteStorage:
+		return &s.sqlStorage
+	case *PostgresStorage:
+		return &s.sqlStorage
+	}
+	panic(fmt.Sprintf("unknown backend %T", b))
+}
+
+// TestBackendContract runs the same checks against every backend, so that
+// the bot behaves alike whichever database it shares.
+func TestBackendContract(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, s Backend)
+	}{
+		{"StoreAndGet", testContractStoreAndGet},
+		{"TimeSeries", testContractTimeSeries},
+		{"TaskTimeline", testContractTaskTimeline},
+		{"DeltaState", testContractDeltaState},
+		{"Prune", testContractPrune},
+		{"SharedPayloads", testContractSharedPayloads},
+		{"Migrate", testContractMigrate},
+	}
+	for _, backend := range contractBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					tt.run(t, backend.open(t))
+				})
+			}
+		})
+	}
+}
+
+func testContractStoreAndGet(t *testing.T, s Backend) {
+	ctx := t.Context()
+
+	if latest, err := s.GetLatest(ctx); err != nil || latest != nil {
+		t.Fatalf("GetLatest on an empty database = %+v, %v, want nil", latest, err)
+	}
+
+	monday := time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)
+	lists := []todo.TaskList{{ID: "work", Name: "Work", Tasks: []todo.Task{{ID: "task-1", Title: "Write report", CreatedDateTime: monday}}}}
+	for i, age := range []int{10, 20, 30} {
+		snap := StatsSnapshot{
+			Timestamp:   monday.AddDate(0, 0, i),
+			GlobalStats: GlobalStats{TotalAge: age, TaskCount: 1},
+			ListAges:    todometrics.ListAges{TotalAge: age, Ages: []todometrics.ListAge{{Title: "Work", Age: age, TaskCount: 1}}},
+			TaskLists:   lists,
+		}
+		if i == 2 {
+			snap.Partial = true
+			snap.MissingLists = []MissingList{{ID: "home", Name: "Home", Error: "timeout"}}
+			snap.ListAges.Ages = append(snap.ListAges.Ages, todometrics.ListAge{Title: "Someday", Excluded: true})
+		}
+		if err := s.Store(ctx, snap); err != nil {
+			t.Fatalf("Store: %v", err)
+		}
+	}
+
+	latest, err := s.GetLatest(ctx)
+	if err != nil || latest == nil {
+		t.Fatalf("GetLatest = %+v, %v", latest, err)
+	}
+	if !latest.Timestamp.Equal(monday.AddDate(0, 0, 2)) || latest.GlobalStats.TotalAge != 30 {
+		t.Errorf("GetLatest = %+v, want Wednesday's", latest)
+	}
+	if !latest.Partial || len(latest.MissingLists) != 1 || latest.MissingLists[0].Name != "Home" {
+		t.Errorf("GetLatest partial = %v, missing %+v, want Home missing", latest.Partial, latest.MissingLists)
+	}
+	if len(latest.ListAges.Ages) != 2 || latest.ListAges.Ages[0].Age != 30 || latest.ListAges.Ages[0].Excluded || !latest.ListAges.Ages[1].Excluded {
+		t.Errorf("list ages = %+v, want Work counted and Someday excluded", latest.ListAges)
+	}
+	if len(latest.TaskLists) != 1 || len(latest.TaskLists[0].Tasks) != 1 || latest.TaskLists[0].Tasks[0].Title != "Write report" {
+		t.Errorf("task lists = %+v", latest.TaskLists)
+	}
+
+	at, err := s.GetAt(ctx, monday.AddDate(0, 0, 1).Add(time.Hour))
+	if err != nil || at == nil || at.GlobalStats.TotalAge != 20 || at.Partial {
+		t.Errorf("GetAt Tuesday = %+v, %v, want TotalAge 20", at, err)
+	}
+	if at, err := s.GetAt(ctx, monday.Add(-time.Hour)); err != nil || at != nil {
+		t.Errorf("GetAt before the first snapshot = %+v, %v, want nil", at, err)
+	}
+
+	// Both bounds are exclusive.
+	history, err := s.GetHistory(ctx, monday.Add(-time.Hour), monday.AddDate(0, 0, 2))
+	if err != nil {
+		t.Fatalf("GetHistory: %v", err)
+	}
+	if len(history) != 2 || history[0].GlobalStats.TotalAge != 10 || history[1].GlobalStats.TotalAge != 20 {
+		t.Errorf("GetHistory = %+v, want Monday and Tuesday", history)
+	}
+}
+
+func testContractTimeSeries(t *testing.T, s Backend) {
+	ctx := t.Context()
+
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	for _, snap := range []StatsSnapshot{
+		{Timestamp: now.Add(-24 * time.Hour), GlobalStats: GlobalStats{TotalAge: 30, TaskCount: 2}},
+		{Timestamp: now.Add(-time.Hour), GlobalStats: GlobalStats{TotalAge: 50, TaskCount: 3}},
+		{Timestamp: now, GlobalStats: GlobalStats{TotalAge: 100, TaskCount: 5}},
+	} {
+		if err := s.Store(ctx, snap); err != nil {
+			t.Fatalf("Store: %v", err)
+		}
+	}
+
+	points, err := s.GetTimeSeriesData(ctx, now, 7)
+	if err != nil {
+		t.Fatalf("GetTimeSeriesData: %v", err)
+	}
+	want := []TimeSeriesPoint{
+		{Date: time.Date(2026, 10, 13, 0, 0, 0, 0, time.UTC), MaxAge: 30, TaskCount: 2},
+		{Date: time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC), MaxAge: 100, TaskCount: 5},
+	}
+	if len(points) != len(want) {
+		t.Fatalf("points = %+v, want %+v", points, want)
+	}
+	for i := range want {
+		if !points[i].Date.Equal(want[i].Date) || points[i].MaxAge != want[i].MaxAge || points[i].TaskCount != want[i].TaskCount {
+			t.Errorf("point %d = %+v, want %+v", i, points[i], want[i])
+		}
+	}
+}
+
+func testContractTaskTimeline(t *testing.T, s Backend) {
+	ctx := t.Context()
+
+	snapshots := historySnapshots()
+	for _, snap := range snapshots {
+		if err := s.Store(ctx, snap); err != nil {
+			t.Fatalf("Store: %v", err)
+		}
+	}
+
+	timeline, err := s.GetTaskTimeline(ctx, "task-1")
+	if err != nil || timeline == nil {
+		t.Fatalf("GetTaskTimeline = %+v, %v", timeline, err)
+	}
+	checkTaskTimeline(t, timeline, snapshots)
+
+	records, err := s.FindTaskRecords(ctx, "REPORT")
+	if err != nil {
+		t.Fatalf("FindTaskRecords: %v", err)
+	}
+	if len(records) != 1 || records[0].TaskID != "task-1" {
+		t.Errorf("FindTaskRecords = %+v, want task-1", records)
+	}
+	// Wildcards in the query match literally.
+	for _, query := range []string{"%", "_rite"} {
+		if records, err := s.FindTaskRecords(ctx, query); err != nil || len(records) != 0 {
+			t.Errorf("FindTaskRecords(%q) = %+v, %v, want none", query, records, err)
+		}
+	}
+}
+
+func testContractDeltaState(t *testing.T, s Backend) {
+	ctx := t.Context()
+
+	created := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	state := todoclient.NewDeltaState()
+	state.ListsDeltaLink = "https://graph.example/lists/delta?token=1"
+	state.Lists["list-1"] = &todoclient.ListDeltaState{
+		ID:        "list-1",
+		Name:      "Work",
+		DeltaLink: "https://graph.example/list-1/delta?token=2",
+		IsShared:  true,
+		Tasks: map[string]todo.Task{
+			"task-1": {ID: "task-1", Title: "Fix bug", CreatedDateTime: created, LastModifiedDateTime: created},
+		},
+	}
+	for range 2 {
+		if err := s.SaveDeltaState(ctx, state); err != nil {
+			t.Fatalf("SaveDeltaState: %v", err)
+		}
+	}
+
+	loaded, err := s.LoadDeltaState(ctx)
+	if err != nil {
+		t.Fatalf("LoadDeltaState: %v", err)
+	}
+	if loaded.ListsDeltaLink != state.ListsDeltaLink || len(loaded.Lists) != 1 {
+		t.Fatalf("LoadDeltaState = %+v", loaded)
+	}
+	list := loaded.Lists["list-1"]
+	if list.DeltaLink != state.Lists["list-1"].DeltaLink || !list.IsShared {
+		t.Errorf("list = %+v", list)
+	}
+	if task := list.Tasks["task-1"]; task.Title != "Fix bug" || !task.CreatedDateTime.Equal(created) {
+		t.Errorf("task = %+v", task)
+	}
+}
+
+func testContractPrune(t *testing.T, s Backend) {
+	ctx := t.Context()
+
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	lists := []todo.TaskList{{ID: "work", Name: "Work", Tasks: []todo.Task{{ID: "task-1", Title: "Write report", CreatedDateTime: now.AddDate(-1, 0, 0)}}}}
+	// Two snapshots a day for 30 days.
+	for day := range 30 {
+		for _, hour := range []int{0, 12} {
+			snap := StatsSnapshot{
+				Timestamp:   now.AddDate(0, 0, -day).Add(-time.Duration(hour) * time.Hour),
+				GlobalStats: GlobalStats{TotalAge: day, TaskCount: 1},
+				ListAges:    todometrics.ListAges{TotalAge: day, Ages: []todometrics.ListAge{{Title: "Work", Age: day, TaskCount: 1}}},
+				TaskLists:   lists,
+			}
+			if err := s.Store(ctx, snap); err != nil {
+				t.Fatalf("Store: %v", err)
+			}
+		}
+	}
+
+	policy := RetentionPolicy{KeepAllDays: 3, BlobDays: 7}
+	result, err := s.Prune(ctx, policy, now)
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	history, err := s.GetHistory(ctx, now.AddDate(0, 0, -60), now.Add(time.Second))
+	if err != nil {
+		t.Fatalf("GetHistory: %v", err)
+	}
+	if result.SnapshotsDeleted == 0 || len(history) != 60-result.SnapshotsDeleted {
+		t.Errorf("SnapshotsDeleted = %d with %d of 60 snapshots left", result.SnapshotsDeleted, len(history))
+	}
+	dropped := 0
+	for _, snap := range history {
+		recent := !snap.Timestamp.Before(now.AddDate(0, 0, -policy.BlobDays))
+		if !recent {
+			dropped++
+		}
+		if recent != (snap.TaskLists != nil) {
+			t.Errorf("snapshot of %v has task lists %v", snap.Timestamp, snap.TaskLists != nil)
+		}
+	}
+	if result.BlobsDropped != dropped {
+		t.Errorf("BlobsDropped = %d, want %d", result.BlobsDropped, dropped)
+	}
+
+	again, err := s.Prune(ctx, policy, now)
+	if err != nil || again.SnapshotsDeleted != 0 || again.BlobsDropped != 0 {
+		t.Errorf("second Prune = %+v, %v, want nothing removed", again, err)
+	}
+	if _, err := s.Vacuum(ctx); err != nil {
+		t.Fatalf("Vacuum: %v", err)
+	}
+}
+
+func testContractSharedPayloads(t *testing.T, s Backend) {
+	ctx := t.Context()
+
+	base := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	lists := []todo.TaskList{{ID: "work", Name: "Work", Tasks: []todo.Task{{ID: "task-1", Title: "Write report", CreatedDateTime: base}}}}
+	for i := range 3 {
+		if err := s.Store(ctx, StatsSnapshot{Timestamp: base.Add(time.Duration(i) * time.Hour), TaskLists: lists}); err != nil {
+			t.Fatalf("Store: %v", err)
+		}
+	}
+
+	var payloads int
+	if err := sqlOf(s).db.QueryRow(`SELECT count(*) FROM payloads`).Scan(&payloads); err != nil || payloads != 1 {
+		t.Errorf("%d payloads (%v), want 1", payloads, err)
+	}
+}
+
+func testContractMigrate(t *testing.T, s Backend) {
+	ctx := t.Context()
+
+	if applied, err := s.Migrate(ctx); err != nil || len(applied) != 0 {
+		t.Errorf("Migrate on a migrated database = %+v, %v, want nothing", applied, err)
+	}
+	statuses, err := s.MigrationStatus(ctx)
+	if err != nil {
+		t.Fatalf("MigrationStatus: %v", err)
+	}
+	if len(statuses) != sqlOf(s).dialect.latestVersion() {
+		t.Errorf("statuses = %+v, want %d", statuses, sqlOf(s).dialect.latestVersion())
+	}
+	for _, m := range statuses {
+		if m.AppliedAt == nil {
+			t.Errorf("migration %d (%s) is pending", m.Version, m.Name)
+		}
+	}
+}
+
+func TestPostgresMigrationsMirrorSQLite(t *testing.T) {
+	if len(postgresMigrations) != len(sqliteMigrations) {
+		t.Fatalf("%d postgres migrations, %d sqlite ones", len(postgresMigrations), len(sqliteMigrations))
+	}
+	for i, m := range postgresMigrations {
+		if m.version != sqliteMigrations[i].version || m.name != sqliteMigrations[i].name {
+			t.Errorf("postgres migration %d (%s), sqlite %d (%s)", m.version, m.name, sqliteMigrations[i].version, sqliteMigrations[i].name)
+		}
+	}
+}
+
+func TestPostgresRebind(t *testing.T) {
+	got := postgresRebind(`SELECT a FROM t WHERE b = ? AND c LIKE ? ESCAPE '\'`)
+	if want := `SELECT a FROM t WHERE b = $1 AND c LIKE $2 ESCAPE '\'`; got != want {
+		t.Errorf("postgresRebind = %q, want %q", got, want)
+	}
+}
+
+func TestOpenSelectsBackend(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "stats.db")
+	s, err := New(dbPath)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer s.Close()
+	if _, ok := s.(*SQLiteStorage); !ok {
+		t.Errorf("New(%q) = %T, want *SQLiteStorage", dbPath, s)
+	}
+	if !isPostgresDSN("postgres://todoinfo@db/todoinfo") || !isPostgresDSN("postgresql://db/todoinfo") || isPostgresDSN(dbPath) {
+		t.Error("isPostgresDSN misclassifies a DSN")
+	}
+}
+
+// The tables are listed by hand for VACUUM FULL; a migrated SQLite database
+// has the same ones.
+func TestPostgresTablesMatchMigrations(t *testing.T) {
+	s := newTestSQLiteStorage(t)
+	rows, err := s.db.Query(`SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name`)
+	if err != nil {
+		t.Fatalf("list tables: %v", err)
+	}
+	defer rows.Close()
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		tables = append(tables, name)
+	}
+	if want := slices.Sorted(slices.Values(postgresTables)); !slices.Equal(tables, want) {
+		t.Errorf("migrated tables %v, postgresTables %v", tables, want)
+	}
+}
@@ -0,0 +1,80 @@
+-- A stats.db written before schema versioning: the tables created by the
+-- single CREATE TABLE IF NOT EXISTS block, with the partial columns added
+-- by ALTER TABLE.
+CREATE TABLE snapshots (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    timestamp       DATETIME NOT NULL,
+    total_age       INTEGER NOT NULL,
+    task_count      INTEGER NOT NULL,
+    task_lists_json TEXT
+);
+
+CREATE TABLE list_ages (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    snapshot_id INTEGER NOT NULL REFERENCES snapshots(id),
+    title       TEXT NOT NULL,
+    age         INTEGER NOT NULL,
+    task_count  INTEGER NOT NULL
+);
+
+CREATE TABLE delta_links (
+    scope TEXT PRIMARY KEY,
+    link  TEXT NOT NULL
+);
+
+CREATE TABLE delta_lists (
+    list_id             TEXT PRIMARY KEY,
+    name                TEXT NOT NULL,
+    wellknown_list_name TEXT NOT NULL,
+    is_shared           INTEGER NOT NULL
+);
+
+CREATE TABLE delta_tasks (
+    list_id   TEXT NOT NULL REFERENCES delta_lists(list_id),
+    task_id   TEXT NOT NULL,
+    task_json TEXT NOT NULL,
+    PRIMARY KEY (list_id, task_id)
+);
+
+CREATE TABLE tasks (
+    task_id     TEXT PRIMARY KEY,
+    list_id     TEXT NOT NULL,
+    list_name   TEXT NOT NULL,
+    title       TEXT NOT NULL,
+    due         DATETIME,
+    created_at  DATETIME NOT NULL,
+    first_seen  DATETIME NOT NULL,
+    last_seen   DATETIME NOT NULL,
+    gone_at     DATETIME,
+    gone_reason TEXT
+);
+
+CREATE TABLE task_events (
+    id        INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id   TEXT NOT NULL REFERENCES tasks(task_id),
+    timestamp DATETIME NOT NULL,
+    kind      TEXT NOT NULL,
+    old_value TEXT NOT NULL,
+    new_value TEXT NOT NULL
+);
+
+CREATE INDEX idx_snapshots_timestamp ON snapshots(timestamp);
+CREATE INDEX idx_list_ages_snapshot   ON list_ages(snapshot_id);
+CREATE INDEX idx_tasks_open           ON tasks(gone_at);
+CREATE INDEX idx_task_events_task     ON task_events(task_id, timestamp);
+
+ALTER TABLE snapshots ADD COLUMN partial INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE snapshots ADD COLUMN missing_lists_json TEXT;
+
+INSERT INTO snapshots (timestamp, total_age, task_count, task_lists_json, partial, missing_lists_json) VALUES
+    ('2026-10-01T08:00:00Z', 12, 1,
+     '[{"ID":"work","Name":"Work","Tasks":[{"id":"task-1","title":"Write report","status":"notStarted","createdDateTime":"2026-09-19T08:00:00Z","lastModifiedDateTime":"2026-09-19T08:00:00Z"}]}]',
+     1, '[{"id":"home","name":"Home","error":"timeout"}]');
+INSERT INTO list_ages (snapshot_id, title, age, task_count) VALUES (1, 'Work', 12, 1);
+
+INSERT INTO delta_links (scope, link) VALUES ('lists', 'https://graph.example/lists/delta?token=1');
+
+INSERT INTO tasks (task_id, list_id, list_name, title, created_at, first_seen, last_seen) VALUES
+    ('task-1', 'work', 'Work', 'Write report', '2026-09-19T08:00:00Z', '2026-10-01T08:00:00Z', '2026-10-01T08:00:00Z');
+INSERT INTO task_events (task_id, timestamp, kind, old_value, new_value) VALUES
+    ('task-1', '2026-10-01T08:00:00Z', 'appeared', '', 'Work');
@@ -0,0 +1,16 @@
+package todo
+
+import (
+	"regexp"
+	"strings"
+)
+
+// MatchName reports whether name matches glob, ignoring case. "*" matches any
+// run of characters, including "/", and "?" matches exactly one, so globs
+// work on list names such as "Someday/Maybe".
+func MatchName(glob, name string) bool {
+	pattern := regexp.QuoteMeta(glob)
+	pattern = strings.ReplaceAll(pattern, `\*`, ".*")
+	pattern = strings.ReplaceAll(pattern, `\?`, ".")
+	return regexp.MustCompile("(?i)^" + pattern + "$").MatchString(name)
+}
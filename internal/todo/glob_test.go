@@ -0,0 +1,28 @@
+package todo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchName(t *testing.T) {
+	tests := []struct {
+		glob, name string
+		expected   bool
+	}{
+		{"Work", "work", true},
+		{"Work", "Workshop", false},
+		{"Some*", "Someday/Maybe", true},
+		{"*shop*", "Workshop ideas", true},
+		{"Q? goals", "Q3 goals", true},
+		{"Q? goals", "Q10 goals", false},
+		{"a.b", "axb", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.glob+"/"+tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, MatchName(tt.glob, tt.name))
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package todo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FuzzyScore rates how well text matches query, ignoring case and
+// surrounding space: 4 for equal, 3 for a prefix, 2 for a substring and 1
+// when every word of query appears in text. 0 means no match.
+func FuzzyScore(query, text string) int {
+	query = strings.ToLower(strings.TrimSpace(query))
+	text = strings.ToLower(strings.TrimSpace(text))
+	if query == "" {
+		return 0
+	}
+
+	switch {
+	case text == query:
+		return 4
+	case strings.HasPrefix(text, query):
+		return 3
+	case strings.Contains(text, query):
+		return 2
+	}
+	for _, word := range strings.Fields(query) {
+		if !strings.Contains(text, word) {
+			return 0
+		}
+	}
+	return 1
+}
+
+// TaskRef is a task together with the list it belongs to.
+type TaskRef struct {
+	List TaskList
+	Task Task
+}
+
+// FindTasks returns the open tasks selected by query: the task whose ID is
+// query, or else the tasks whose titles match it best. More than one result
+// means the query is ambiguous.
+func FindTasks(lists []TaskList, query string) []TaskRef {
+	var best []TaskRef
+	bestScore := 0
+	for _, list := range lists {
+		for _, task := range list.Tasks {
+			if task.ID == query {
+				return []TaskRef{{List: list, Task: task}}
+			}
+
+			score := FuzzyScore(query, task.Title)
+			switch {
+			case score == 0 || score < bestScore:
+				continue
+			case score > bestScore:
+				best = nil
+				bestScore = score
+			}
+			best = append(best, TaskRef{List: list, Task: task})
+		}
+	}
+	return best
+}
+
+// FindLists returns the list whose ID is query, or else the lists whose
+// names match it best. More than one result means the query is ambiguous.
+func FindLists(lists []TaskList, query string) []TaskList {
+	var best []TaskList
+	bestScore := 0
+	for _, list := range lists {
+		if list.ID == query {
+			return []TaskList{list}
+		}
+
+		score := FuzzyScore(query, list.Name)
+		switch {
+		case score == 0 || score < bestScore:
+			continue
+		case score > bestScore:
+			best = nil
+			bestScore = score
+		}
+		best = append(best, list)
+	}
+	return best
+}
+
+// FindList resolves query to a single list with FindLists. An ambiguous
+// query fails with the names of the lists it matches.
+func FindList(lists []TaskList, query string) (TaskList, error) {
+	matches := FindLists(lists, query)
+	switch len(matches) {
+	case 0:
+		return TaskList{}, fmt.Errorf("no list matches %q", query)
+	case 1:
+		return matches[0], nil
+	}
+	names := make([]string, 0, len(matches))
+	for _, list := range matches {
+		names = append(names, list.Name)
+	}
+	return TaskList{}, fmt.Errorf("%d lists match %q: %s", len(matches), query, strings.Join(names, ", "))
+}
+
+// FindTask returns the task with taskID in the list with listID.
+func FindTask(lists []TaskList, listID, taskID string) (TaskRef, bool) {
+	for _, list := range lists {
+		if list.ID != listID {
+			continue
+		}
+		for _, task := range list.Tasks {
+			if task.ID == taskID {
+				return TaskRef{List: list, Task: task}, true
+			}
+		}
+	}
+	return TaskRef{}, false
+}
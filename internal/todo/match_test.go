@@ -0,0 +1,93 @@
+package todo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	tests := []struct {
+		query, text string
+		expected    int
+	}{
+		{"buy milk", "Buy milk", 4},
+		{"buy", "Buy milk", 3},
+		{"milk", "Buy milk", 2},
+		{"milk buy", "Buy milk", 1},
+		{"bread", "Buy milk", 0},
+		{"  ", "Buy milk", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			assert.Equal(t, tt.expected, FuzzyScore(tt.query, tt.text))
+		})
+	}
+}
+
+func TestFindTasks(t *testing.T) {
+	lists := []TaskList{
+		{ID: "home", Name: "Home", Tasks: []Task{
+			{ID: "h-1", Title: "Fix the fence"},
+			{ID: "h-2", Title: "Buy milk"},
+		}},
+		{ID: "work", Name: "Work", Tasks: []Task{
+			{ID: "w-1", Title: "Fix the build"},
+			{ID: "w-2", Title: "Buy milk for the office"},
+		}},
+	}
+
+	titles := func(refs []TaskRef) []string {
+		var result []string
+		for _, ref := range refs {
+			result = append(result, ref.List.Name+"/"+ref.Task.Title)
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{"Work/Fix the build"}, titles(FindTasks(lists, "w-1")))
+	// An exact title beats a longer title starting the same way.
+	assert.Equal(t, []string{"Home/Buy milk"}, titles(FindTasks(lists, "buy milk")))
+	assert.Equal(t, []string{"Home/Fix the fence", "Work/Fix the build"}, titles(FindTasks(lists, "fix")))
+	assert.Equal(t, []string{"Home/Fix the fence"}, titles(FindTasks(lists, "fence fix")))
+	assert.Empty(t, FindTasks(lists, "paint"))
+}
+
+func TestFindLists(t *testing.T) {
+	lists := []TaskList{{ID: "1", Name: "Work"}, {ID: "2", Name: "Workshop"}, {ID: "3", Name: "Home"}}
+
+	assert.Len(t, FindLists(lists, "work"), 1)
+	assert.Len(t, FindLists(lists, "wor"), 2)
+	assert.Equal(t, "Home", FindLists(lists, "3")[0].Name)
+	assert.Empty(t, FindLists(lists, "garden"))
+}
+
+func TestFindList(t *testing.T) {
+	lists := []TaskList{{ID: "1", Name: "Work"}, {ID: "2", Name: "Workshop"}, {ID: "3", Name: "Home"}}
+
+	list, err := FindList(lists, "home")
+	require.NoError(t, err)
+	assert.Equal(t, "3", list.ID)
+
+	_, err = FindList(lists, "wor")
+	assert.EqualError(t, err, `2 lists match "wor": Work, Workshop`)
+	_, err = FindList(lists, "garden")
+	assert.EqualError(t, err, `no list matches "garden"`)
+}
+
+func TestFindTask(t *testing.T) {
+	lists := []TaskList{
+		{ID: "1", Name: "Work", Tasks: []Task{{ID: "a", Title: "Report"}}},
+		{ID: "2", Name: "Home", Tasks: []Task{{ID: "b", Title: "Fence"}}},
+	}
+
+	ref, ok := FindTask(lists, "2", "b")
+	require.True(t, ok)
+	assert.Equal(t, "Home", ref.List.Name)
+	assert.Equal(t, "Fence", ref.Task.Title)
+
+	_, ok = FindTask(lists, "1", "b")
+	assert.False(t, ok, "the task must be in the given list")
+}
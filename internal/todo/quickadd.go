@@ -0,0 +1,108 @@
+package todo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QuickAdd is a task typed in one line, e.g.
+//
+//	Call the landlord #home due:friday !high
+//
+// "#name" picks the list, with "_" standing for a space, when it matches one
+// of the lists; "due:" takes today, tomorrow, a weekday, YYYY-MM-DD, +N or
+// Nd; "!high", "!normal" and "!low" set the importance. The remaining words,
+// including "#" and "!" words that are neither, are the title.
+type QuickAdd struct {
+	Title string
+	// List is the list query, empty for the default list.
+	List string
+	// Due is midnight UTC of the due day, as Graph stores all-day due dates.
+	Due        *DateTimeTimeZone
+	Importance string
+}
+
+// ParseQuickAdd parses a quick-add line; "#name" words are looked up in
+// lists with FindLists. Relative due dates count from the calendar day of
+// now; a weekday means its next occurrence after today.
+func ParseQuickAdd(text string, now time.Time, lists []TaskList) (QuickAdd, error) {
+	var (
+		add   QuickAdd
+		title []string
+	)
+	for _, word := range strings.Fields(text) {
+		lower := strings.ToLower(word)
+		list, isList := listQuery(word, lists)
+		importance, isImportance := quickAddImportance[lower]
+		switch {
+		case isList:
+			if add.List != "" {
+				return QuickAdd{}, fmt.Errorf("more than one list: #%s and %s", add.List, word)
+			}
+			add.List = list
+		case strings.HasPrefix(lower, "due:"):
+			due, err := parseDueDay(lower[len("due:"):], now)
+			if err != nil {
+				return QuickAdd{}, err
+			}
+			add.Due = &DateTimeTimeZone{Time: due, TimeZone: "UTC"}
+		case isImportance:
+			add.Importance = importance
+		default:
+			title = append(title, word)
+		}
+	}
+
+	add.Title = strings.Join(title, " ")
+	if add.Title == "" {
+		return QuickAdd{}, fmt.Errorf("the task needs a title")
+	}
+	return add, nil
+}
+
+var quickAddImportance = map[string]string{
+	"!high":   ImportanceHigh,
+	"!normal": ImportanceNormal,
+	"!low":    ImportanceLow,
+}
+
+// listQuery returns the list query of a "#name" word that matches a list.
+func listQuery(word string, lists []TaskList) (string, bool) {
+	name, ok := strings.CutPrefix(word, "#")
+	if !ok || name == "" {
+		return "", false
+	}
+	query := strings.ReplaceAll(name, "_", " ")
+	return query, len(FindLists(lists, query)) > 0
+}
+
+func parseDueDay(value string, now time.Time) (time.Time, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	switch value {
+	case "today":
+		return today, nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), nil
+	}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+		if value == name || value == name[:3] {
+			ahead := (int(day)-int(today.Weekday())+6)%7 + 1
+			return today.AddDate(0, 0, ahead), nil
+		}
+	}
+	if date, err := time.Parse(time.DateOnly, value); err == nil {
+		return date, nil
+	}
+	number, ok := strings.CutPrefix(value, "+")
+	if !ok {
+		number, _ = strings.CutSuffix(value, "d")
+	}
+	if days, err := strconv.Atoi(number); err == nil && days >= 0 && number != value {
+		return today.AddDate(0, 0, days), nil
+	}
+	return time.Time{}, fmt.Errorf("unknown due date %q, use today, tomorrow, a weekday, YYYY-MM-DD or +N", value)
+}
@@ -0,0 +1,67 @@
+package todo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var quickAddLists = []TaskList{
+	{ID: "home", Name: "Home"},
+	{ID: "work", Name: "Work"},
+	{ID: "someday", Name: "Someday/Maybe"},
+}
+
+func TestParseQuickAdd(t *testing.T) {
+	// A Wednesday evening.
+	now := time.Date(2026, 10, 14, 21, 30, 0, 0, time.UTC)
+	day := func(d int) *DateTimeTimeZone {
+		return &DateTimeTimeZone{Time: time.Date(2026, 10, d, 0, 0, 0, 0, time.UTC), TimeZone: "UTC"}
+	}
+
+	tests := []struct {
+		text     string
+		expected QuickAdd
+	}{
+		{"Buy milk", QuickAdd{Title: "Buy milk"}},
+		{"Call the landlord #home due:friday !high", QuickAdd{Title: "Call the landlord", List: "home", Due: day(16), Importance: ImportanceHigh}},
+		{"#Someday_Maybe learn the cello", QuickAdd{Title: "learn the cello", List: "Someday Maybe"}},
+		{"Renew passport due:Wed !HIGH", QuickAdd{Title: "Renew passport", Due: day(21), Importance: ImportanceHigh}},
+		{"Water plants due:tomorrow !low", QuickAdd{Title: "Water plants", Due: day(15), Importance: ImportanceLow}},
+		{"Pay rent due:2026-10-31", QuickAdd{Title: "Pay rent", Due: day(31)}},
+		{"Reply to Ann due:+3", QuickAdd{Title: "Reply to Ann", Due: day(17)}},
+		{"Reply to Bob due:2d", QuickAdd{Title: "Reply to Bob", Due: day(16)}},
+		{"Fix bug # 12 due:today", QuickAdd{Title: "Fix bug # 12", Due: day(14)}},
+		{"Fix issue #12", QuickAdd{Title: "Fix issue #12"}},
+		{"Fix issue #12 #work", QuickAdd{Title: "Fix issue #12", List: "work"}},
+		{"Call mum!!", QuickAdd{Title: "Call mum!!"}},
+		{"!important thing", QuickAdd{Title: "!important thing"}},
+		{"Buy milk !urgent !normal", QuickAdd{Title: "Buy milk !urgent", Importance: ImportanceNormal}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			add, err := ParseQuickAdd(tt.text, now, quickAddLists)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, add)
+		})
+	}
+}
+
+func TestParseQuickAddErrors(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+
+	for _, text := range []string{
+		"#home due:friday",
+		"Buy milk #home #work",
+		"Buy milk due:someday",
+		"Buy milk due:d",
+	} {
+		t.Run(text, func(t *testing.T) {
+			_, err := ParseQuickAdd(text, now, quickAddLists)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -4,39 +4,96 @@ import (
 	"time"
 )
 
+// Task status values used by Microsoft To Do.
+const (
+	StatusNotStarted      = "notStarted"
+	StatusInProgress      = "inProgress"
+	StatusCompleted       = "completed"
+	StatusWaitingOnOthers = "waitingOnOthers"
+	StatusDeferred        = "deferred"
+)
+
+// Task importance values used by Microsoft To Do.
+const (
+	ImportanceLow    = "low"
+	ImportanceNormal = "normal"
+	ImportanceHigh   = "high"
+)
+
 type TaskBody struct {
 	Content     string `json:"content"`
 	ContentType string `json:"contentType"`
 }
 
+// DateTimeTimeZone mirrors the Graph dateTimeTimeZone resource: a wall-clock
+// time together with the name of the zone it is expressed in.
+type DateTimeTimeZone struct {
+	Time     time.Time
+	TimeZone string
+}
+
 type RecurrenceTask struct {
 	Pattern struct {
-		Type           string `json:"type"`
-		Interval       int    `json:"interval"`
-		Month          int    `json:"month"`
-		DayOfMonth     int    `json:"dayOfMonth"`
-		FirstDayOfWeek string `json:"firstDayOfWeek"`
-		Index          string `json:"index"`
+		Type           string   `json:"type"`
+		Interval       int      `json:"interval"`
+		Month          int      `json:"month"`
+		DayOfMonth     int      `json:"dayOfMonth"`
+		DaysOfWeek     []string `json:"daysOfWeek,omitempty"`
+		FirstDayOfWeek string   `json:"firstDayOfWeek"`
+		Index          string   `json:"index"`
 	} `json:"pattern"`
 	Range struct {
-		Type string `json:"type"`
+		Type                string `json:"type"`
+		StartDate           string `json:"startDate,omitempty"`
+		EndDate             string `json:"endDate,omitempty"`
+		NumberOfOccurrences int    `json:"numberOfOccurrences,omitempty"`
+		RecurrenceTimeZone  string `json:"recurrenceTimeZone,omitempty"`
 	} `json:"range"`
 }
 
+type ChecklistItem struct {
+	ID              string     `json:"id"`
+	DisplayName     string     `json:"displayName"`
+	IsChecked       bool       `json:"isChecked"`
+	CreatedDateTime time.Time  `json:"createdDateTime"`
+	CheckedDateTime *time.Time `json:"checkedDateTime,omitempty"`
+}
+
+type LinkedResource struct {
+	ID              string `json:"id"`
+	WebURL          string `json:"webUrl"`
+	ApplicationName string `json:"applicationName"`
+	DisplayName     string `json:"displayName"`
+	ExternalID      string `json:"externalId"`
+}
+
 type Task struct {
-	IsReminderOn         bool            `json:"isReminderOn"`
-	Title                string          `json:"title"`
-	CreatedDateTime      time.Time       `json:"createdDateTime"`
-	LastModifiedDateTime time.Time       `json:"lastModifiedDateTime"`
-	DueDateTime          *time.Time      `json:"dueDateTime"`
-	Body                 TaskBody        `json:"body"`
-	Recurrence           *RecurrenceTask `json:"recurrence"`
+	ID                   string            `json:"id"`
+	Status               string            `json:"status"`
+	Importance           string            `json:"importance,omitempty"`
+	Categories           []string          `json:"categories,omitempty"`
+	IsReminderOn         bool              `json:"isReminderOn"`
+	HasAttachments       bool              `json:"hasAttachments,omitempty"`
+	Title                string            `json:"title"`
+	CreatedDateTime      time.Time         `json:"createdDateTime"`
+	LastModifiedDateTime time.Time         `json:"lastModifiedDateTime"`
+	DueDateTime          *DateTimeTimeZone `json:"dueDateTime"`
+	StartDateTime        *DateTimeTimeZone `json:"startDateTime,omitempty"`
+	ReminderDateTime     *DateTimeTimeZone `json:"reminderDateTime,omitempty"`
+	CompletedDateTime    *DateTimeTimeZone `json:"completedDateTime"`
+	Body                 TaskBody          `json:"body"`
+	Recurrence           *RecurrenceTask   `json:"recurrence"`
+	ChecklistItems       []ChecklistItem   `json:"checklistItems,omitempty"`
+	LinkedResources      []LinkedResource  `json:"linkedResources,omitempty"`
 }
 
 type TaskList struct {
+	ID                string
 	Name              string
 	WellknownListName string
 	IsShared          bool
 
 	Tasks []Task
+	// CompletedTasks holds tasks finished within the completion window, if fetched.
+	CompletedTasks []Task `json:",omitempty"`
 }
@@ -0,0 +1,154 @@
+package todo
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDateTimeTimeZoneUnmarshal(t *testing.T) {
+	tests := []struct {
+		name     string
+		dateTime string
+		timeZone string
+		expected time.Time
+	}{
+		{
+			name:     "UTC",
+			dateTime: "2024-06-01T00:00:00.0000000",
+			timeZone: "UTC",
+			expected: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Empty zone is UTC",
+			dateTime: "2024-06-01T00:00:00",
+			timeZone: "",
+			expected: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "IANA name, UTC+3 without DST",
+			dateTime: "2024-06-01T00:00:00.0000000",
+			timeZone: "Europe/Moscow",
+			expected: time.Date(2024, 5, 31, 21, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Windows name, UTC+3 without DST",
+			dateTime: "2024-06-01T00:00:00.0000000",
+			timeZone: "Russian Standard Time",
+			expected: time.Date(2024, 5, 31, 21, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Pacific, last minute before spring forward",
+			dateTime: "2024-03-10T01:59:00.0000000",
+			timeZone: "Pacific Standard Time",
+			expected: time.Date(2024, 3, 10, 9, 59, 0, 0, time.UTC),
+		},
+		{
+			name:     "Pacific, first minute after spring forward",
+			dateTime: "2024-03-10T03:00:00.0000000",
+			timeZone: "Pacific Standard Time",
+			expected: time.Date(2024, 3, 10, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Pacific, before fall back",
+			dateTime: "2024-11-03T00:59:00.0000000",
+			timeZone: "Pacific Standard Time",
+			expected: time.Date(2024, 11, 3, 7, 59, 0, 0, time.UTC),
+		},
+		{
+			name:     "Pacific, after fall back",
+			dateTime: "2024-11-03T02:00:00.0000000",
+			timeZone: "Pacific Standard Time",
+			expected: time.Date(2024, 11, 3, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Central Europe, before spring forward",
+			dateTime: "2024-03-31T01:59:00.0000000",
+			timeZone: "W. Europe Standard Time",
+			expected: time.Date(2024, 3, 31, 0, 59, 0, 0, time.UTC),
+		},
+		{
+			name:     "Central Europe, after spring forward",
+			dateTime: "2024-03-31T03:00:00.0000000",
+			timeZone: "W. Europe Standard Time",
+			expected: time.Date(2024, 3, 31, 1, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Southern hemisphere DST",
+			dateTime: "2024-01-15T00:00:00.0000000",
+			timeZone: "AUS Eastern Standard Time",
+			expected: time.Date(2024, 1, 14, 13, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Unknown zone falls back to UTC",
+			dateTime: "2024-06-01T12:00:00.0000000",
+			timeZone: "Mars Standard Time",
+			expected: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := fmt.Sprintf(`{"dateTime":%q,"timeZone":%q}`, tt.dateTime, tt.timeZone)
+
+			var d DateTimeTimeZone
+			require.NoError(t, json.Unmarshal([]byte(data), &d))
+
+			assert.True(t, tt.expected.Equal(d.Time), "got %s, want %s", d.Time.UTC(), tt.expected)
+			assert.Equal(t, tt.timeZone, d.TimeZone)
+		})
+	}
+}
+
+func TestDateTimeTimeZoneKeepsOriginalZone(t *testing.T) {
+	tests := []struct {
+		name     string
+		dateTime string
+		timeZone string
+	}{
+		{name: "Windows name", dateTime: "2024-03-10T03:00:00", timeZone: "Pacific Standard Time"},
+		{name: "IANA name", dateTime: "2024-11-03T00:59:00", timeZone: "America/Los_Angeles"},
+		{name: "UTC+3", dateTime: "2024-06-01T00:00:00", timeZone: "Russian Standard Time"},
+		{name: "UTC", dateTime: "2024-06-01T00:00:00", timeZone: "UTC"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := fmt.Sprintf(`{"dateTime":%q,"timeZone":%q}`, tt.dateTime, tt.timeZone)
+
+			var d DateTimeTimeZone
+			require.NoError(t, json.Unmarshal([]byte(data), &d))
+
+			out, err := json.Marshal(d)
+			require.NoError(t, err)
+			assert.JSONEq(t, data, string(out))
+		})
+	}
+}
+
+func TestTaskDueDateKeepsZoneInSnapshot(t *testing.T) {
+	const data = `{"id":"t1","title":"Pay rent","createdDateTime":"2024-05-20T08:00:00Z","lastModifiedDateTime":"2024-05-20T08:00:00Z",` +
+		`"dueDateTime":{"dateTime":"2024-06-01T00:00:00.0000000","timeZone":"Russian Standard Time"}}`
+
+	var task Task
+	require.NoError(t, json.Unmarshal([]byte(data), &task))
+	require.NotNil(t, task.DueDateTime)
+	assert.True(t, time.Date(2024, 5, 31, 21, 0, 0, 0, time.UTC).Equal(task.DueDateTime.Time))
+
+	out, err := json.Marshal(&task)
+	require.NoError(t, err)
+
+	var stored struct {
+		DueDateTime struct {
+			DateTime string `json:"dateTime"`
+			TimeZone string `json:"timeZone"`
+		} `json:"dueDateTime"`
+	}
+	require.NoError(t, json.Unmarshal(out, &stored))
+	assert.Equal(t, "2024-06-01T00:00:00", stored.DueDateTime.DateTime)
+	assert.Equal(t, "Russian Standard Time", stored.DueDateTime.TimeZone)
+}
@@ -1,24 +1,23 @@
 package todo
 
 import (
+	"bytes"
 	"encoding/json"
 	"time"
 )
 
-func (t *Task) UnmarshalJSON(data []byte) error {
-	const layout = "2006-01-02T15:04:05.9999999Z"
+const (
+	dateTimeOffsetLayout = "2006-01-02T15:04:05.9999999Z"
+	dateTimeLayout       = "2006-01-02T15:04:05.9999999"
+)
 
+func (t *Task) UnmarshalJSON(data []byte) error {
 	type TaskAlias Task
 
-	// First try the original API format
 	aliasValue := &struct {
 		*TaskAlias
 		CreatedDateTime      string `json:"createdDateTime"`
 		LastModifiedDateTime string `json:"lastModifiedDateTime"`
-		DueDateTime          *struct {
-			DateTime string `json:"dateTime"`
-			TimeZone string `json:"timeZone"`
-		} `json:"dueDateTime"`
 	}{
 		TaskAlias: (*TaskAlias)(t),
 	}
@@ -28,38 +27,15 @@ func (t *Task) UnmarshalJSON(data []byte) error {
 	}
 
 	var err error
-	t.CreatedDateTime, err = time.Parse(layout, aliasValue.CreatedDateTime)
+	t.CreatedDateTime, err = time.Parse(dateTimeOffsetLayout, aliasValue.CreatedDateTime)
 	if err != nil {
 		return err
 	}
-	t.LastModifiedDateTime, err = time.Parse(layout, aliasValue.LastModifiedDateTime)
+	t.LastModifiedDateTime, err = time.Parse(dateTimeOffsetLayout, aliasValue.LastModifiedDateTime)
 	if err != nil {
 		return err
 	}
 
-	if aliasValue.DueDateTime != nil {
-		const DueDateTimeLayout = "2006-01-02T15:04:05.9999999"
-		dueDateTime, err := time.Parse(DueDateTimeLayout, aliasValue.DueDateTime.DateTime)
-		if err != nil {
-			return err
-		}
-		t.DueDateTime = &dueDateTime
-	} else {
-		// Try fallback format for stored data (simple string format)
-		fallbackValue := &struct {
-			*TaskAlias
-			CreatedDateTime      string     `json:"createdDateTime"`
-			LastModifiedDateTime string     `json:"lastModifiedDateTime"`
-			DueDateTime          *time.Time `json:"dueDateTime"`
-		}{
-			TaskAlias: (*TaskAlias)(t),
-		}
-
-		if err := json.Unmarshal(data, fallbackValue); err == nil && fallbackValue.DueDateTime != nil {
-			t.DueDateTime = fallbackValue.DueDateTime
-		}
-	}
-
 	return nil
 }
 
@@ -68,27 +44,61 @@ func (t *Task) MarshalJSON() ([]byte, error) {
 
 	aliasValue := &struct {
 		*TaskAlias
-		CreatedDateTime      string  `json:"createdDateTime"`
-		LastModifiedDateTime string  `json:"lastModifiedDateTime"`
-		DueDateTime          *struct {
-			DateTime string `json:"dateTime"`
-			TimeZone string `json:"timeZone"`
-		} `json:"dueDateTime"`
+		CreatedDateTime      string `json:"createdDateTime"`
+		LastModifiedDateTime string `json:"lastModifiedDateTime"`
 	}{
 		TaskAlias:            (*TaskAlias)(t),
-		CreatedDateTime:      t.CreatedDateTime.Format("2006-01-02T15:04:05.9999999Z"),
-		LastModifiedDateTime: t.LastModifiedDateTime.Format("2006-01-02T15:04:05.9999999Z"),
+		CreatedDateTime:      t.CreatedDateTime.Format(dateTimeOffsetLayout),
+		LastModifiedDateTime: t.LastModifiedDateTime.Format(dateTimeOffsetLayout),
 	}
 
-	if t.DueDateTime != nil {
-		aliasValue.DueDateTime = &struct {
-			DateTime string `json:"dateTime"`
-			TimeZone string `json:"timeZone"`
-		}{
-			DateTime: t.DueDateTime.Format("2006-01-02T15:04:05.9999999"),
-			TimeZone: "UTC",
+	return json.Marshal(aliasValue)
+}
+
+// UnmarshalJSON resolves the wall-clock dateTime in its timeZone, so Time is
+// the actual instant. Windows zone names are mapped to IANA locations.
+func (d *DateTimeTimeZone) UnmarshalJSON(data []byte) error {
+	// Older stored snapshots kept due dates as plain RFC 3339 strings.
+	if bytes.HasPrefix(data, []byte(`"`)) {
+		var legacy time.Time
+		if err := json.Unmarshal(data, &legacy); err != nil {
+			return err
 		}
+		d.Time = legacy.UTC()
+		d.TimeZone = "UTC"
+		return nil
 	}
 
-	return json.Marshal(aliasValue)
+	value := struct {
+		DateTime string `json:"dateTime"`
+		TimeZone string `json:"timeZone"`
+	}{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	parsed, err := time.ParseInLocation(dateTimeLayout, value.DateTime, ResolveTimeZone(value.TimeZone))
+	if err != nil {
+		return err
+	}
+	d.Time = parsed
+	d.TimeZone = value.TimeZone
+	return nil
+}
+
+func (d DateTimeTimeZone) MarshalJSON() ([]byte, error) {
+	timeZone := d.TimeZone
+	if timeZone == "" {
+		timeZone = "UTC"
+	}
+
+	// Write the wall clock back in the original zone so the snapshot keeps
+	// the value exactly as Graph reported it.
+	return json.Marshal(struct {
+		DateTime string `json:"dateTime"`
+		TimeZone string `json:"timeZone"`
+	}{
+		DateTime: d.Time.In(ResolveTimeZone(timeZone)).Format(dateTimeLayout),
+		TimeZone: timeZone,
+	})
 }
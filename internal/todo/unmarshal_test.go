@@ -0,0 +1,103 @@
+package todo
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const graphTaskJSON = `{
+	"@odata.etag": "W/\"abc\"",
+	"id": "AAMkAGI2",
+	"status": "notStarted",
+	"importance": "high",
+	"categories": ["Red category", "Errands"],
+	"isReminderOn": true,
+	"hasAttachments": true,
+	"title": "Renew passport",
+	"createdDateTime": "2024-03-01T10:00:00.1234567Z",
+	"lastModifiedDateTime": "2024-03-02T11:30:00Z",
+	"dueDateTime": {"dateTime": "2024-03-10T00:00:00.0000000", "timeZone": "UTC"},
+	"startDateTime": {"dateTime": "2024-03-05T00:00:00.0000000", "timeZone": "UTC"},
+	"reminderDateTime": {"dateTime": "2024-03-09T09:00:00.0000000", "timeZone": "UTC"},
+	"body": {"content": "Bring photos", "contentType": "text"},
+	"recurrence": {
+		"pattern": {"type": "weekly", "interval": 2, "month": 0, "dayOfMonth": 0, "daysOfWeek": ["monday", "thursday"], "firstDayOfWeek": "sunday", "index": "first"},
+		"range": {"type": "noEnd", "startDate": "2024-03-10", "endDate": "0001-01-01", "numberOfOccurrences": 0, "recurrenceTimeZone": "UTC"}
+	},
+	"checklistItems": [
+		{"id": "c1", "displayName": "Photos", "isChecked": true, "createdDateTime": "2024-03-01T10:05:00Z", "checkedDateTime": "2024-03-03T08:00:00Z"}
+	],
+	"linkedResources": [
+		{"id": "l1", "webUrl": "https://example.com/mail/1", "applicationName": "Outlook", "displayName": "Reminder email", "externalId": "ext-1"}
+	]
+}`
+
+// legacySnapshotJSON is task_lists_json as written before the full task model existed.
+const legacySnapshotJSON = `[{"Name":"Work","WellknownListName":"none","IsShared":false,"Tasks":[` +
+	`{"isReminderOn":false,"title":"Fix bug","createdDateTime":"2024-03-01T10:00:00Z","lastModifiedDateTime":"2024-03-02T11:30:00.5Z",` +
+	`"dueDateTime":{"dateTime":"2024-03-10T00:00:00","timeZone":"UTC"},"body":{"content":"","contentType":"text"},"recurrence":null},` +
+	`{"isReminderOn":false,"title":"No due date","createdDateTime":"2024-02-01T08:00:00Z","lastModifiedDateTime":"2024-02-01T08:00:00Z",` +
+	`"dueDateTime":null,"body":{"content":"","contentType":"text"},"recurrence":null}]}]`
+
+func TestTaskUnmarshalGraphPayload(t *testing.T) {
+	var task Task
+	require.NoError(t, json.Unmarshal([]byte(graphTaskJSON), &task))
+
+	assert.Equal(t, "AAMkAGI2", task.ID)
+	assert.Equal(t, StatusNotStarted, task.Status)
+	assert.Equal(t, ImportanceHigh, task.Importance)
+	assert.Equal(t, []string{"Red category", "Errands"}, task.Categories)
+	assert.True(t, task.HasAttachments)
+	assert.Equal(t, time.Date(2024, 3, 1, 10, 0, 0, 123456700, time.UTC), task.CreatedDateTime)
+	require.NotNil(t, task.DueDateTime)
+	assert.Equal(t, time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC), task.DueDateTime.Time)
+	assert.Equal(t, "UTC", task.DueDateTime.TimeZone)
+	require.NotNil(t, task.StartDateTime)
+	require.NotNil(t, task.ReminderDateTime)
+	assert.Equal(t, time.Date(2024, 3, 9, 9, 0, 0, 0, time.UTC), task.ReminderDateTime.Time)
+	assert.Nil(t, task.CompletedDateTime)
+	require.NotNil(t, task.Recurrence)
+	assert.Equal(t, []string{"monday", "thursday"}, task.Recurrence.Pattern.DaysOfWeek)
+	assert.Equal(t, "2024-03-10", task.Recurrence.Range.StartDate)
+	require.Len(t, task.ChecklistItems, 1)
+	assert.True(t, task.ChecklistItems[0].IsChecked)
+	require.NotNil(t, task.ChecklistItems[0].CheckedDateTime)
+	require.Len(t, task.LinkedResources, 1)
+	assert.Equal(t, "Outlook", task.LinkedResources[0].ApplicationName)
+}
+
+func TestTaskRoundTrip(t *testing.T) {
+	var task Task
+	require.NoError(t, json.Unmarshal([]byte(graphTaskJSON), &task))
+
+	data, err := json.Marshal(&task)
+	require.NoError(t, err)
+
+	var decoded Task
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, task, decoded)
+}
+
+func TestLegacySnapshotRoundTrip(t *testing.T) {
+	var taskLists []TaskList
+	require.NoError(t, json.Unmarshal([]byte(legacySnapshotJSON), &taskLists))
+
+	require.Len(t, taskLists, 1)
+	require.Len(t, taskLists[0].Tasks, 2)
+	first := taskLists[0].Tasks[0]
+	assert.Equal(t, "Fix bug", first.Title)
+	require.NotNil(t, first.DueDateTime)
+	assert.Equal(t, time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC), first.DueDateTime.Time)
+	assert.Nil(t, taskLists[0].Tasks[1].DueDateTime)
+
+	data, err := json.Marshal(taskLists)
+	require.NoError(t, err)
+
+	var again []TaskList
+	require.NoError(t, json.Unmarshal(data, &again))
+	assert.Equal(t, taskLists, again)
+}
@@ -10,13 +10,11 @@ import (
 
 	"github.com/pkg/errors"
 
+	"github.com/uchr/ToDoInfo/internal/clock"
 	"github.com/uchr/ToDoInfo/internal/httpclient"
 	"github.com/uchr/ToDoInfo/internal/todo"
 )
 
-const baseRequestUrl = "https://graph.microsoft.com/v1.0/me/todo/lists"
-const baseRequestUrlDelta = "https://graph.microsoft.com/v1.0/me/todo/lists/microsoft.graph.delta()"
-
 type taskListInfo struct {
 	ID                string `json:"id"`
 	DisplayName       string `json:"displayName"`
@@ -25,98 +23,169 @@ type taskListInfo struct {
 }
 
 type TodoParser struct {
+	config *Config
+	http   *httpclient.Client
+	clock  clock.Clock
 }
 
-func New() *TodoParser {
-	return &TodoParser{}
+func New(cfg *Config) *TodoParser {
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		client = httpclient.New(httpclient.DefaultConfig())
+	}
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.Real()
+	}
+	return &TodoParser{config: cfg, http: client, clock: clk}
 }
 
-func (parser *TodoParser) requestTaskListInfos(ctx context.Context, logger *slog.Logger, token string) ([]taskListInfo, error) {
-	responseBody, err := httpclient.GetRequest(ctx, logger, baseRequestUrlDelta, token)
-	if err != nil {
-		return nil, err
-	}
+// listsUrl returns the collection URL of the user's To Do lists.
+func (parser *TodoParser) listsUrl() string {
+	return parser.config.BaseURL + "/me/todo/lists"
+}
 
-	type taskListResponse struct {
-		Value []taskListInfo `json:"value"`
-	}
+// listsDeltaUrl returns the delta query URL of the user's To Do lists.
+func (parser *TodoParser) listsDeltaUrl() string {
+	return parser.listsUrl() + "/microsoft.graph.delta()"
+}
 
-	err = httpclient.GetResponseError(responseBody)
-	if err != nil {
-		return nil, errors.Errorf("request task lists error. %v", err)
-	}
+func (parser *TodoParser) requestTaskListInfos(ctx context.Context, logger *slog.Logger, token string) ([]taskListInfo, error) {
+	requestUrl := fmt.Sprintf("%s?$top=%d", parser.listsDeltaUrl(), parser.config.PageSize)
 
-	tl := taskListResponse{}
-	err = json.Unmarshal(responseBody, &tl)
-	if err != nil {
-		return nil, err
-	}
-	return tl.Value, nil
+	return requestAllPages[taskListInfo](ctx, logger, parser.http, token, requestUrl, parser.config.MaxPages, "task lists")
 }
 
+// taskExpandQuery asks Graph to inline the task navigation properties.
+// Delta queries are issued without it, so ChecklistItems and LinkedResources
+// are only populated by full fetches and GetTask.
+const taskExpandQuery = "$expand=checklistItems,linkedResources"
+
+const taskExpand = "&" + taskExpandQuery
+
 func (parser *TodoParser) requestTaskList(ctx context.Context, logger *slog.Logger, token string, taskListId string) ([]todo.Task, error) {
 	const taskListUrl = "tasks?$filter=status%20eq%20'notStarted'"
 
 	logger.DebugContext(ctx, "Request tasks infos", slog.String("taskListId", taskListId))
 
-	responseBody, err := httpclient.GetRequest(ctx, logger, baseRequestUrl+fmt.Sprintf("/%s/", taskListId)+taskListUrl, token)
-	if err != nil {
-		return nil, err
-	}
+	requestUrl := parser.listsUrl() + fmt.Sprintf("/%s/", taskListId) + taskListUrl + fmt.Sprintf("&$top=%d", parser.config.PageSize) + taskExpand
 
-	type taskListResponse struct {
-		Value []todo.Task `json:"value"`
-	}
+	return requestAllPages[todo.Task](ctx, logger, parser.http, token, requestUrl, parser.config.MaxPages, fmt.Sprintf("tasks '%s'", taskListId))
+}
 
-	err = httpclient.GetResponseError(responseBody)
+// requestCompletedTaskList fetches the tasks of a list completed on or after since.
+func (parser *TodoParser) requestCompletedTaskList(ctx context.Context, logger *slog.Logger, token string, taskListId string, since time.Time) ([]todo.Task, error) {
+	taskListUrl := fmt.Sprintf("tasks?$filter=status%%20eq%%20'completed'%%20and%%20completedDateTime/dateTime%%20ge%%20'%s'",
+		since.UTC().Format("2006-01-02T15:04:05"))
+
+	logger.DebugContext(ctx, "Request completed tasks infos", slog.String("taskListId", taskListId))
+
+	requestUrl := parser.listsUrl() + fmt.Sprintf("/%s/", taskListId) + taskListUrl + fmt.Sprintf("&$top=%d", parser.config.PageSize) + taskExpand
+
+	return requestAllPages[todo.Task](ctx, logger, parser.http, token, requestUrl, parser.config.MaxPages, fmt.Sprintf("completed tasks '%s'", taskListId))
+}
+
+// GetTask fetches a single task together with its checklist items and
+// linked resources.
+func (parser *TodoParser) GetTask(ctx context.Context, logger *slog.Logger, token string, listID, taskID string) (todo.Task, error) {
+	body, err := parser.http.Get(ctx, logger, parser.taskUrl(listID, taskID)+"?"+taskExpandQuery, token)
 	if err != nil {
-		return nil, errors.Errorf("request tasks '%s' error. %v", taskListId, err)
+		return todo.Task{}, errors.Wrapf(err, "request task '%s' error", taskID)
 	}
 
-	tl := taskListResponse{}
-	err = json.Unmarshal(responseBody, &tl)
-	if err != nil {
-		return nil, err
+	task := todo.Task{}
+	if err := json.Unmarshal(body, &task); err != nil {
+		return todo.Task{}, errors.Wrapf(err, "parse task '%s'", taskID)
 	}
+	return task, nil
+}
 
-	return tl.Value, nil
+// CompletedWindow returns how far back completed tasks are fetched; zero when disabled.
+func (parser *TodoParser) CompletedWindow() time.Duration {
+	return parser.config.CompletedWindow
 }
 
-func (parser *TodoParser) GetTasks(ctx context.Context, logger *slog.Logger, token string) ([]todo.TaskList, error) {
+// GetTasks fetches the open tasks of every user-created list. Lists that fail
+// are reported in the result instead of failing the fetch; an error is
+// returned only when the lists cannot be enumerated or none could be fetched.
+func (parser *TodoParser) GetTasks(ctx context.Context, logger *slog.Logger, token string) (*FetchResult, error) {
 	taskListInfos, err := parser.requestTaskListInfos(ctx, logger, token)
 	if err != nil {
 		return nil, err
 	}
 
-	taskLists, err := parser.getListInfos(ctx, logger, token, taskListInfos)
-
-	return taskLists, err
+	return parser.getListInfos(ctx, logger, token, taskListInfos)
 }
 
-func (parser *TodoParser) getListInfos(ctx context.Context, logger *slog.Logger, token string, taskListInfos []taskListInfo) ([]todo.TaskList, error) {
-	const maxRequestForSecond = 4 // Max number of request to MS TODO
+func (parser *TodoParser) getListInfos(ctx context.Context, logger *slog.Logger, token string, taskListInfos []taskListInfo) (*FetchResult, error) {
+	return parser.runForLists(ctx, logger, taskListInfos, func(info taskListInfo) (todo.TaskList, error) {
+		tasks, err := parser.requestTaskList(ctx, logger, token, info.ID)
+		if err != nil {
+			return todo.TaskList{}, err
+		}
 
-	outputCh := make(chan taskListProcessingResult)
-	go func() {
-		wg := sync.WaitGroup{}
-		counter := 0
-		for _, info := range taskListInfos {
-			if info.WellknownListName != "none" {
-				continue
+		var completedTasks []todo.Task
+		if parser.config.CompletedWindow > 0 {
+			since := parser.clock.Now().Add(-parser.config.CompletedWindow)
+			completedTasks, err = parser.requestCompletedTaskList(ctx, logger, token, info.ID, since)
+			if err != nil {
+				return todo.TaskList{}, err
 			}
+		}
 
-			if counter >= maxRequestForSecond {
-				time.Sleep(time.Second)
-				counter = 0
-			}
+		return todo.TaskList{
+			ID:                info.ID,
+			Name:              info.DisplayName,
+			WellknownListName: info.WellknownListName,
+			IsShared:          info.IsShared,
+			Tasks:             tasks,
+			CompletedTasks:    completedTasks}, nil
+	})
+}
 
-			wg.Add(1)
-			go func(info taskListInfo) {
-				defer wg.Done()
-				parser.processTaskListInfo(ctx, logger, token, info, outputCh)
-			}(info)
+// runForLists calls process for every list the ListFilter allows on a
+// bounded pool of workers. Request pacing is left to the shared limiter of
+// the HTTP client. A list that fails is recorded in the result and the
+// others carry on; the error is only set when ctx is done or every list failed.
+func (parser *TodoParser) runForLists(ctx context.Context, logger *slog.Logger, taskListInfos []taskListInfo, process func(info taskListInfo) (todo.TaskList, error)) (*FetchResult, error) {
+	result := &FetchResult{}
+	var selected []taskListInfo
+	for _, info := range taskListInfos {
+		if !parser.config.Lists.Allows(info.DisplayName, info.WellknownListName) {
+			result.Excluded = append(result.Excluded, ExcludedList{
+				ID:                info.ID,
+				Name:              info.DisplayName,
+				WellknownListName: info.WellknownListName,
+			})
+			continue
+		}
+		selected = append(selected, info)
+	}
 
-			counter++
+	jobs := make(chan taskListInfo)
+	go func() {
+		defer close(jobs)
+		for _, info := range selected {
+			select {
+			case jobs <- info:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	outputCh := make(chan taskListProcessingResult)
+	go func() {
+		wg := sync.WaitGroup{}
+		for range parser.config.Concurrency {
+			wg.Go(func() {
+				for info := range jobs {
+					parser.processTaskListInfo(ctx, logger, info, process, outputCh)
+				}
+			})
 		}
 
 		wg.Wait()
@@ -124,40 +193,47 @@ func (parser *TodoParser) getListInfos(ctx context.Context, logger *slog.Logger,
 		close(outputCh)
 	}()
 
-	var err error
-	var taskLists []todo.TaskList
 	for processingResult := range outputCh {
 		if processingResult.err != nil {
-			err = processingResult.err
-			continue
-		}
-		if err != nil {
+			result.Failed = append(result.Failed, ListError{
+				ListID:   processingResult.info.ID,
+				ListName: processingResult.info.DisplayName,
+				Err:      processingResult.err,
+			})
 			continue
 		}
-		taskLists = append(taskLists, processingResult.taskList)
+		result.TaskLists = append(result.TaskLists, processingResult.taskList)
 	}
+	result.sort()
 
-	return taskLists, err
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(result.TaskLists) == 0 && result.Partial() {
+		return nil, errors.Wrapf(&result.Failed[0], "all %d task lists failed", len(result.Failed))
+	}
+
+	return result, nil
+}
+
+// HTTPStats reports the state of the limiter pacing the Graph requests.
+func (parser *TodoParser) HTTPStats() httpclient.LimiterStats {
+	return parser.http.Stats()
 }
 
 type taskListProcessingResult struct {
+	info     taskListInfo
 	taskList todo.TaskList
 	err      error
 }
 
-func (parser *TodoParser) processTaskListInfo(ctx context.Context, logger *slog.Logger, token string, info taskListInfo, out chan taskListProcessingResult) {
-	tasks, err := parser.requestTaskList(ctx, logger, token, info.ID)
+func (parser *TodoParser) processTaskListInfo(ctx context.Context, logger *slog.Logger, info taskListInfo, process func(info taskListInfo) (todo.TaskList, error), out chan taskListProcessingResult) {
+	taskList, err := process(info)
 	if err != nil {
-		logger.ErrorContext(ctx, "Error processing task list info", slog.Any("error", err))
-		out <- taskListProcessingResult{err: err}
+		logger.ErrorContext(ctx, "Error processing task list info", slog.String("taskList", info.DisplayName), slog.Any("error", err))
+		out <- taskListProcessingResult{info: info, err: err}
 		return
 	}
 
-	taskList := todo.TaskList{
-		Name:              info.DisplayName,
-		WellknownListName: info.WellknownListName,
-		IsShared:          info.IsShared,
-		Tasks:             tasks}
-
-	out <- taskListProcessingResult{taskList: taskList}
+	out <- taskListProcessingResult{info: info, taskList: taskList}
 }
@@ -0,0 +1,224 @@
+package todoclient
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uchr/ToDoInfo/internal/clock"
+	"github.com/uchr/ToDoInfo/internal/graphfake"
+	"github.com/uchr/ToDoInfo/internal/httpclient"
+	"github.com/uchr/ToDoInfo/internal/todo"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func newTestParser(server *graphfake.Server) *TodoParser {
+	return New(DefaultConfig().
+		WithBaseURL(server.URL()).
+		WithHTTPClient(server.HTTPClient()))
+}
+
+func listNames(lists []todo.TaskList) []string {
+	names := make([]string, 0, len(lists))
+	for _, l := range lists {
+		names = append(names, l.Name)
+	}
+	return names
+}
+
+func taskIDs(list todo.TaskList) []string {
+	ids := make([]string, 0, len(list.Tasks))
+	for _, t := range list.Tasks {
+		ids = append(ids, t.ID)
+	}
+	return ids
+}
+
+func TestGetTasksFollowsPages(t *testing.T) {
+	server := graphfake.New(t, "basic")
+	server.SetPageSize(1)
+
+	result, err := newTestParser(server).GetTasks(t.Context(), testLogger, graphfake.Token)
+	require.NoError(t, err)
+
+	assert.False(t, result.Partial())
+	// The default list is skipped and the rest are sorted by name.
+	require.Equal(t, []string{"Family", "Home", "Work"}, listNames(result.TaskLists))
+	assert.Equal(t, []string{"Tasks"}, result.ExcludedNames())
+	assert.ElementsMatch(t, []string{"w-1", "w-2", "w-3"}, taskIDs(result.TaskLists[2]))
+	assert.True(t, result.TaskLists[0].IsShared)
+}
+
+func TestGetTasksListFilter(t *testing.T) {
+	server := graphfake.New(t, "basic")
+	parser := New(DefaultConfig().
+		WithBaseURL(server.URL()).
+		WithHTTPClient(server.HTTPClient()).
+		WithListFilter(ListFilter{
+			Wellknown: []string{WellknownNone, WellknownDefaultList},
+			Exclude:   []string{"fam*"},
+		}))
+
+	result, err := parser.GetTasks(t.Context(), testLogger, graphfake.Token)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"Home", "Tasks", "Work"}, listNames(result.TaskLists))
+	assert.Equal(t, []string{"Family"}, result.ExcludedNames())
+}
+
+func TestGetTasksReportsFailedLists(t *testing.T) {
+	server := graphfake.New(t, "basic")
+	server.FailList("family", http.StatusForbidden)
+
+	result, err := newTestParser(server).GetTasks(t.Context(), testLogger, graphfake.Token)
+	require.NoError(t, err)
+
+	assert.True(t, result.Partial())
+	assert.Equal(t, []string{"Home", "Work"}, listNames(result.TaskLists))
+	require.Len(t, result.Failed, 1)
+	assert.Equal(t, "family", result.Failed[0].ListID)
+	assert.Equal(t, "Family", result.Failed[0].ListName)
+
+	var respErr *httpclient.ResponseError
+	require.True(t, errors.As(&result.Failed[0], &respErr))
+	assert.Equal(t, http.StatusForbidden, respErr.StatusCode)
+}
+
+func TestGetTasksFailsWhenEveryListFails(t *testing.T) {
+	server := graphfake.New(t, "basic")
+	for _, id := range []string{"work", "home", "family"} {
+		server.FailList(id, http.StatusNotFound)
+	}
+
+	_, err := newTestParser(server).GetTasks(t.Context(), testLogger, graphfake.Token)
+	assert.ErrorContains(t, err, "all 3 task lists failed")
+}
+
+func TestGetTasksRetriesThrottledRequests(t *testing.T) {
+	server := graphfake.New(t, "basic")
+	server.Throttle(3, 0)
+
+	parser := newTestParser(server)
+	result, err := parser.GetTasks(t.Context(), testLogger, graphfake.Token)
+	require.NoError(t, err)
+
+	assert.Len(t, result.TaskLists, 3)
+	assert.Equal(t, 3, parser.HTTPStats().Throttled)
+}
+
+func TestGetTasksRejectedToken(t *testing.T) {
+	server := graphfake.New(t, "basic")
+
+	_, err := newTestParser(server).GetTasks(t.Context(), testLogger, "expired")
+
+	var respErr *httpclient.ResponseError
+	require.True(t, errors.As(err, &respErr))
+	assert.Equal(t, http.StatusUnauthorized, respErr.StatusCode)
+	assert.Equal(t, "InvalidAuthenticationToken", respErr.Code)
+}
+
+func TestSyncTasksAppliesDeltas(t *testing.T) {
+	server := graphfake.New(t, "basic")
+	server.SetPageSize(2)
+	parser := newTestParser(server)
+	state := NewDeltaState()
+
+	result, err := parser.SyncTasks(t.Context(), testLogger, graphfake.Token, state)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Family", "Home", "Work"}, listNames(result.TaskLists))
+	assert.ElementsMatch(t, []string{"h-1", "h-2"}, taskIDs(result.TaskLists[1]))
+
+	server.RemoveTask("home", "h-2")
+	server.PutTask("home", todo.Task{
+		ID:              "h-3",
+		Status:          todo.StatusNotStarted,
+		Title:           "Paint the door",
+		CreatedDateTime: time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC),
+	})
+	server.RemoveList("family")
+
+	result, err = parser.SyncTasks(t.Context(), testLogger, graphfake.Token, state)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Home", "Work"}, listNames(result.TaskLists))
+	assert.ElementsMatch(t, []string{"h-1", "h-3"}, taskIDs(result.TaskLists[0]))
+}
+
+func TestSyncTasksExpiresCompletedByClock(t *testing.T) {
+	server := graphfake.New(t, "basic")
+	clk := clock.NewFixed(time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC))
+	parser := New(DefaultConfig().
+		WithBaseURL(server.URL()).
+		WithHTTPClient(server.HTTPClient()).
+		WithCompletedDays(7).
+		WithClock(clk))
+	state := NewDeltaState()
+
+	result, err := parser.SyncTasks(t.Context(), testLogger, graphfake.Token, state)
+	require.NoError(t, err)
+	require.Len(t, result.TaskLists[2].CompletedTasks, 1)
+	assert.Equal(t, "w-4", result.TaskLists[2].CompletedTasks[0].ID)
+
+	clk.Advance(7 * 24 * time.Hour)
+	result, err = parser.SyncTasks(t.Context(), testLogger, graphfake.Token, state)
+	require.NoError(t, err)
+	assert.Empty(t, result.TaskLists[2].CompletedTasks, "w-4 left the window as the clock moved on")
+}
+
+func TestSyncTasksResyncsExpiredDeltaLinks(t *testing.T) {
+	server := graphfake.New(t, "basic")
+	parser := newTestParser(server)
+	state := NewDeltaState()
+
+	_, err := parser.SyncTasks(t.Context(), testLogger, graphfake.Token, state)
+	require.NoError(t, err)
+
+	server.ExpireDeltaLinks()
+	server.RemoveTask("work", "w-1")
+
+	result, err := parser.SyncTasks(t.Context(), testLogger, graphfake.Token, state)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Family", "Home", "Work"}, listNames(result.TaskLists))
+	assert.ElementsMatch(t, []string{"w-2", "w-3"}, taskIDs(result.TaskLists[2]))
+}
+
+func TestSyncTasksKeepsStateWhenResyncFails(t *testing.T) {
+	server := graphfake.New(t, "basic")
+	parser := newTestParser(server)
+	state := NewDeltaState()
+
+	_, err := parser.SyncTasks(t.Context(), testLogger, graphfake.Token, state)
+	require.NoError(t, err)
+	workDeltaLink := state.Lists["work"].DeltaLink
+
+	// Work's resync fails after its delta link is rejected.
+	server.ExpireTaskDeltaLinks("work")
+	server.FailList("work", http.StatusForbidden)
+	result, err := parser.SyncTasks(t.Context(), testLogger, graphfake.Token, state)
+	require.NoError(t, err)
+	require.Len(t, result.Failed, 1)
+	assert.Equal(t, "work", result.Failed[0].ListID)
+	assert.Equal(t, workDeltaLink, state.Lists["work"].DeltaLink)
+	assert.Len(t, state.Lists["work"].Tasks, 3)
+
+	// The task lists resync needs more pages than allowed.
+	listsDeltaLink := state.ListsDeltaLink
+	server.ExpireDeltaLinks()
+	server.SetPageSize(1)
+	parser = New(DefaultConfig().
+		WithBaseURL(server.URL()).
+		WithHTTPClient(server.HTTPClient()).
+		WithPageSize(1).
+		WithMaxPages(2))
+	_, err = parser.SyncTasks(t.Context(), testLogger, graphfake.Token, state)
+	require.ErrorContains(t, err, "page limit 2 reached")
+	assert.Equal(t, listsDeltaLink, state.ListsDeltaLink)
+	assert.Len(t, state.Lists, 4)
+	assert.Len(t, state.Lists["work"].Tasks, 3)
+}
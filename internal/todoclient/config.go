@@ -0,0 +1,108 @@
+package todoclient
+
+import (
+	"strings"
+	"time"
+
+	"github.com/uchr/ToDoInfo/internal/clock"
+	"github.com/uchr/ToDoInfo/internal/httpclient"
+)
+
+const (
+	// DefaultBaseURL is the Microsoft Graph v1.0 endpoint.
+	DefaultBaseURL  = "https://graph.microsoft.com/v1.0"
+	defaultPageSize = 100
+	defaultMaxPages = 50
+	// defaultConcurrency is how many lists are fetched at once.
+	defaultConcurrency = 4
+)
+
+// Config holds the settings used when querying Microsoft To Do
+type Config struct {
+	// BaseURL is the Graph endpoint the request paths are appended to.
+	BaseURL string
+	// PageSize is the $top value sent with every collection request.
+	PageSize int
+	// MaxPages caps how many @odata.nextLink pages are followed per collection.
+	MaxPages int
+	// CompletedWindow is how far back completed tasks are fetched. Zero disables it.
+	CompletedWindow time.Duration
+	// Lists selects which lists are fetched.
+	Lists ListFilter
+	// Concurrency bounds how many lists are fetched at the same time.
+	Concurrency int
+	// HTTPClient sends the Graph requests. Nil uses a client with default retries.
+	HTTPClient *httpclient.Client
+	// Clock dates the CompletedWindow. Nil uses the wall clock.
+	Clock clock.Clock
+}
+
+// DefaultConfig returns the default To Do client configuration
+func DefaultConfig() *Config {
+	return &Config{
+		BaseURL:     DefaultBaseURL,
+		PageSize:    defaultPageSize,
+		MaxPages:    defaultMaxPages,
+		Lists:       DefaultListFilter(),
+		Concurrency: defaultConcurrency,
+	}
+}
+
+// WithBaseURL points the client at another Graph endpoint, e.g. a local
+// fake server. Empty values keep the default.
+func (c *Config) WithBaseURL(baseURL string) *Config {
+	if baseURL != "" {
+		c.BaseURL = strings.TrimSuffix(baseURL, "/")
+	}
+	return c
+}
+
+// WithPageSize sets the page size requested from Graph. Non-positive values keep the default.
+func (c *Config) WithPageSize(pageSize int) *Config {
+	if pageSize > 0 {
+		c.PageSize = pageSize
+	}
+	return c
+}
+
+// WithMaxPages sets the page cap per collection. Non-positive values keep the default.
+func (c *Config) WithMaxPages(maxPages int) *Config {
+	if maxPages > 0 {
+		c.MaxPages = maxPages
+	}
+	return c
+}
+
+// WithCompletedDays enables fetching tasks completed within the last days days.
+func (c *Config) WithCompletedDays(days int) *Config {
+	if days > 0 {
+		c.CompletedWindow = time.Duration(days) * 24 * time.Hour
+	}
+	return c
+}
+
+// WithConcurrency sets how many lists are fetched at once. Non-positive values keep the default.
+func (c *Config) WithConcurrency(concurrency int) *Config {
+	if concurrency > 0 {
+		c.Concurrency = concurrency
+	}
+	return c
+}
+
+// WithListFilter sets which lists are fetched.
+func (c *Config) WithListFilter(filter ListFilter) *Config {
+	c.Lists = filter
+	return c
+}
+
+// WithClock sets the clock the completed-task window is measured from.
+func (c *Config) WithClock(clk clock.Clock) *Config {
+	c.Clock = clk
+	return c
+}
+
+// WithHTTPClient sets the HTTP client used for Graph requests.
+func (c *Config) WithHTTPClient(client *httpclient.Client) *Config {
+	c.HTTPClient = client
+	return c
+}
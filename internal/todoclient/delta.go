@@ -0,0 +1,247 @@
+package todoclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/uchr/ToDoInfo/internal/httpclient"
+	"github.com/uchr/ToDoInfo/internal/todo"
+)
+
+// DeltaState is the locally materialised copy of the user's lists and open
+// tasks, together with the delta links needed to bring it up to date.
+type DeltaState struct {
+	ListsDeltaLink string
+	Lists          map[string]*ListDeltaState
+}
+
+// ListDeltaState holds one list's metadata, its task delta link and the open
+// tasks known for it keyed by task ID. CompletedTasks is only populated when
+// a completion window is configured.
+type ListDeltaState struct {
+	ID                string
+	Name              string
+	WellknownListName string
+	IsShared          bool
+	DeltaLink         string
+	Tasks             map[string]todo.Task
+	CompletedTasks    map[string]todo.Task
+}
+
+// NewListDeltaState returns an empty state for the list with the given ID.
+func NewListDeltaState(id string) *ListDeltaState {
+	return &ListDeltaState{
+		ID:             id,
+		Tasks:          make(map[string]todo.Task),
+		CompletedTasks: make(map[string]todo.Task),
+	}
+}
+
+// NewDeltaState returns an empty state; syncing it performs a full fetch.
+func NewDeltaState() *DeltaState {
+	return &DeltaState{Lists: make(map[string]*ListDeltaState)}
+}
+
+func (l *ListDeltaState) taskList() todo.TaskList {
+	return todo.TaskList{
+		ID:                l.ID,
+		Name:              l.Name,
+		WellknownListName: l.WellknownListName,
+		IsShared:          l.IsShared,
+		Tasks:             sortedTasks(l.Tasks),
+		CompletedTasks:    sortedTasks(l.CompletedTasks),
+	}
+}
+
+// expireCompleted drops completed tasks finished before since.
+func (l *ListDeltaState) expireCompleted(since time.Time) {
+	for id, task := range l.CompletedTasks {
+		if task.CompletedDateTime == nil || task.CompletedDateTime.Time.Before(since) {
+			delete(l.CompletedTasks, id)
+		}
+	}
+}
+
+func sortedTasks(taskMap map[string]todo.Task) []todo.Task {
+	if len(taskMap) == 0 {
+		return nil
+	}
+
+	tasks := make([]todo.Task, 0, len(taskMap))
+	for _, task := range taskMap {
+		tasks = append(tasks, task)
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		if tasks[i].CreatedDateTime.Equal(tasks[j].CreatedDateTime) {
+			return tasks[i].ID < tasks[j].ID
+		}
+		return tasks[i].CreatedDateTime.Before(tasks[j].CreatedDateTime)
+	})
+	return tasks
+}
+
+// deltaEntry is the part of a delta item needed to tell removals apart from
+// additions and updates.
+type deltaEntry struct {
+	ID      string `json:"id"`
+	Removed *struct {
+		Reason string `json:"reason"`
+	} `json:"@removed"`
+}
+
+// SyncTasks brings state up to date using Graph delta queries and returns
+// the resulting task lists. An empty state triggers a full download; when
+// Graph no longer recognises a stored delta link the affected scope is
+// resynced from scratch. A list that fails to sync keeps its stored state,
+// so the next sync retries it, and is reported in the result.
+func (parser *TodoParser) SyncTasks(ctx context.Context, logger *slog.Logger, token string, state *DeltaState) (*FetchResult, error) {
+	err := parser.syncTaskListInfos(ctx, logger, token, state)
+	if isSyncStateLost(err) {
+		logger.WarnContext(ctx, "Task lists delta link expired, running full resync", slog.Any("error", err))
+		fresh := NewDeltaState()
+		if err = parser.syncTaskListInfos(ctx, logger, token, fresh); err == nil {
+			*state = *fresh
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	taskListInfos := make([]taskListInfo, 0, len(state.Lists))
+	for _, list := range state.Lists {
+		taskListInfos = append(taskListInfos, taskListInfo{
+			ID:                list.ID,
+			DisplayName:       list.Name,
+			WellknownListName: list.WellknownListName,
+			IsShared:          list.IsShared,
+		})
+	}
+
+	return parser.runForLists(ctx, logger, taskListInfos, func(info taskListInfo) (todo.TaskList, error) {
+		return parser.syncTaskList(ctx, logger, token, state.Lists[info.ID])
+	})
+}
+
+func (parser *TodoParser) syncTaskListInfos(ctx context.Context, logger *slog.Logger, token string, state *DeltaState) error {
+	requestUrl := state.ListsDeltaLink
+	if requestUrl == "" {
+		requestUrl = fmt.Sprintf("%s?$top=%d", parser.listsDeltaUrl(), parser.config.PageSize)
+	}
+
+	entries, deltaLink, err := requestAllPagesWithDelta[json.RawMessage](ctx, logger, parser.http, token, requestUrl, parser.config.MaxPages, "task lists delta")
+	if err != nil {
+		return err
+	}
+
+	for _, raw := range entries {
+		entry := deltaEntry{}
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			return err
+		}
+		if entry.Removed != nil {
+			delete(state.Lists, entry.ID)
+			continue
+		}
+
+		info := taskListInfo{}
+		if err := json.Unmarshal(raw, &info); err != nil {
+			return err
+		}
+
+		list, ok := state.Lists[info.ID]
+		if !ok {
+			list = NewListDeltaState(info.ID)
+			state.Lists[info.ID] = list
+		}
+		list.Name = info.DisplayName
+		list.WellknownListName = info.WellknownListName
+		list.IsShared = info.IsShared
+	}
+
+	state.ListsDeltaLink = deltaLink
+	return nil
+}
+
+func (parser *TodoParser) syncTaskList(ctx context.Context, logger *slog.Logger, token string, list *ListDeltaState) (todo.TaskList, error) {
+	err := parser.applyTaskDelta(ctx, logger, token, list)
+	if isSyncStateLost(err) {
+		logger.WarnContext(ctx, "Tasks delta link expired, running full resync", slog.String("taskListId", list.ID), slog.Any("error", err))
+		// Resync into a fresh state so that a failure keeps the stored one.
+		fresh := NewListDeltaState(list.ID)
+		fresh.Name = list.Name
+		fresh.WellknownListName = list.WellknownListName
+		fresh.IsShared = list.IsShared
+		if err = parser.applyTaskDelta(ctx, logger, token, fresh); err == nil {
+			*list = *fresh
+		}
+	}
+	if err != nil {
+		return todo.TaskList{}, err
+	}
+
+	if parser.config.CompletedWindow > 0 {
+		list.expireCompleted(parser.clock.Now().Add(-parser.config.CompletedWindow))
+	} else {
+		clear(list.CompletedTasks)
+	}
+
+	return list.taskList(), nil
+}
+
+func (parser *TodoParser) applyTaskDelta(ctx context.Context, logger *slog.Logger, token string, list *ListDeltaState) error {
+	requestUrl := list.DeltaLink
+	if requestUrl == "" {
+		requestUrl = fmt.Sprintf("%s/%s/tasks/delta()?$top=%d", parser.listsUrl(), list.ID, parser.config.PageSize)
+	}
+
+	logger.DebugContext(ctx, "Request tasks delta", slog.String("taskListId", list.ID), slog.Bool("incremental", list.DeltaLink != ""))
+
+	entries, deltaLink, err := requestAllPagesWithDelta[json.RawMessage](ctx, logger, parser.http, token, requestUrl, parser.config.MaxPages, fmt.Sprintf("tasks delta '%s'", list.ID))
+	if err != nil {
+		return err
+	}
+
+	for _, raw := range entries {
+		entry := deltaEntry{}
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			return err
+		}
+		if entry.Removed != nil {
+			delete(list.Tasks, entry.ID)
+			continue
+		}
+
+		task := todo.Task{}
+		if err := json.Unmarshal(raw, &task); err != nil {
+			return err
+		}
+		// Only notStarted counts as open, matching the $filter of the full fetch.
+		delete(list.Tasks, task.ID)
+		delete(list.CompletedTasks, task.ID)
+		switch task.Status {
+		case todo.StatusNotStarted:
+			list.Tasks[task.ID] = task
+		case todo.StatusCompleted:
+			list.CompletedTasks[task.ID] = task
+		}
+	}
+
+	list.DeltaLink = deltaLink
+	return nil
+}
+
+// isSyncStateLost reports whether Graph rejected a delta link and a full
+// resync is required.
+func isSyncStateLost(err error) bool {
+	var respErr *httpclient.ResponseError
+	if !errors.As(err, &respErr) {
+		return false
+	}
+	return respErr.Code == httpclient.SyncStateNotFoundCode || respErr.Code == httpclient.ResyncRequiredCode
+}
@@ -0,0 +1,71 @@
+package todoclient
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/uchr/ToDoInfo/internal/todo"
+)
+
+// Well-known list types reported by Graph in wellknownListName.
+const (
+	// WellknownNone marks the lists the user created.
+	WellknownNone          = "none"
+	WellknownDefaultList   = "defaultList"
+	WellknownFlaggedEmails = "flaggedEmails"
+)
+
+// wellknownAliases maps the names accepted in the configuration to the Graph values.
+var wellknownAliases = map[string]string{
+	"none":          WellknownNone,
+	"custom":        WellknownNone,
+	"defaultlist":   WellknownDefaultList,
+	"tasks":         WellknownDefaultList,
+	"flaggedemails": WellknownFlaggedEmails,
+	"flagged":       WellknownFlaggedEmails,
+}
+
+// ParseWellknownList accepts a Graph well-known list name, case-insensitively,
+// or one of the aliases custom, tasks and flagged.
+func ParseWellknownList(name string) (string, error) {
+	if wellknown, ok := wellknownAliases[strings.ToLower(strings.TrimSpace(name))]; ok {
+		return wellknown, nil
+	}
+	return "", fmt.Errorf("unknown list type %q (want custom, tasks or flagged)", name)
+}
+
+// ListFilter selects the lists that are fetched. A list is fetched when its
+// well-known type is selected or its name matches an Include glob, unless its
+// name matches an Exclude glob.
+type ListFilter struct {
+	// Wellknown holds the selected wellknownListName values.
+	Wellknown []string
+	Include   []string
+	Exclude   []string
+}
+
+// DefaultListFilter fetches the user-created lists only.
+func DefaultListFilter() ListFilter {
+	return ListFilter{Wellknown: []string{WellknownNone}}
+}
+
+// Allows reports whether the list is fetched.
+func (f ListFilter) Allows(name, wellknown string) bool {
+	for _, glob := range f.Exclude {
+		if todo.MatchName(glob, name) {
+			return false
+		}
+	}
+	if slices.ContainsFunc(f.Wellknown, func(w string) bool { return strings.EqualFold(w, wellknown) }) {
+		return true
+	}
+	return slices.ContainsFunc(f.Include, func(glob string) bool { return todo.MatchName(glob, name) })
+}
+
+// ExcludedList is a list the filter left out.
+type ExcludedList struct {
+	ID                string
+	Name              string
+	WellknownListName string
+}
@@ -0,0 +1,55 @@
+package todoclient
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWellknownList(t *testing.T) {
+	for input, expected := range map[string]string{
+		"custom":        WellknownNone,
+		"none":          WellknownNone,
+		"Tasks":         WellknownDefaultList,
+		"defaultList":   WellknownDefaultList,
+		"flagged":       WellknownFlaggedEmails,
+		"FLAGGEDEMAILS": WellknownFlaggedEmails,
+	} {
+		got, err := ParseWellknownList(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, expected, got, input)
+	}
+
+	_, err := ParseWellknownList("inbox")
+	assert.Error(t, err)
+}
+
+func TestListFilterAllows(t *testing.T) {
+	filter := ListFilter{
+		Wellknown: []string{WellknownNone},
+		Include:   []string{"Flagged*"},
+		Exclude:   []string{"Someday*", "Tasks"},
+	}
+
+	tests := []struct {
+		name      string
+		wellknown string
+		expected  bool
+	}{
+		{"Work", WellknownNone, true},
+		{"Someday/Maybe", WellknownNone, false},
+		{"Flagged email", WellknownFlaggedEmails, true},
+		{"Tasks", WellknownDefaultList, false},
+		{"Groceries", WellknownDefaultList, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, filter.Allows(tt.name, tt.wellknown))
+		})
+	}
+
+	assert.False(t, DefaultListFilter().Allows("Tasks", WellknownDefaultList))
+	assert.True(t, DefaultListFilter().Allows("Work", WellknownNone))
+}
@@ -0,0 +1,66 @@
+package todoclient
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/pkg/errors"
+
+	"github.com/uchr/ToDoInfo/internal/httpclient"
+)
+
+// pageResponse is the envelope Graph wraps around every collection page.
+type pageResponse[T any] struct {
+	Value     []T    `json:"value"`
+	NextLink  string `json:"@odata.nextLink"`
+	DeltaLink string `json:"@odata.deltaLink"`
+}
+
+// requestAllPages fetches requestUrl and follows @odata.nextLink until the
+// collection is exhausted. It fails rather than returning a truncated result
+// when more than maxPages pages would be needed.
+func requestAllPages[T any](ctx context.Context, logger *slog.Logger, client *httpclient.Client, token string, requestUrl string, maxPages int, what string) ([]T, error) {
+	items, _, err := requestAllPagesWithDelta[T](ctx, logger, client, token, requestUrl, maxPages, what)
+	return items, err
+}
+
+// requestAllPagesWithDelta is requestAllPages for delta queries: it also
+// returns the @odata.deltaLink found on the last page.
+func requestAllPagesWithDelta[T any](ctx context.Context, logger *slog.Logger, client *httpclient.Client, token string, requestUrl string, maxPages int, what string) ([]T, string, error) {
+	var items []T
+	var deltaLink string
+	for page := 1; requestUrl != ""; page++ {
+		if page > maxPages {
+			return nil, "", errors.Errorf("request %s error. page limit %d reached", what, maxPages)
+		}
+
+		responseBody, err := client.Get(ctx, logger, requestUrl, token)
+		if err != nil {
+			return nil, "", errors.Wrapf(err, "request %s error", what)
+		}
+
+		err = httpclient.GetResponseError(responseBody)
+		if err != nil {
+			return nil, "", errors.Wrapf(err, "request %s error", what)
+		}
+
+		resp := pageResponse[T]{}
+		err = json.Unmarshal(responseBody, &resp)
+		if err != nil {
+			return nil, "", err
+		}
+
+		items = append(items, resp.Value...)
+		logger.DebugContext(ctx, "Fetched page",
+			slog.String("what", what),
+			slog.Int("page", page),
+			slog.Int("pageItems", len(resp.Value)),
+			slog.Int("totalItems", len(items)))
+
+		requestUrl = resp.NextLink
+		deltaLink = resp.DeltaLink
+	}
+
+	return items, deltaLink, nil
+}
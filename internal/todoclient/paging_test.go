@@ -0,0 +1,67 @@
+package todoclient
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uchr/ToDoInfo/internal/httpclient"
+)
+
+type pagedItem struct {
+	ID int `json:"id"`
+}
+
+// newPagedServer serves pages of two items each, numbered from 1, with an
+// @odata.nextLink to the next page and a deltaLink on the last one. It
+// counts the pages requested in requests.
+func newPagedServer(t *testing.T, pages int, requests *int) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		page = max(page, 1)
+		link := fmt.Sprintf(`"@odata.nextLink": "%s/items?page=%d"`, server.URL, page+1)
+		if page == pages {
+			link = fmt.Sprintf(`"@odata.deltaLink": "%s/items/delta?token=%d"`, server.URL, page)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"value": [{"id": %d}, {"id": %d}], %s}`, 2*page-1, 2*page, link)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newPagingClient() *httpclient.Client {
+	limiter := httpclient.NewLimiter(httpclient.LimiterConfig{Rate: 1000, MinRate: 1000, MaxRate: 1000, Burst: 1000})
+	return httpclient.New(httpclient.DefaultConfig().WithLimiter(limiter))
+}
+
+func TestRequestAllPagesMergesEveryPage(t *testing.T) {
+	var requests int
+	server := newPagedServer(t, 3, &requests)
+
+	items, deltaLink, err := requestAllPagesWithDelta[pagedItem](t.Context(), testLogger, newPagingClient(), "token", server.URL+"/items", 3, "items")
+	require.NoError(t, err)
+
+	assert.Equal(t, []pagedItem{{1}, {2}, {3}, {4}, {5}, {6}}, items)
+	assert.Equal(t, server.URL+"/items/delta?token=3", deltaLink)
+	assert.Equal(t, 3, requests)
+}
+
+func TestRequestAllPagesStopsAtMaxPages(t *testing.T) {
+	var requests int
+	server := newPagedServer(t, 5, &requests)
+
+	items, err := requestAllPages[pagedItem](t.Context(), testLogger, newPagingClient(), "token", server.URL+"/items", 2, "items")
+	require.ErrorContains(t, err, "page limit 2 reached")
+
+	assert.Nil(t, items, "a truncated collection must not be returned")
+	assert.Equal(t, 2, requests)
+}
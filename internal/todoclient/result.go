@@ -0,0 +1,59 @@
+package todoclient
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/uchr/ToDoInfo/internal/todo"
+)
+
+// ListError reports a list whose tasks could not be fetched.
+type ListError struct {
+	ListID   string
+	ListName string
+	Err      error
+}
+
+func (e *ListError) Error() string {
+	return fmt.Sprintf("list '%s': %v", e.ListName, e.Err)
+}
+
+func (e *ListError) Unwrap() error {
+	return e.Err
+}
+
+// FetchResult holds the lists that were fetched and a report of the ones
+// that failed. A failed list is left out of TaskLists rather than failing
+// the whole fetch. Excluded lists the ListFilter skipped.
+type FetchResult struct {
+	TaskLists []todo.TaskList
+	Failed    []ListError
+	Excluded  []ExcludedList
+}
+
+// ExcludedNames returns the names of the excluded lists.
+func (r *FetchResult) ExcludedNames() []string {
+	var names []string
+	for _, l := range r.Excluded {
+		names = append(names, l.Name)
+	}
+	return names
+}
+
+// Partial reports whether any list is missing from TaskLists.
+func (r *FetchResult) Partial() bool {
+	return len(r.Failed) > 0
+}
+
+// sort orders lists and failures by name so results are stable between runs.
+func (r *FetchResult) sort() {
+	sort.Slice(r.TaskLists, func(i, j int) bool {
+		return r.TaskLists[i].Name < r.TaskLists[j].Name
+	})
+	sort.Slice(r.Failed, func(i, j int) bool {
+		return r.Failed[i].ListName < r.Failed[j].ListName
+	})
+	sort.Slice(r.Excluded, func(i, j int) bool {
+		return r.Excluded[i].Name < r.Excluded[j].Name
+	})
+}
@@ -0,0 +1,253 @@
+package todoclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/uchr/ToDoInfo/internal/todo"
+)
+
+// WriteRequest is a single change to send to Graph. Write operations are
+// built as requests first so callers can print them for a dry run or keep
+// them in a plan before anything is sent.
+type WriteRequest struct {
+	Method string
+	URL    string
+	// Body is marshalled to JSON; nil sends no body.
+	Body any
+}
+
+// String renders the request as the method, URL and indented JSON body.
+func (r WriteRequest) String() string {
+	if r.Body == nil {
+		return r.Method + " " + r.URL
+	}
+	body, err := json.MarshalIndent(r.Body, "  ", "  ")
+	if err != nil {
+		return fmt.Sprintf("%s %s\n  <%v>", r.Method, r.URL, err)
+	}
+	return fmt.Sprintf("%s %s\n  %s", r.Method, r.URL, body)
+}
+
+// taskPatch holds the task properties the write API changes. Unset fields
+// are left out so PATCH only touches what changed.
+type taskPatch struct {
+	Status      string                 `json:"status,omitempty"`
+	DueDateTime *todo.DateTimeTimeZone `json:"dueDateTime,omitempty"`
+	Categories  []string               `json:"categories,omitempty"`
+}
+
+// duePatch sets or, when DueDateTime is nil, clears the due date.
+type duePatch struct {
+	DueDateTime *todo.DateTimeTimeZone `json:"dueDateTime"`
+}
+
+// NewTask holds the writable properties of a task to create. Graph assigns
+// the ID and the created and modified times.
+type NewTask struct {
+	Title            string                 `json:"title"`
+	Body             *todo.TaskBody         `json:"body,omitempty"`
+	Importance       string                 `json:"importance,omitempty"`
+	Categories       []string               `json:"categories,omitempty"`
+	IsReminderOn     bool                   `json:"isReminderOn,omitempty"`
+	DueDateTime      *todo.DateTimeTimeZone `json:"dueDateTime,omitempty"`
+	StartDateTime    *todo.DateTimeTimeZone `json:"startDateTime,omitempty"`
+	ReminderDateTime *todo.DateTimeTimeZone `json:"reminderDateTime,omitempty"`
+	Recurrence       *todo.RecurrenceTask   `json:"recurrence,omitempty"`
+	ChecklistItems   []newChecklistItem     `json:"checklistItems,omitempty"`
+	LinkedResources  []newLinkedResource    `json:"linkedResources,omitempty"`
+}
+
+type newChecklistItem struct {
+	DisplayName string `json:"displayName"`
+	IsChecked   bool   `json:"isChecked"`
+}
+
+type newLinkedResource struct {
+	WebURL          string `json:"webUrl,omitempty"`
+	ApplicationName string `json:"applicationName"`
+	DisplayName     string `json:"displayName"`
+	ExternalID      string `json:"externalId,omitempty"`
+}
+
+// NewTaskFrom copies the writable properties of task, including checklist
+// items and linked resources.
+func NewTaskFrom(task todo.Task) NewTask {
+	created := NewTask{
+		Title:            task.Title,
+		Importance:       task.Importance,
+		Categories:       task.Categories,
+		IsReminderOn:     task.IsReminderOn,
+		DueDateTime:      task.DueDateTime,
+		StartDateTime:    task.StartDateTime,
+		ReminderDateTime: task.ReminderDateTime,
+		Recurrence:       task.Recurrence,
+	}
+	if task.Body.Content != "" {
+		body := task.Body
+		created.Body = &body
+	}
+	for _, item := range task.ChecklistItems {
+		created.ChecklistItems = append(created.ChecklistItems, newChecklistItem{DisplayName: item.DisplayName, IsChecked: item.IsChecked})
+	}
+	for _, resource := range task.LinkedResources {
+		created.LinkedResources = append(created.LinkedResources, newLinkedResource{
+			WebURL:          resource.WebURL,
+			ApplicationName: resource.ApplicationName,
+			DisplayName:     resource.DisplayName,
+			ExternalID:      resource.ExternalID,
+		})
+	}
+	return created
+}
+
+func (parser *TodoParser) taskUrl(listID, taskID string) string {
+	return fmt.Sprintf("%s/%s/tasks/%s", parser.listsUrl(), listID, taskID)
+}
+
+// CompleteRequest marks a task completed.
+func (parser *TodoParser) CompleteRequest(listID, taskID string) WriteRequest {
+	return WriteRequest{
+		Method: http.MethodPatch,
+		URL:    parser.taskUrl(listID, taskID),
+		Body:   taskPatch{Status: todo.StatusCompleted},
+	}
+}
+
+// ReopenRequest marks a completed task not started again.
+func (parser *TodoParser) ReopenRequest(listID, taskID string) WriteRequest {
+	return WriteRequest{
+		Method: http.MethodPatch,
+		URL:    parser.taskUrl(listID, taskID),
+		Body:   taskPatch{Status: todo.StatusNotStarted},
+	}
+}
+
+// DueRequest sets the due date of a task; nil removes it.
+func (parser *TodoParser) DueRequest(listID, taskID string, due *todo.DateTimeTimeZone) WriteRequest {
+	return WriteRequest{
+		Method: http.MethodPatch,
+		URL:    parser.taskUrl(listID, taskID),
+		Body:   duePatch{DueDateTime: due},
+	}
+}
+
+// SnoozeRequest makes task due days after the later of its due date and
+// today, with today taken from now, so an overdue task ends up in the
+// future. A task without a due date is snoozed from today.
+func (parser *TodoParser) SnoozeRequest(listID string, task todo.Task, days int, now time.Time) WriteRequest {
+	due := todo.DateTimeTimeZone{Time: startOfDay(now, time.UTC), TimeZone: "UTC"}
+	if task.DueDateTime != nil {
+		due = *task.DueDateTime
+		// Today in the due date's own zone, to keep its time zone.
+		if today := startOfDay(now, due.Time.Location()); due.Time.Before(today) {
+			due.Time = today
+		}
+	}
+	due.Time = due.Time.AddDate(0, 0, days)
+	return WriteRequest{
+		Method: http.MethodPatch,
+		URL:    parser.taskUrl(listID, task.ID),
+		Body:   taskPatch{DueDateTime: &due},
+	}
+}
+
+// startOfDay returns midnight of the day t falls on in loc.
+func startOfDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// TagRequest adds categories to task, keeping the ones it already has.
+// Categories are compared ignoring case.
+func (parser *TodoParser) TagRequest(listID string, task todo.Task, categories []string) WriteRequest {
+	merged := append([]string(nil), task.Categories...)
+	for _, category := range categories {
+		found := false
+		for _, existing := range merged {
+			if strings.EqualFold(existing, category) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, category)
+		}
+	}
+	return WriteRequest{
+		Method: http.MethodPatch,
+		URL:    parser.taskUrl(listID, task.ID),
+		Body:   taskPatch{Categories: merged},
+	}
+}
+
+// CreateRequest creates task in listID.
+func (parser *TodoParser) CreateRequest(listID string, task NewTask) WriteRequest {
+	return WriteRequest{
+		Method: http.MethodPost,
+		URL:    fmt.Sprintf("%s/%s/tasks", parser.listsUrl(), listID),
+		Body:   task,
+	}
+}
+
+// DeleteRequest deletes a task.
+func (parser *TodoParser) DeleteRequest(listID, taskID string) WriteRequest {
+	return WriteRequest{Method: http.MethodDelete, URL: parser.taskUrl(listID, taskID)}
+}
+
+// MoveRequests moves task to another list. Graph can't move tasks, so it is
+// recreated in the target list and then deleted; the copy gets a new ID and
+// creation time.
+func (parser *TodoParser) MoveRequests(fromListID string, task todo.Task, toListID string) []WriteRequest {
+	return []WriteRequest{
+		parser.CreateRequest(toListID, NewTaskFrom(task)),
+		parser.DeleteRequest(fromListID, task.ID),
+	}
+}
+
+// Apply sends requests in order and stops at the first failure, so a move
+// never deletes a task whose copy wasn't created. It returns the response
+// bodies of the requests that succeeded. A create whose response was lost
+// isn't resent, so it may fail even though Graph created the task.
+func (parser *TodoParser) Apply(ctx context.Context, logger *slog.Logger, token string, requests ...WriteRequest) ([][]byte, error) {
+	var responses [][]byte
+	for _, request := range requests {
+		var body []byte
+		if request.Body != nil {
+			var err error
+			body, err = json.Marshal(request.Body)
+			if err != nil {
+				return responses, errors.Wrapf(err, "marshal %s %s", request.Method, request.URL)
+			}
+		}
+
+		logger.DebugContext(ctx, "Send write request", slog.String("method", request.Method), slog.String("url", request.URL))
+		response, err := parser.http.Send(ctx, logger, request.Method, request.URL, token, body)
+		if err != nil {
+			return responses, errors.Wrapf(err, "%s %s error", request.Method, request.URL)
+		}
+		responses = append(responses, response)
+	}
+	return responses, nil
+}
+
+// CreateTask creates task in listID and returns it as Graph stored it.
+func (parser *TodoParser) CreateTask(ctx context.Context, logger *slog.Logger, token string, listID string, task NewTask) (todo.Task, error) {
+	responses, err := parser.Apply(ctx, logger, token, parser.CreateRequest(listID, task))
+	if err != nil {
+		return todo.Task{}, err
+	}
+
+	created := todo.Task{}
+	if err := json.Unmarshal(responses[0], &created); err != nil {
+		return todo.Task{}, errors.Wrap(err, "parse created task")
+	}
+	return created, nil
+}
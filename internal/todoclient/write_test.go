@@ -0,0 +1,178 @@
+package todoclient
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uchr/ToDoInfo/internal/graphfake"
+	"github.com/uchr/ToDoInfo/internal/todo"
+)
+
+func TestCompleteRequest(t *testing.T) {
+	server := graphfake.New(t, "basic")
+	parser := newTestParser(server)
+
+	_, err := parser.Apply(t.Context(), testLogger, graphfake.Token, parser.CompleteRequest("work", "w-1"))
+	require.NoError(t, err)
+
+	task, ok := server.Task("work", "w-1")
+	require.True(t, ok)
+	assert.Equal(t, todo.StatusCompleted, task.Status)
+}
+
+func TestSnoozeRequest(t *testing.T) {
+	server := graphfake.New(t, "basic")
+	parser := newTestParser(server)
+	now := time.Date(2026, 10, 14, 18, 30, 0, 0, time.UTC)
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	due := &todo.DateTimeTimeZone{Time: time.Date(2026, 10, 20, 0, 0, 0, 0, berlin), TimeZone: "W. Europe Standard Time"}
+	server.PutTask("home", todo.Task{ID: "h-due", Status: todo.StatusNotStarted, Title: "Renew passport", DueDateTime: due})
+	overdue := &todo.DateTimeTimeZone{Time: time.Date(2026, 10, 1, 0, 0, 0, 0, berlin), TimeZone: "W. Europe Standard Time"}
+	server.PutTask("home", todo.Task{ID: "h-overdue", Status: todo.StatusNotStarted, Title: "Call the bank", DueDateTime: overdue})
+	withDue, _ := server.Task("home", "h-due")
+	withOverdue, _ := server.Task("home", "h-overdue")
+	withoutDue, _ := server.Task("home", "h-1")
+
+	_, err = parser.Apply(t.Context(), testLogger, graphfake.Token,
+		parser.SnoozeRequest("home", withDue, 3, now),
+		parser.SnoozeRequest("home", withOverdue, 3, now),
+		parser.SnoozeRequest("home", withoutDue, 7, now))
+	require.NoError(t, err)
+
+	snoozed, _ := server.Task("home", "h-due")
+	assert.Equal(t, "W. Europe Standard Time", snoozed.DueDateTime.TimeZone)
+	assert.True(t, time.Date(2026, 10, 23, 0, 0, 0, 0, berlin).Equal(snoozed.DueDateTime.Time))
+
+	snoozed, _ = server.Task("home", "h-overdue")
+	assert.True(t, time.Date(2026, 10, 17, 0, 0, 0, 0, berlin).Equal(snoozed.DueDateTime.Time), "an overdue task is snoozed from today")
+
+	snoozed, _ = server.Task("home", "h-1")
+	assert.True(t, time.Date(2026, 10, 21, 0, 0, 0, 0, time.UTC).Equal(snoozed.DueDateTime.Time))
+}
+
+func TestDueRequestClearsDueDate(t *testing.T) {
+	server := graphfake.New(t, "basic")
+	parser := newTestParser(server)
+	task, _ := server.Task("home", "h-1")
+
+	_, err := parser.Apply(t.Context(), testLogger, graphfake.Token, parser.SnoozeRequest("home", task, 3, time.Now()))
+	require.NoError(t, err)
+	snoozed, _ := server.Task("home", "h-1")
+	require.NotNil(t, snoozed.DueDateTime)
+
+	_, err = parser.Apply(t.Context(), testLogger, graphfake.Token, parser.DueRequest("home", "h-1", nil))
+	require.NoError(t, err)
+	cleared, _ := server.Task("home", "h-1")
+	assert.Nil(t, cleared.DueDateTime)
+}
+
+func TestTagRequestKeepsExistingCategories(t *testing.T) {
+	parser := New(nil)
+	task := todo.Task{ID: "t", Categories: []string{"Work", "Urgent"}}
+
+	request := parser.TagRequest("list", task, []string{"urgent", "Errand"})
+
+	assert.Equal(t, http.MethodPatch, request.Method)
+	assert.Equal(t, []string{"Work", "Urgent", "Errand"}, request.Body.(taskPatch).Categories)
+}
+
+func TestMoveRequests(t *testing.T) {
+	server := graphfake.New(t, "basic")
+	parser := newTestParser(server)
+	server.PutTask("work", todo.Task{
+		ID:             "w-move",
+		Status:         todo.StatusNotStarted,
+		Title:          "Call the landlord",
+		Importance:     todo.ImportanceHigh,
+		Categories:     []string{"Calls"},
+		Body:           todo.TaskBody{Content: "About the heating", ContentType: "text"},
+		ChecklistItems: []todo.ChecklistItem{{ID: "c-1", DisplayName: "Find the contract", IsChecked: true}},
+	})
+	task, _ := server.Task("work", "w-move")
+
+	requests := parser.MoveRequests("work", task, "home")
+	require.Len(t, requests, 2)
+	assert.Equal(t, http.MethodPost, requests[0].Method)
+	assert.Equal(t, http.MethodDelete, requests[1].Method)
+
+	_, err := parser.Apply(t.Context(), testLogger, graphfake.Token, requests...)
+	require.NoError(t, err)
+
+	_, ok := server.Task("work", "w-move")
+	assert.False(t, ok, "the original is deleted")
+
+	home := server.Tasks("home")
+	moved := home[len(home)-1]
+	assert.Equal(t, "Call the landlord", moved.Title)
+	assert.Equal(t, todo.ImportanceHigh, moved.Importance)
+	assert.Equal(t, []string{"Calls"}, moved.Categories)
+	assert.Equal(t, "About the heating", moved.Body.Content)
+	require.Len(t, moved.ChecklistItems, 1)
+	assert.Equal(t, "Find the contract", moved.ChecklistItems[0].DisplayName)
+	assert.True(t, moved.ChecklistItems[0].IsChecked)
+}
+
+func TestApplyStopsAtFirstFailure(t *testing.T) {
+	server := graphfake.New(t, "basic")
+	server.FailList("home", http.StatusForbidden)
+	parser := newTestParser(server)
+	task, _ := server.Task("work", "w-1")
+
+	responses, err := parser.Apply(t.Context(), testLogger, graphfake.Token, parser.MoveRequests("work", task, "home")...)
+	assert.ErrorContains(t, err, "POST")
+	assert.Empty(t, responses)
+
+	_, ok := server.Task("work", "w-1")
+	assert.True(t, ok, "the original is kept when the copy fails")
+}
+
+func TestCreateTask(t *testing.T) {
+	server := graphfake.New(t, "basic")
+	parser := newTestParser(server)
+
+	created, err := parser.CreateTask(t.Context(), testLogger, graphfake.Token, "home", NewTask{Title: "Water the plants"})
+	require.NoError(t, err)
+
+	assert.NotEmpty(t, created.ID)
+	assert.Equal(t, "Water the plants", created.Title)
+	stored, ok := server.Task("home", created.ID)
+	require.True(t, ok)
+	assert.Equal(t, todo.StatusNotStarted, stored.Status)
+}
+
+func TestCreateTaskIsNotResentAfterLostResponse(t *testing.T) {
+	server := graphfake.New(t, "basic")
+	parser := newTestParser(server)
+	before := len(server.Tasks("home"))
+
+	server.DropResponses(1)
+	_, err := parser.CreateTask(t.Context(), testLogger, graphfake.Token, "home", NewTask{Title: "Water the plants"})
+	require.Error(t, err)
+
+	var created []todo.Task
+	for _, task := range server.Tasks("home") {
+		if task.Title == "Water the plants" {
+			created = append(created, task)
+		}
+	}
+	assert.Len(t, created, 1)
+	assert.Len(t, server.Tasks("home"), before+1)
+}
+
+func TestDeleteSucceedsWhenRetryFindsTaskGone(t *testing.T) {
+	server := graphfake.New(t, "basic")
+	parser := newTestParser(server)
+
+	server.DropResponses(1)
+	_, err := parser.Apply(t.Context(), testLogger, graphfake.Token, parser.DeleteRequest("home", "h-1"))
+	require.NoError(t, err)
+
+	_, ok := server.Task("home", "h-1")
+	assert.False(t, ok)
+}
@@ -0,0 +1,141 @@
+package todometrics
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const dateKeyLayout = "2006-01-02"
+
+// Holiday is a non-working calendar date.
+type Holiday struct {
+	Date time.Time
+	Name string
+}
+
+type vacation struct {
+	from, to string
+}
+
+// WorkCalendar describes which days count towards task age in business-day
+// mode. Tasks don't age on weekends, holidays or vacation days.
+type WorkCalendar struct {
+	weekend   map[time.Weekday]bool
+	holidays  map[string]string
+	vacations []vacation
+}
+
+// NewWorkCalendar returns a calendar with a Saturday/Sunday weekend and no holidays.
+func NewWorkCalendar() *WorkCalendar {
+	return &WorkCalendar{
+		weekend:  map[time.Weekday]bool{time.Saturday: true, time.Sunday: true},
+		holidays: make(map[string]string),
+	}
+}
+
+// WithWeekend replaces the weekend days. An empty list keeps the default.
+func (c *WorkCalendar) WithWeekend(days []time.Weekday) *WorkCalendar {
+	if len(days) == 0 {
+		return c
+	}
+	c.weekend = make(map[time.Weekday]bool, len(days))
+	for _, day := range days {
+		c.weekend[day] = true
+	}
+	return c
+}
+
+// WithHolidays adds holidays. Only the calendar date of each entry is used.
+func (c *WorkCalendar) WithHolidays(holidays []Holiday) *WorkCalendar {
+	for _, h := range holidays {
+		c.holidays[h.Date.Format(dateKeyLayout)] = h.Name
+	}
+	return c
+}
+
+// WithVacation pauses ageing from one calendar date to another, inclusive.
+func (c *WorkCalendar) WithVacation(from, to time.Time) *WorkCalendar {
+	c.vacations = append(c.vacations, vacation{from: from.Format(dateKeyLayout), to: to.Format(dateKeyLayout)})
+	return c
+}
+
+// String describes the calendar for display next to ages.
+func (c *WorkCalendar) String() string {
+	weekend := make([]string, 0, len(c.weekend))
+	// List Monday first so the usual weekend reads "Sat/Sun".
+	for i := 1; i <= 7; i++ {
+		if day := time.Weekday(i % 7); c.weekend[day] {
+			weekend = append(weekend, day.String()[:3])
+		}
+	}
+
+	parts := []string{"weekend " + strings.Join(weekend, "/")}
+	switch n := len(c.holidays); n {
+	case 0:
+	case 1:
+		parts = append(parts, "1 holiday")
+	default:
+		parts = append(parts, fmt.Sprintf("%d holidays", n))
+	}
+	for _, v := range c.vacations {
+		parts = append(parts, fmt.Sprintf("vacation %s – %s", v.from, v.to))
+	}
+	return fmt.Sprintf("business days (%s)", strings.Join(parts, ", "))
+}
+
+// IsWorkingDay reports whether the calendar date of day counts towards age.
+func (c *WorkCalendar) IsWorkingDay(day time.Time) bool {
+	if c.weekend[day.Weekday()] {
+		return false
+	}
+	key := day.Format(dateKeyLayout)
+	if _, ok := c.holidays[key]; ok {
+		return false
+	}
+	for _, v := range c.vacations {
+		if key >= v.from && key <= v.to {
+			return false
+		}
+	}
+	return true
+}
+
+// workingDuration returns how much of [from, to) falls on working days,
+// splitting days at midnight in the location of to.
+func (c *WorkCalendar) workingDuration(from, to time.Time) time.Duration {
+	if !to.After(from) {
+		return 0
+	}
+
+	from = from.In(to.Location())
+	var total time.Duration
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, to.Location())
+	for day.Before(to) {
+		next := day.AddDate(0, 0, 1)
+		if c.IsWorkingDay(day) {
+			start, end := day, next
+			if from.After(start) {
+				start = from
+			}
+			if to.Before(end) {
+				end = to
+			}
+			total += end.Sub(start)
+		}
+		day = next
+	}
+	return total
+}
+
+// ParseWeekday parses an English weekday name or its three-letter abbreviation.
+func ParseWeekday(name string) (time.Weekday, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		full := strings.ToLower(day.String())
+		if name == full || name == full[:3] {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown weekday %q", name)
+}
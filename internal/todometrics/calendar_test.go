@@ -0,0 +1,121 @@
+package todometrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkingDuration(t *testing.T) {
+	// 2026-10-16 is a Friday.
+	friday := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		calendar *WorkCalendar
+		from     time.Time
+		to       time.Time
+		expected time.Duration
+	}{
+		{
+			name:     "Friday to Monday skips the weekend",
+			calendar: NewWorkCalendar(),
+			from:     friday,
+			to:       friday.AddDate(0, 0, 3),
+			expected: 24 * time.Hour,
+		},
+		{
+			name:     "Within one working day",
+			calendar: NewWorkCalendar(),
+			from:     friday,
+			to:       friday.Add(5 * time.Hour),
+			expected: 5 * time.Hour,
+		},
+		{
+			name:     "Friday/Saturday weekend",
+			calendar: NewWorkCalendar().WithWeekend([]time.Weekday{time.Friday, time.Saturday}),
+			from:     friday,
+			to:       friday.AddDate(0, 0, 3),
+			expected: 34 * time.Hour,
+		},
+		{
+			name:     "Holiday on Monday",
+			calendar: NewWorkCalendar().WithHolidays([]Holiday{{Date: time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC), Name: "Bridge day"}}),
+			from:     friday,
+			to:       friday.AddDate(0, 0, 4),
+			expected: 24 * time.Hour,
+		},
+		{
+			name: "Vacation pauses ageing",
+			calendar: NewWorkCalendar().WithVacation(
+				time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+				time.Date(2026, 10, 23, 0, 0, 0, 0, time.UTC),
+			),
+			from:     friday,
+			to:       friday.AddDate(0, 0, 10),
+			expected: 24 * time.Hour,
+		},
+		{
+			name:     "Reversed range",
+			calendar: NewWorkCalendar(),
+			from:     friday,
+			to:       friday.Add(-time.Hour),
+			expected: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.calendar.workingDuration(tt.from, tt.to))
+		})
+	}
+}
+
+func TestParseICSHolidays(t *testing.T) {
+	ics := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT",
+		"DTSTART;VALUE=DATE:20261224",
+		"DTEND;VALUE=DATE:20261227",
+		"SUMMARY:Christmas ",
+		" break",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART;TZID=Europe/Berlin:20261003T000000",
+		"SUMMARY:Unity Day",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	holidays, err := ParseICSHolidays(strings.NewReader(ics))
+	require.NoError(t, err)
+	require.Len(t, holidays, 4)
+
+	dates := make([]string, 0, len(holidays))
+	for _, h := range holidays {
+		dates = append(dates, h.Date.Format(dateKeyLayout))
+	}
+	assert.Equal(t, []string{"2026-12-24", "2026-12-25", "2026-12-26", "2026-10-03"}, dates)
+	assert.Equal(t, "Christmas break", holidays[0].Name)
+
+	calendar := NewWorkCalendar().WithHolidays(holidays)
+	assert.False(t, calendar.IsWorkingDay(time.Date(2026, 12, 24, 12, 0, 0, 0, time.UTC)))
+	assert.True(t, calendar.IsWorkingDay(time.Date(2026, 12, 28, 12, 0, 0, 0, time.UTC)))
+	assert.Equal(t, "business days (weekend Sat/Sun, 4 holidays)", calendar.String())
+}
+
+func TestParseWeekday(t *testing.T) {
+	day, err := ParseWeekday("Fri")
+	require.NoError(t, err)
+	assert.Equal(t, time.Friday, day)
+
+	day, err = ParseWeekday("saturday")
+	require.NoError(t, err)
+	assert.Equal(t, time.Saturday, day)
+
+	_, err = ParseWeekday("someday")
+	assert.Error(t, err)
+}
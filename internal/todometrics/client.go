@@ -4,47 +4,95 @@ import (
 	"sort"
 	"time"
 
+	"github.com/uchr/ToDoInfo/internal/clock"
 	"github.com/uchr/ToDoInfo/internal/todo"
 )
 
+// String returns the emoji of r in the default ladder. Use
+// RottennessPolicy.Level when a custom policy may be active.
 func (r TaskRottenness) String() string {
-	switch r {
-	case ZombieTaskRottenness:
-		return "🤢"
-	case TiredTaskRottenness:
-		return "🥱"
-	case RipeTaskRottenness:
-		return "😏"
-	case FreshTaskRottenness:
-		return "😊"
+	return DefaultRottennessPolicy().Level(r).Emoji
+}
+
+func New(taskLists []todo.TaskList, opts ...Option) *Metrics {
+	m := &Metrics{policy: DefaultRottennessPolicy(), clock: clock.Real(), recurrence: RecurrenceInclude}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	// Ages are taken at a single instant so all metrics agree with each other.
+	m.now = m.clock.Now()
+	m.lists = filterTasks(taskLists)
+	if m.recurrence != RecurrenceInclude {
+		var recurring []todo.TaskList
+		m.lists, recurring = splitRecurring(m.lists)
+		if m.recurrence == RecurrenceSeparate {
+			m.recurringTasks = getSortedTasks(recurring, m.now, m.policy, m.calendar)
+		}
 	}
-	return "❓"
+	m.sortedTasks = getSortedTasks(m.lists, m.now, m.policy, m.calendar)
+	return m
+}
+
+// RecurrenceMode returns how recurring tasks are treated.
+func (l *Metrics) RecurrenceMode() RecurrenceMode {
+	return l.recurrence
+}
+
+// GetRecurringTasks returns the recurring tasks, oldest first, when they are
+// reported separately. It is empty in the other recurrence modes.
+func (l *Metrics) GetRecurringTasks() []TaskRottennessInfo {
+	return l.recurringTasks
+}
+
+// Now returns the instant the metrics were evaluated at.
+func (l *Metrics) Now() time.Time {
+	return l.now
 }
 
-func New(taskLists []todo.TaskList) *Metrics {
-	filteredTasks := filterTasks(taskLists)
+// AgeMode describes how task ages are counted, for display next to them.
+func (l *Metrics) AgeMode() string {
+	if l.calendar == nil {
+		return "calendar days"
+	}
+	return l.calendar.String()
+}
 
-	return &Metrics{lists: filteredTasks, sortedTasks: getSortedTasks(filteredTasks)}
+// Policy returns the rottenness ladder the metrics were computed with.
+func (l *Metrics) Policy() RottennessPolicy {
+	return l.policy
 }
 
+// GetListAges sums task ages per list. Tasks excluded by a policy override
+// still count towards their list but not towards TotalAge. A list is
+// Excluded when overrides exclude all of its tasks; an empty list is Excluded
+// when a list override does.
 func (l *Metrics) GetListAges() ListAges {
 	sum := 0
 	ages := make(map[string]int)
 	taskCount := make(map[string]int)
+	excluded := make(map[string]bool)
 
 	for _, taskList := range l.lists {
 		ages[taskList.Name] = 0
 		taskCount[taskList.Name] = len(taskList.Tasks)
+		_, excluded[taskList.Name] = l.policy.resolve(taskList, todo.Task{})
+		if len(taskList.Tasks) > 0 {
+			excluded[taskList.Name] = true
+		}
 		for _, task := range taskList.Tasks {
-			age, _ := getTaskAge(task)
-			sum += age
+			age, _ := getTaskAge(task, l.now, l.calendar)
+			if _, skip := l.policy.resolve(taskList, task); !skip {
+				sum += age
+				excluded[taskList.Name] = false
+			}
 			ages[taskList.Name] += age
 		}
 	}
 
 	listAges := ListAges{TotalAge: sum}
 	for listName, listAge := range ages {
-		listAges.Ages = append(listAges.Ages, ListAge{Title: listName, Age: listAge, TaskCount: taskCount[listName]})
+		listAges.Ages = append(listAges.Ages, ListAge{Title: listName, Age: listAge, TaskCount: taskCount[listName], Excluded: excluded[listName]})
 	}
 
 	sort.Slice(listAges.Ages, func(i, j int) bool {
@@ -80,35 +128,41 @@ func (l *Metrics) GetOldestTaskForList() map[string]TaskRottennessInfo {
 		var maxExactAge time.Duration
 		taskIndex := 0
 		for i, task := range taskList.Tasks {
-			_, exactAge := getTaskAge(task)
+			_, exactAge := getTaskAge(task, l.now, l.calendar)
 			if exactAge >= maxExactAge {
 				maxExactAge = exactAge
 				taskIndex = i
 			}
 		}
-		taskAge, exactAge := getTaskAge(taskList.Tasks[taskIndex])
+		taskAge, exactAge := getTaskAge(taskList.Tasks[taskIndex], l.now, l.calendar)
+		taskPolicy, excluded := l.policy.resolve(taskList, taskList.Tasks[taskIndex])
 		result[taskList.Name] = TaskRottennessInfo{
-			TaskName:   taskList.Tasks[taskIndex].Title,
-			TaskList:   taskList.Name,
-			Age:        taskAge,
-			Rottenness: getTaskRottenness(taskAge),
-			exactAge:   exactAge,
+			TaskID:            taskList.Tasks[taskIndex].ID,
+			ListID:            taskList.ID,
+			TaskName:          taskList.Tasks[taskIndex].Title,
+			TaskList:          taskList.Name,
+			Age:               taskAge,
+			Rottenness:        taskPolicy.Rottenness(taskAge),
+			ExcludedFromTotal: excluded,
+			Recurring:         taskList.Tasks[taskIndex].Recurrence != nil,
+			exactAge:          exactAge,
 		}
 	}
 	return result
 }
 
+// GetRottenTasks returns the tasks at minLevel or rottener, oldest first.
 func (l *Metrics) GetRottenTasks(minLevel TaskRottenness) []TaskRottennessInfo {
-	if minLevel == FreshTaskRottenness {
+	if minLevel <= FreshTaskRottenness {
 		return l.sortedTasks
 	}
 
-	minAge := getRottennessAge(minLevel)
-	for i, task := range l.sortedTasks {
-		if task.Age <= minAge {
-			return l.sortedTasks[:i]
+	var result []TaskRottennessInfo
+	for _, task := range l.sortedTasks {
+		if task.Rottenness >= minLevel {
+			result = append(result, task)
 		}
 	}
 
-	return nil
+	return result
 }
@@ -0,0 +1,88 @@
+package todometrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ParseICSHolidays reads all-day and timed VEVENTs from an iCalendar file and
+// returns one Holiday per covered date. DTEND is exclusive, as in RFC 5545.
+// Recurrence rules are not expanded; holiday feeds usually list each year.
+func ParseICSHolidays(r io.Reader) ([]Holiday, error) {
+	lines, err := unfoldICSLines(r)
+	if err != nil {
+		return nil, fmt.Errorf("read ics: %w", err)
+	}
+
+	var holidays []Holiday
+	var inEvent bool
+	var summary string
+	var start, end time.Time
+	for _, line := range lines {
+		switch {
+		case line == "BEGIN:VEVENT":
+			inEvent = true
+			summary, start, end = "", time.Time{}, time.Time{}
+		case line == "END:VEVENT":
+			inEvent = false
+			if start.IsZero() {
+				continue
+			}
+			if !end.After(start) {
+				end = start.AddDate(0, 0, 1)
+			}
+			for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+				holidays = append(holidays, Holiday{Date: day, Name: summary})
+			}
+		case inEvent:
+			name, value, ok := strings.Cut(line, ":")
+			if !ok {
+				continue
+			}
+			name, _, _ = strings.Cut(name, ";")
+			switch strings.ToUpper(name) {
+			case "SUMMARY":
+				summary = value
+			case "DTSTART":
+				if start, err = parseICSDate(value); err != nil {
+					return nil, err
+				}
+			case "DTEND":
+				if end, err = parseICSDate(value); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return holidays, nil
+}
+
+// parseICSDate keeps only the date part of a DATE or DATE-TIME value.
+func parseICSDate(value string) (time.Time, error) {
+	if len(value) < 8 {
+		return time.Time{}, fmt.Errorf("parse ics date %q", value)
+	}
+	date, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse ics date %q: %w", value, err)
+	}
+	return date, nil
+}
+
+// unfoldICSLines joins continuation lines, which start with a space or tab.
+func unfoldICSLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
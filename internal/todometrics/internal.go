@@ -8,53 +8,47 @@ import (
 	"github.com/uchr/ToDoInfo/internal/todo"
 )
 
-func getRottennessAge(r TaskRottenness) int {
-	switch r {
-	case ZombieTaskRottenness:
-		return zombieTaskDay
-	case TiredTaskRottenness:
-		return tiredTaskDay
-	case RipeTaskRottenness:
-		return ripeTaskDay
-	}
-	return 0
-}
-
-func getTaskRottenness(age int) TaskRottenness {
-	if age <= ripeTaskDay {
-		return FreshTaskRottenness
-	} else if age <= tiredTaskDay {
-		return RipeTaskRottenness
-	} else if age <= zombieTaskDay {
-		return TiredTaskRottenness
-	}
-	return ZombieTaskRottenness
-}
-
-func getTaskAge(task todo.Task) (int, time.Duration) {
+// getTaskAge returns the age at now in whole days and the exact age. With a
+// calendar only time on working days counts. Recurring tasks age from their
+// most recently missed occurrence.
+func getTaskAge(task todo.Task, now time.Time, calendar *WorkCalendar) (int, time.Duration) {
 	taskTime := task.CreatedDateTime
 	if task.DueDateTime != nil {
-		taskTime = *task.DueDateTime
+		taskTime = task.DueDateTime.Time
+	}
+	if task.Recurrence != nil {
+		occurrence, ok := lastOccurrence(task, now)
+		if !ok {
+			return 0, 0
+		}
+		taskTime = occurrence
 	}
 
-	currentTime := time.Now()
-	delta := currentTime.Sub(taskTime)
+	delta := now.Sub(taskTime)
+	if calendar != nil {
+		delta = calendar.workingDuration(taskTime, now)
+	}
 	if delta <= 0 {
 		return 0, 0
 	}
 	return int(delta.Hours() / 24), delta
 }
 
-func getSortedTasks(taskLists []todo.TaskList) []TaskRottennessInfo {
+func getSortedTasks(taskLists []todo.TaskList, now time.Time, policy RottennessPolicy, calendar *WorkCalendar) []TaskRottennessInfo {
 	result := make([]TaskRottennessInfo, 0)
 	for _, taskList := range taskLists {
 		for _, task := range taskList.Tasks {
-			age, exactAge := getTaskAge(task)
+			age, exactAge := getTaskAge(task, now, calendar)
+			taskPolicy, excluded := policy.resolve(taskList, task)
 			result = append(result, TaskRottennessInfo{
-				TaskName:   task.Title,
-				TaskList:   taskList.Name,
-				Age:        age,
-				Rottenness: getTaskRottenness(age),
+				TaskID:            task.ID,
+				ListID:            taskList.ID,
+				TaskName:          task.Title,
+				TaskList:          taskList.Name,
+				Age:               age,
+				Rottenness:        taskPolicy.Rottenness(age),
+				ExcludedFromTotal: excluded,
+				Recurring:         task.Recurrence != nil,
 
 				exactAge: exactAge,
 			})
@@ -80,11 +74,21 @@ func filterTasks(taskLists []todo.TaskList) []todo.TaskList {
 			tasks = append(tasks, task)
 		}
 
+		var completedTasks []todo.Task
+		for _, task := range taskList.CompletedTasks {
+			if strings.Contains(task.Body.Content, "#todo-info-skip") {
+				continue
+			}
+			completedTasks = append(completedTasks, task)
+		}
+
 		filteredTaskList = append(filteredTaskList, todo.TaskList{
+			ID:                taskList.ID,
 			Name:              taskList.Name,
 			WellknownListName: taskList.WellknownListName,
 			IsShared:          taskList.IsShared,
 			Tasks:             tasks,
+			CompletedTasks:    completedTasks,
 		})
 	}
 
@@ -0,0 +1,36 @@
+package todometrics
+
+import "github.com/uchr/ToDoInfo/internal/clock"
+
+// Option configures Metrics.
+type Option func(*Metrics)
+
+// WithPolicy sets the rottenness ladder used to classify tasks.
+func WithPolicy(policy RottennessPolicy) Option {
+	return func(m *Metrics) {
+		m.policy = policy
+	}
+}
+
+// WithCalendar switches task age to business days counted by calendar.
+// A nil calendar keeps the default wall-clock age.
+func WithCalendar(calendar *WorkCalendar) Option {
+	return func(m *Metrics) {
+		m.calendar = calendar
+	}
+}
+
+// WithClock sets the clock ages are measured against. Metrics read it once,
+// in New, so passing a fixed clock evaluates them at that instant.
+func WithClock(c clock.Clock) Option {
+	return func(m *Metrics) {
+		m.clock = c
+	}
+}
+
+// WithRecurrenceMode sets how recurring tasks take part in the metrics.
+func WithRecurrenceMode(mode RecurrenceMode) Option {
+	return func(m *Metrics) {
+		m.recurrence = mode
+	}
+}
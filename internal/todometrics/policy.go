@@ -0,0 +1,191 @@
+package todometrics
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/uchr/ToDoInfo/internal/todo"
+)
+
+// RottennessLevel is one rung of a rottenness ladder.
+type RottennessLevel struct {
+	Name  string
+	Emoji string
+	// Days is the age in days a task must exceed to reach this level.
+	// It is ignored for the first level, which every task starts at.
+	Days int
+	// Color is an optional hex colour used by the CLI tables.
+	Color string
+}
+
+// PolicyOverride adjusts the ladder for matching lists or tasks. Every
+// criterion that is set must match; an override without criteria never matches.
+type PolicyOverride struct {
+	// List is a glob matched against the whole list name, ignoring case.
+	// "*" matches any run of characters, including "/", and "?" matches one.
+	List              string
+	WellknownListName string
+	// Category matches tasks carrying this Outlook category, ignoring case.
+	Category string
+	// Days replaces the thresholds of the named levels. Levels themselves
+	// can't be added or removed, so a TaskRottenness means the same everywhere.
+	Days map[string]int
+	// ExcludeFromTotal keeps matching tasks out of the total age.
+	ExcludeFromTotal bool
+}
+
+// RottennessPolicy is an ordered ladder of rottenness levels, freshest first.
+// A TaskRottenness value is an index into Levels. The first matching entry of
+// Overrides, if any, adjusts the ladder for a given task.
+type RottennessPolicy struct {
+	Levels    []RottennessLevel
+	Overrides []PolicyOverride
+}
+
+var defaultLevelColors = []string{"#50FA7B", "#F1FA8C", "#FFB86C", "#FF5555", "#BD93F9"}
+
+// DefaultRottennessPolicy returns the built-in Fresh/Ripe/Tired/Zombie ladder.
+func DefaultRottennessPolicy() RottennessPolicy {
+	return RottennessPolicy{
+		Levels: []RottennessLevel{
+			{Name: "Fresh", Emoji: "😊", Days: 0},
+			{Name: "Ripe", Emoji: "😏", Days: ripeTaskDay},
+			{Name: "Tired", Emoji: "🥱", Days: tiredTaskDay},
+			{Name: "Zombie", Emoji: "🤢", Days: zombieTaskDay},
+		},
+	}
+}
+
+// Validate checks that the ladder is non-empty, named and strictly ascending,
+// and that every override is well formed and keeps it ascending.
+func (p RottennessPolicy) Validate() error {
+	if err := validateLevels(p.Levels); err != nil {
+		return err
+	}
+
+	for i, o := range p.Overrides {
+		if o.List == "" && o.WellknownListName == "" && o.Category == "" {
+			return fmt.Errorf("rottenness override %d has no list, wellknownListName or category", i+1)
+		}
+		for name := range o.Days {
+			if _, ok := p.Find(name); !ok {
+				return fmt.Errorf("rottenness override %d: unknown level %q", i+1, name)
+			}
+		}
+		if err := validateLevels(p.applyOverride(o).Levels); err != nil {
+			return fmt.Errorf("rottenness override %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func validateLevels(levels []RottennessLevel) error {
+	if len(levels) == 0 {
+		return fmt.Errorf("rottenness policy needs at least one level")
+	}
+	for i, level := range levels {
+		if level.Name == "" {
+			return fmt.Errorf("rottenness level %d has no name", i+1)
+		}
+		if i > 0 && level.Days <= levels[i-1].Days {
+			return fmt.Errorf("rottenness level %q must start after %d days", level.Name, levels[i-1].Days)
+		}
+	}
+	return nil
+}
+
+// resolve returns the ladder that applies to task in list and whether the
+// task is excluded from the total age. The returned policy has no overrides.
+func (p RottennessPolicy) resolve(list todo.TaskList, task todo.Task) (RottennessPolicy, bool) {
+	for _, o := range p.Overrides {
+		if o.matches(list, task) {
+			return p.applyOverride(o), o.ExcludeFromTotal
+		}
+	}
+	return RottennessPolicy{Levels: p.Levels}, false
+}
+
+func (p RottennessPolicy) applyOverride(o PolicyOverride) RottennessPolicy {
+	levels := make([]RottennessLevel, len(p.Levels))
+	copy(levels, p.Levels)
+	for name, days := range o.Days {
+		if r, ok := p.Find(name); ok {
+			levels[r].Days = days
+		}
+	}
+	return RottennessPolicy{Levels: levels}
+}
+
+func (o PolicyOverride) matches(list todo.TaskList, task todo.Task) bool {
+	if o.List == "" && o.WellknownListName == "" && o.Category == "" {
+		return false
+	}
+	if o.List != "" && !todo.MatchName(o.List, list.Name) {
+		return false
+	}
+	if o.WellknownListName != "" && !strings.EqualFold(o.WellknownListName, list.WellknownListName) {
+		return false
+	}
+	if o.Category != "" {
+		found := false
+		for _, category := range task.Categories {
+			if strings.EqualFold(o.Category, category) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Rottenness returns the level reached by a task of the given age in days.
+func (p RottennessPolicy) Rottenness(age int) TaskRottenness {
+	result := FreshTaskRottenness
+	for i := 1; i < len(p.Levels); i++ {
+		if age > p.Levels[i].Days {
+			result = TaskRottenness(i)
+		}
+	}
+	return result
+}
+
+// Level returns the definition of r, or an unnamed "❓" level if r is out of range.
+func (p RottennessPolicy) Level(r TaskRottenness) RottennessLevel {
+	if int(r) < 0 || int(r) >= len(p.Levels) {
+		return RottennessLevel{Emoji: "❓"}
+	}
+	return p.Levels[r]
+}
+
+// LevelColor returns the configured colour of r, or one from the default palette.
+func (p RottennessPolicy) LevelColor(r TaskRottenness) string {
+	if color := p.Level(r).Color; color != "" {
+		return color
+	}
+	if int(r) < 0 {
+		return defaultLevelColors[0]
+	}
+	return defaultLevelColors[min(int(r), len(defaultLevelColors)-1)]
+}
+
+// Find returns the level with the given name, ignoring case.
+func (p RottennessPolicy) Find(name string) (TaskRottenness, bool) {
+	for i, level := range p.Levels {
+		if strings.EqualFold(level.Name, name) {
+			return TaskRottenness(i), true
+		}
+	}
+	return 0, false
+}
+
+// ZombieLevel returns the level named "Zombie", or the rottenest level when
+// the ladder has none. Tasks at or above it are reported as zombies.
+func (p RottennessPolicy) ZombieLevel() TaskRottenness {
+	if r, ok := p.Find("Zombie"); ok {
+		return r
+	}
+	return TaskRottenness(len(p.Levels) - 1)
+}
@@ -0,0 +1,228 @@
+package todometrics
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/uchr/ToDoInfo/internal/todo"
+)
+
+// RecurrenceMode controls how recurring tasks take part in the metrics.
+type RecurrenceMode string
+
+const (
+	// RecurrenceInclude ranks recurring tasks together with one-off tasks.
+	RecurrenceInclude RecurrenceMode = "include"
+	// RecurrenceSeparate keeps recurring tasks out of list ages and the task
+	// ranking and reports them through GetRecurringTasks instead.
+	RecurrenceSeparate RecurrenceMode = "separate"
+	// RecurrenceExclude drops recurring tasks entirely.
+	RecurrenceExclude RecurrenceMode = "exclude"
+)
+
+// maxRecurrencePeriods bounds the occurrence walk for very old anchors.
+const maxRecurrencePeriods = 100000
+
+// ParseRecurrenceMode validates a mode name; an empty name means include.
+func ParseRecurrenceMode(name string) (RecurrenceMode, error) {
+	switch mode := RecurrenceMode(strings.ToLower(name)); mode {
+	case "":
+		return RecurrenceInclude, nil
+	case RecurrenceInclude, RecurrenceSeparate, RecurrenceExclude:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown recurrence mode %q (use include, separate or exclude)", name)
+}
+
+// splitRecurring separates recurring tasks from one-off ones, keeping list metadata on both sides.
+func splitRecurring(taskLists []todo.TaskList) (oneOff, recurring []todo.TaskList) {
+	for _, taskList := range taskLists {
+		single := taskList
+		single.Tasks = nil
+		repeated := taskList
+		repeated.Tasks = nil
+		repeated.CompletedTasks = nil
+
+		for _, task := range taskList.Tasks {
+			if task.Recurrence != nil {
+				repeated.Tasks = append(repeated.Tasks, task)
+			} else {
+				single.Tasks = append(single.Tasks, task)
+			}
+		}
+
+		oneOff = append(oneOff, single)
+		if len(repeated.Tasks) > 0 {
+			recurring = append(recurring, repeated)
+		}
+	}
+	return oneOff, recurring
+}
+
+// lastOccurrence returns the most recent occurrence of a recurring task at or
+// before now. The series is anchored at the due date, falling back to the
+// range start and then the creation time. A numbered range counts its
+// occurrences from the range start, as the due date moves along the series
+// with every completion. It reports false when no occurrence has passed yet.
+func lastOccurrence(task todo.Task, now time.Time) (time.Time, bool) {
+	r := task.Recurrence
+	anchor := recurrenceAnchor(task)
+
+	var endDate time.Time
+	if r.Range.Type == "endDate" {
+		if d, err := time.ParseInLocation(time.DateOnly, r.Range.EndDate, anchor.Location()); err == nil {
+			endDate = d
+		}
+	}
+
+	interval := max(r.Pattern.Interval, 1)
+	anchorDate := dateOf(anchor)
+	if r.Range.Type == "numbered" {
+		if start, ok := rangeStart(r, anchor.Location()); ok && start.Before(anchorDate) {
+			anchorDate = start
+		}
+	}
+
+	var last time.Time
+	found := false
+	count := 0
+	for period := 0; period < maxRecurrencePeriods; period++ {
+		for _, date := range periodDates(r, anchorDate, period*interval) {
+			if date.Before(anchorDate) {
+				continue
+			}
+			if !endDate.IsZero() && date.After(endDate) {
+				return last, found
+			}
+			count++
+			if r.Range.Type == "numbered" && r.Range.NumberOfOccurrences > 0 && count > r.Range.NumberOfOccurrences {
+				return last, found
+			}
+
+			occurrence := time.Date(date.Year(), date.Month(), date.Day(), anchor.Hour(), anchor.Minute(), anchor.Second(), 0, anchor.Location())
+			if occurrence.After(now) {
+				return last, found
+			}
+			last, found = occurrence, true
+		}
+	}
+	return last, found
+}
+
+func recurrenceAnchor(task todo.Task) time.Time {
+	if task.DueDateTime != nil {
+		return task.DueDateTime.Time
+	}
+	r := task.Recurrence
+	if start, ok := rangeStart(r, todo.ResolveTimeZone(r.Range.RecurrenceTimeZone)); ok {
+		return start
+	}
+	return task.CreatedDateTime
+}
+
+// rangeStart returns midnight of the range's start date in loc, if it has one.
+func rangeStart(r *todo.RecurrenceTask, loc *time.Location) (time.Time, bool) {
+	if r.Range.StartDate == "" {
+		return time.Time{}, false
+	}
+	d, err := time.ParseInLocation(time.DateOnly, r.Range.StartDate, loc)
+	return d, err == nil
+}
+
+// periodDates lists, in order, the candidate dates of the period that starts
+// offset units (days, weeks, months or years) after the anchor's period.
+func periodDates(r *todo.RecurrenceTask, anchor time.Time, offset int) []time.Time {
+	p := r.Pattern
+	switch p.Type {
+	case "daily":
+		return []time.Time{anchor.AddDate(0, 0, offset)}
+	case "weekly":
+		firstDay := parseWeekdayOr(p.FirstDayOfWeek, time.Sunday)
+		weekStart := anchor.AddDate(0, 0, -((int(anchor.Weekday())-int(firstDay)+7)%7)+7*offset)
+		var dates []time.Time
+		for _, day := range weekdaysOr(p.DaysOfWeek, anchor.Weekday()) {
+			dates = append(dates, weekStart.AddDate(0, 0, (int(day)-int(firstDay)+7)%7))
+		}
+		slices.SortFunc(dates, func(a, b time.Time) int { return a.Compare(b) })
+		return dates
+	case "absoluteMonthly":
+		month := firstOfMonth(anchor).AddDate(0, offset, 0)
+		return []time.Time{dayInMonth(month, dayOr(p.DayOfMonth, anchor.Day()))}
+	case "relativeMonthly":
+		return relativeDates(firstOfMonth(anchor).AddDate(0, offset, 0), p.Index, weekdaysOr(p.DaysOfWeek, anchor.Weekday()))
+	case "absoluteYearly":
+		month := time.Date(anchor.Year()+offset, time.Month(dayOr(p.Month, int(anchor.Month()))), 1, 0, 0, 0, 0, anchor.Location())
+		return []time.Time{dayInMonth(month, dayOr(p.DayOfMonth, anchor.Day()))}
+	case "relativeYearly":
+		month := time.Date(anchor.Year()+offset, time.Month(dayOr(p.Month, int(anchor.Month()))), 1, 0, 0, 0, 0, anchor.Location())
+		return relativeDates(month, p.Index, weekdaysOr(p.DaysOfWeek, anchor.Weekday()))
+	}
+
+	// Unknown patterns behave like a single due date.
+	if offset == 0 {
+		return []time.Time{anchor}
+	}
+	return nil
+}
+
+// relativeDates returns, for each weekday, its first/second/third/fourth/last
+// occurrence in the month starting at month.
+func relativeDates(month time.Time, index string, days []time.Weekday) []time.Time {
+	var dates []time.Time
+	for _, day := range days {
+		if index == "last" {
+			last := month.AddDate(0, 1, -1)
+			dates = append(dates, last.AddDate(0, 0, -((int(last.Weekday())-int(day)+7)%7)))
+			continue
+		}
+
+		first := month.AddDate(0, 0, (int(day)-int(month.Weekday())+7)%7)
+		n := slices.Index([]string{"first", "second", "third", "fourth"}, index)
+		dates = append(dates, first.AddDate(0, 0, 7*max(n, 0)))
+	}
+	slices.SortFunc(dates, func(a, b time.Time) int { return a.Compare(b) })
+	return dates
+}
+
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func firstOfMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
+
+// dayInMonth clamps day to the length of the month starting at month.
+func dayInMonth(month time.Time, day int) time.Time {
+	lastDay := month.AddDate(0, 1, -1).Day()
+	return month.AddDate(0, 0, min(day, lastDay)-1)
+}
+
+func dayOr(value, fallback int) int {
+	if value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+func parseWeekdayOr(name string, fallback time.Weekday) time.Weekday {
+	if day, err := ParseWeekday(name); err == nil {
+		return day
+	}
+	return fallback
+}
+
+func weekdaysOr(names []string, fallback time.Weekday) []time.Weekday {
+	var days []time.Weekday
+	for _, name := range names {
+		if day, err := ParseWeekday(name); err == nil {
+			days = append(days, day)
+		}
+	}
+	if len(days) == 0 {
+		return []time.Weekday{fallback}
+	}
+	return days
+}
@@ -0,0 +1,184 @@
+package todometrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/uchr/ToDoInfo/internal/todo"
+)
+
+func recurringTask(patternType string, interval int, due string, configure func(r *todo.RecurrenceTask)) todo.Task {
+	r := &todo.RecurrenceTask{}
+	r.Pattern.Type = patternType
+	r.Pattern.Interval = interval
+	r.Pattern.FirstDayOfWeek = "sunday"
+	r.Range.Type = "noEnd"
+	if configure != nil {
+		configure(r)
+	}
+
+	task := todo.Task{Title: patternType, Recurrence: r, CreatedDateTime: testNow}
+	if due != "" {
+		d, _ := time.Parse(time.DateOnly, due)
+		task.DueDateTime = &todo.DateTimeTimeZone{Time: d, TimeZone: "UTC"}
+	}
+	return task
+}
+
+func TestLastOccurrence(t *testing.T) {
+	// testNow is Wednesday 2026-10-14 12:00 UTC.
+	tests := []struct {
+		name     string
+		task     todo.Task
+		expected string
+	}{
+		{
+			name:     "Daily",
+			task:     recurringTask("daily", 1, "2026-10-10", nil),
+			expected: "2026-10-14",
+		},
+		{
+			name:     "Every third day",
+			task:     recurringTask("daily", 3, "2026-10-10", nil),
+			expected: "2026-10-13",
+		},
+		{
+			name: "Weekly on Monday and Thursday",
+			task: recurringTask("weekly", 1, "2026-09-28", func(r *todo.RecurrenceTask) {
+				r.Pattern.DaysOfWeek = []string{"thursday", "monday"}
+			}),
+			expected: "2026-10-12",
+		},
+		{
+			name: "Fortnightly on Monday",
+			task: recurringTask("weekly", 2, "2026-10-05", func(r *todo.RecurrenceTask) {
+				r.Pattern.DaysOfWeek = []string{"monday"}
+			}),
+			expected: "2026-10-05",
+		},
+		{
+			name: "Monthly on the 31st clamps to short months",
+			task: recurringTask("absoluteMonthly", 1, "2026-08-31", func(r *todo.RecurrenceTask) {
+				r.Pattern.DayOfMonth = 31
+			}),
+			expected: "2026-09-30",
+		},
+		{
+			name: "Last Friday of the month",
+			task: recurringTask("relativeMonthly", 1, "2026-07-31", func(r *todo.RecurrenceTask) {
+				r.Pattern.DaysOfWeek = []string{"friday"}
+				r.Pattern.Index = "last"
+			}),
+			expected: "2026-09-25",
+		},
+		{
+			name: "Second Tuesday of the month",
+			task: recurringTask("relativeMonthly", 1, "2026-01-13", func(r *todo.RecurrenceTask) {
+				r.Pattern.DaysOfWeek = []string{"tuesday"}
+				r.Pattern.Index = "second"
+			}),
+			expected: "2026-10-13",
+		},
+		{
+			name: "Yearly on 29 February",
+			task: recurringTask("absoluteYearly", 1, "2024-02-29", func(r *todo.RecurrenceTask) {
+				r.Pattern.Month = 2
+				r.Pattern.DayOfMonth = 29
+			}),
+			expected: "2026-02-28",
+		},
+		{
+			name: "Numbered range stops after the last occurrence",
+			task: recurringTask("daily", 1, "2026-10-01", func(r *todo.RecurrenceTask) {
+				r.Range.Type = "numbered"
+				r.Range.NumberOfOccurrences = 3
+			}),
+			expected: "2026-10-03",
+		},
+		{
+			name: "Numbered range counts from the range start",
+			task: recurringTask("daily", 1, "2026-10-04", func(r *todo.RecurrenceTask) {
+				r.Range.Type = "numbered"
+				r.Range.StartDate = "2026-10-01"
+				r.Range.NumberOfOccurrences = 5
+			}),
+			expected: "2026-10-05",
+		},
+		{
+			name: "Numbered range due on its last occurrence",
+			task: recurringTask("weekly", 1, "2026-09-21", func(r *todo.RecurrenceTask) {
+				r.Pattern.DaysOfWeek = []string{"monday"}
+				r.Range.Type = "numbered"
+				r.Range.StartDate = "2026-09-07"
+				r.Range.NumberOfOccurrences = 3
+			}),
+			expected: "2026-09-21",
+		},
+		{
+			name: "End date range",
+			task: recurringTask("daily", 1, "2026-10-01", func(r *todo.RecurrenceTask) {
+				r.Range.Type = "endDate"
+				r.Range.EndDate = "2026-10-05"
+			}),
+			expected: "2026-10-05",
+		},
+		{
+			name: "Range start without due date",
+			task: recurringTask("weekly", 1, "", func(r *todo.RecurrenceTask) {
+				r.Pattern.DaysOfWeek = []string{"monday"}
+				r.Range.StartDate = "2026-10-12"
+				r.Range.RecurrenceTimeZone = "UTC"
+			}),
+			expected: "2026-10-12",
+		},
+		{
+			name:     "Not due yet",
+			task:     recurringTask("daily", 1, "2026-10-20", nil),
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			occurrence, ok := lastOccurrence(tt.task, testNow)
+			if tt.expected == "" {
+				assert.False(t, ok)
+				return
+			}
+			assert.True(t, ok)
+			assert.Equal(t, tt.expected, occurrence.Format(time.DateOnly))
+		})
+	}
+}
+
+func TestRecurrenceModes(t *testing.T) {
+	oneOff := todo.Task{Title: "Write report", CreatedDateTime: getDateFromNow(5, 0)}
+	habit := recurringTask("daily", 1, "2026-09-01", nil)
+	habit.Title = "Stretch"
+	taskLists := []todo.TaskList{{Name: "List1", Tasks: []todo.Task{oneOff, habit}}}
+
+	m := newTestMetrics(taskLists)
+	tasks := m.GetSortedTasks()
+	clearExactAge(tasks)
+	assert.Equal(t, []TaskRottennessInfo{
+		{TaskName: "Write report", TaskList: "List1", Age: 5, Rottenness: RipeTaskRottenness},
+		{TaskName: "Stretch", TaskList: "List1", Age: 0, Rottenness: FreshTaskRottenness, Recurring: true},
+	}, tasks)
+	assert.Empty(t, m.GetRecurringTasks())
+
+	m = newTestMetrics(taskLists, WithRecurrenceMode(RecurrenceSeparate))
+	assert.Len(t, m.GetSortedTasks(), 1)
+	assert.Equal(t, 5, m.GetListAges().TotalAge)
+	if assert.Len(t, m.GetRecurringTasks(), 1) {
+		assert.Equal(t, "Stretch", m.GetRecurringTasks()[0].TaskName)
+	}
+
+	m = newTestMetrics(taskLists, WithRecurrenceMode(RecurrenceExclude))
+	assert.Len(t, m.GetSortedTasks(), 1)
+	assert.Empty(t, m.GetRecurringTasks())
+
+	_, err := ParseRecurrenceMode("sometimes")
+	assert.Error(t, err)
+}
@@ -3,18 +3,22 @@ package todometrics
 import (
 	"time"
 
+	"github.com/uchr/ToDoInfo/internal/clock"
 	"github.com/uchr/ToDoInfo/internal/todo"
 )
 
+// TaskRottenness is an index into the levels of a RottennessPolicy, freshest
+// first. The named constants refer to the default ladder.
 type TaskRottenness int
 
 const (
-	ZombieTaskRottenness = iota
-	TiredTaskRottenness
+	FreshTaskRottenness TaskRottenness = iota
 	RipeTaskRottenness
-	FreshTaskRottenness
+	TiredTaskRottenness
+	ZombieTaskRottenness
 )
 
+// Day thresholds of the default rottenness ladder.
 const (
 	zombieTaskDay = 14
 	tiredTaskDay  = 7
@@ -25,6 +29,9 @@ type ListAge struct {
 	Age       int
 	TaskCount int
 	Title     string
+	// Excluded is set when policy overrides keep every task of the list out
+	// of the total age.
+	Excluded bool
 }
 
 type ListAges struct {
@@ -33,15 +40,55 @@ type ListAges struct {
 }
 
 type TaskRottennessInfo struct {
+	// TaskID and ListID identify the task in Microsoft To Do.
+	TaskID     string
+	ListID     string
 	TaskName   string
 	TaskList   string
 	Age        int
 	Rottenness TaskRottenness
+	// ExcludedFromTotal is set when a policy override keeps the task out of the total age.
+	ExcludedFromTotal bool
+	// Recurring is set for tasks with a recurrence pattern; their age counts
+	// from the most recently missed occurrence.
+	Recurring bool
 
 	exactAge time.Duration
 }
 
 type Metrics struct {
-	lists       []todo.TaskList
-	sortedTasks []TaskRottennessInfo
+	lists          []todo.TaskList
+	sortedTasks    []TaskRottennessInfo
+	recurringTasks []TaskRottennessInfo
+	policy         RottennessPolicy
+	calendar       *WorkCalendar
+	recurrence     RecurrenceMode
+	clock          clock.Clock
+	now            time.Time
+}
+
+type WeeklyThroughput struct {
+	WeekStart time.Time
+	Completed int
+}
+
+type ListCycleTime struct {
+	Title       string
+	Completed   int
+	AverageDays float64
+	MedianDays  float64
+}
+
+// Throughput summarises completed work within a completion window.
+type Throughput struct {
+	WindowStart time.Time
+	// Queued counts the tasks created within the window that are either
+	// still not started or completed within it. Tasks in progress, waiting
+	// on others or deferred aren't fetched, so they aren't counted.
+	Queued    int
+	Completed int
+	// NetFlow is Queued minus Completed; positive means the backlog grows.
+	NetFlow    int
+	Weekly     []WeeklyThroughput
+	CycleTimes []ListCycleTime
 }
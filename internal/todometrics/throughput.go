@@ -0,0 +1,96 @@
+package todometrics
+
+import (
+	"sort"
+	"time"
+)
+
+// GetThroughput reports completion throughput for the window ending at the
+// instant the metrics were evaluated.
+// Only completed tasks fetched alongside the lists are taken into account.
+func (l *Metrics) GetThroughput(window time.Duration) Throughput {
+	now := l.now
+	windowStart := now.Add(-window)
+
+	result := Throughput{WindowStart: windowStart}
+
+	weekly := make(map[time.Time]int)
+	for week := getWeekStart(windowStart); !week.After(now); week = week.AddDate(0, 0, 7) {
+		weekly[week] = 0
+	}
+
+	for _, taskList := range l.lists {
+		for _, task := range taskList.Tasks {
+			if !task.CreatedDateTime.Before(windowStart) {
+				result.Queued++
+			}
+		}
+
+		var cycleDays []float64
+		for _, task := range taskList.CompletedTasks {
+			if task.CompletedDateTime == nil || task.CompletedDateTime.Time.Before(windowStart) {
+				continue
+			}
+			if !task.CreatedDateTime.Before(windowStart) {
+				result.Queued++
+			}
+			result.Completed++
+			weekly[getWeekStart(task.CompletedDateTime.Time.In(now.Location()))]++
+
+			cycle := task.CompletedDateTime.Time.Sub(task.CreatedDateTime)
+			if cycle < 0 {
+				cycle = 0
+			}
+			cycleDays = append(cycleDays, cycle.Hours()/24)
+		}
+
+		if len(cycleDays) > 0 {
+			result.CycleTimes = append(result.CycleTimes, ListCycleTime{
+				Title:       taskList.Name,
+				Completed:   len(cycleDays),
+				AverageDays: average(cycleDays),
+				MedianDays:  median(cycleDays),
+			})
+		}
+	}
+	result.NetFlow = result.Queued - result.Completed
+
+	for week, completed := range weekly {
+		result.Weekly = append(result.Weekly, WeeklyThroughput{WeekStart: week, Completed: completed})
+	}
+	sort.Slice(result.Weekly, func(i, j int) bool {
+		return result.Weekly[i].WeekStart.Before(result.Weekly[j].WeekStart)
+	})
+
+	sort.Slice(result.CycleTimes, func(i, j int) bool {
+		return result.CycleTimes[i].Title < result.CycleTimes[j].Title
+	})
+
+	return result
+}
+
+// getWeekStart returns midnight of the Monday starting t's week, in t's location.
+func getWeekStart(t time.Time) time.Time {
+	year, month, day := t.Date()
+	midnight := time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+	offset := (int(midnight.Weekday()) + 6) % 7
+	return midnight.AddDate(0, 0, -offset)
+}
+
+func average(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
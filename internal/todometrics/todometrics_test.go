@@ -6,12 +6,20 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/uchr/ToDoInfo/internal/clock"
 	"github.com/uchr/ToDoInfo/internal/todo"
 )
 
+// testNow is the instant every test evaluates metrics at.
+var testNow = time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+
 func getDateFromNow(dayBefore int, hourBefore int) time.Time {
 	d := time.Duration(int(time.Hour) * -(24*dayBefore + hourBefore))
-	return time.Now().Add(d)
+	return testNow.Add(d)
+}
+
+func newTestMetrics(taskLists []todo.TaskList, opts ...Option) *Metrics {
+	return New(taskLists, append([]Option{WithClock(clock.NewFixed(testNow))}, opts...)...)
 }
 
 func clearExactAge(t []TaskRottennessInfo) {
@@ -36,7 +44,7 @@ func TestGetTaskAge(t *testing.T) {
 	}{
 		{
 			name:        "Current time",
-			taskTime:    time.Now(),
+			taskTime:    testNow,
 			expectedAge: 0,
 		},
 		{
@@ -66,7 +74,7 @@ func TestGetTaskAge(t *testing.T) {
 			age, _ := getTaskAge(todo.Task{
 				CreatedDateTime:      tt.taskTime,
 				LastModifiedDateTime: tt.taskTime,
-			})
+			}, testNow, nil)
 
 			assert.Equal(t, tt.expectedAge, age)
 		})
@@ -174,7 +182,7 @@ func TestGetSortedTasks(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			tasks := getSortedTasks(tt.taskLists)
+			tasks := getSortedTasks(tt.taskLists, testNow, DefaultRottennessPolicy(), nil)
 			clearExactAge(tasks)
 
 			assert.Equal(t, tt.expectedResult, tasks)
@@ -281,7 +289,7 @@ func TestGetListAges(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			m := New(tt.taskLists)
+			m := newTestMetrics(tt.taskLists)
 			tasks := m.GetListAges()
 
 			assert.Equal(t, tt.expectedResult, tasks)
@@ -455,7 +463,7 @@ func TestGetTopOldestTasks(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			m := New(tt.taskLists)
+			m := newTestMetrics(tt.taskLists)
 			tasks := m.GetTopTasksByAge(tt.taskCount)
 			clearExactAge(tasks)
 
@@ -550,7 +558,7 @@ func TestGetOldestTaskForList(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			m := New(tt.taskLists)
+			m := newTestMetrics(tt.taskLists)
 			tasks := m.GetOldestTaskForList()
 			clearExactAgeForMap(tasks)
 
@@ -719,7 +727,7 @@ func TestGetRottenTasks(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			m := New(tt.taskLists)
+			m := newTestMetrics(tt.taskLists)
 			tasks := m.GetRottenTasks(tt.minRottenness)
 			clearExactAge(tasks)
 
@@ -727,3 +735,207 @@ func TestGetRottenTasks(t *testing.T) {
 		})
 	}
 }
+
+func TestRottennessPolicy(t *testing.T) {
+	policy := RottennessPolicy{
+		Levels: []RottennessLevel{
+			{Name: "Fresh", Emoji: "🌱"},
+			{Name: "Ripe", Emoji: "🍋", Days: 1},
+			{Name: "Tired", Emoji: "🥱", Days: 3},
+			{Name: "Zombie", Emoji: "🤢", Days: 10},
+			{Name: "Fossil", Emoji: "🦴", Days: 30},
+		},
+	}
+	assert.NoError(t, policy.Validate())
+
+	tests := []struct {
+		age      int
+		expected string
+	}{
+		{age: 0, expected: "Fresh"},
+		{age: 1, expected: "Fresh"},
+		{age: 2, expected: "Ripe"},
+		{age: 10, expected: "Tired"},
+		{age: 11, expected: "Zombie"},
+		{age: 31, expected: "Fossil"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, policy.Level(policy.Rottenness(tt.age)).Name, "age %d", tt.age)
+	}
+
+	taskLists := []todo.TaskList{
+		{
+			Name: "List1",
+			Tasks: []todo.Task{
+				{Title: "Old", CreatedDateTime: getDateFromNow(40, 0), LastModifiedDateTime: getDateFromNow(40, 0)},
+				{Title: "Stale", CreatedDateTime: getDateFromNow(12, 0), LastModifiedDateTime: getDateFromNow(12, 0)},
+				{Title: "New", CreatedDateTime: getDateFromNow(2, 0), LastModifiedDateTime: getDateFromNow(2, 0)},
+			},
+		},
+	}
+	m := newTestMetrics(taskLists, WithPolicy(policy))
+	assert.Equal(t, TaskRottenness(3), policy.ZombieLevel())
+
+	zombies := m.GetRottenTasks(policy.ZombieLevel())
+	if assert.Len(t, zombies, 2) {
+		assert.Equal(t, "Old", zombies[0].TaskName)
+		assert.Equal(t, "Fossil", policy.Level(zombies[0].Rottenness).Name)
+		assert.Equal(t, "Stale", zombies[1].TaskName)
+	}
+}
+
+func TestRottennessPolicyOverrides(t *testing.T) {
+	policy := DefaultRottennessPolicy()
+	policy.Overrides = []PolicyOverride{
+		{List: "shop*", Days: map[string]int{"ripe": 1, "tired": 2, "zombie": 3}},
+		{List: "Someday*", Days: map[string]int{"Ripe": 30, "Tired": 90, "Zombie": 180}, ExcludeFromTotal: true},
+		{Category: "Waiting", Days: map[string]int{"Zombie": 60}},
+	}
+	assert.NoError(t, policy.Validate())
+
+	taskLists := []todo.TaskList{
+		{
+			Name: "Shopping",
+			Tasks: []todo.Task{
+				{Title: "Milk", CreatedDateTime: getDateFromNow(4, 1), LastModifiedDateTime: getDateFromNow(4, 1)},
+			},
+		},
+		{
+			Name: "Someday/Maybe",
+			Tasks: []todo.Task{
+				{Title: "Learn Go", CreatedDateTime: getDateFromNow(60, 0), LastModifiedDateTime: getDateFromNow(60, 0)},
+			},
+		},
+		{
+			Name: "Work",
+			Tasks: []todo.Task{
+				{Title: "Reply", Categories: []string{"waiting"}, CreatedDateTime: getDateFromNow(20, 0), LastModifiedDateTime: getDateFromNow(20, 0)},
+				{Title: "Report", CreatedDateTime: getDateFromNow(20, 0).Add(time.Minute), LastModifiedDateTime: getDateFromNow(20, 0)},
+			},
+		},
+	}
+	m := newTestMetrics(taskLists, WithPolicy(policy))
+
+	tasks := m.GetSortedTasks()
+	clearExactAge(tasks)
+	assert.Equal(t, []TaskRottennessInfo{
+		{TaskName: "Learn Go", TaskList: "Someday/Maybe", Age: 60, Rottenness: RipeTaskRottenness, ExcludedFromTotal: true},
+		{TaskName: "Reply", TaskList: "Work", Age: 20, Rottenness: TiredTaskRottenness},
+		{TaskName: "Report", TaskList: "Work", Age: 19, Rottenness: ZombieTaskRottenness},
+		{TaskName: "Milk", TaskList: "Shopping", Age: 4, Rottenness: ZombieTaskRottenness},
+	}, tasks)
+
+	assert.Equal(t, ListAges{
+		TotalAge: 43,
+		Ages: []ListAge{
+			{Title: "Someday/Maybe", Age: 60, TaskCount: 1, Excluded: true},
+			{Title: "Work", Age: 39, TaskCount: 2},
+			{Title: "Shopping", Age: 4, TaskCount: 1},
+		},
+	}, m.GetListAges())
+}
+
+func TestGetListAgesExcludedByCategory(t *testing.T) {
+	policy := DefaultRottennessPolicy()
+	policy.Overrides = []PolicyOverride{
+		{Category: "Someday", ExcludeFromTotal: true},
+		{List: "Archive", ExcludeFromTotal: true},
+	}
+
+	taskLists := []todo.TaskList{
+		{
+			Name: "Ideas",
+			Tasks: []todo.Task{
+				{Title: "Learn Go", Categories: []string{"someday"}, CreatedDateTime: getDateFromNow(30, 0), LastModifiedDateTime: getDateFromNow(30, 0)},
+			},
+		},
+		{
+			Name: "Work",
+			Tasks: []todo.Task{
+				{Title: "Rewrite it in Rust", Categories: []string{"Someday"}, CreatedDateTime: getDateFromNow(20, 0), LastModifiedDateTime: getDateFromNow(20, 0)},
+				{Title: "Report", CreatedDateTime: getDateFromNow(10, 0), LastModifiedDateTime: getDateFromNow(10, 0)},
+			},
+		},
+		{Name: "Archive"},
+	}
+	m := newTestMetrics(taskLists, WithPolicy(policy))
+
+	assert.Equal(t, ListAges{
+		TotalAge: 10,
+		Ages: []ListAge{
+			{Title: "Ideas", Age: 30, TaskCount: 1, Excluded: true},
+			{Title: "Work", Age: 30, TaskCount: 2},
+			{Title: "Archive", Age: 0, TaskCount: 0, Excluded: true},
+		},
+	}, m.GetListAges())
+}
+
+func TestRottennessPolicyValidate(t *testing.T) {
+	assert.NoError(t, DefaultRottennessPolicy().Validate())
+	assert.Error(t, RottennessPolicy{}.Validate())
+	assert.Error(t, RottennessPolicy{Levels: []RottennessLevel{{Name: "A"}, {Name: "B", Days: 5}, {Name: "C", Days: 5}}}.Validate())
+	assert.Error(t, RottennessPolicy{Levels: []RottennessLevel{{Name: "A"}, {Days: 5}}}.Validate())
+
+	withOverride := func(o PolicyOverride) RottennessPolicy {
+		policy := DefaultRottennessPolicy()
+		policy.Overrides = []PolicyOverride{o}
+		return policy
+	}
+	assert.Error(t, withOverride(PolicyOverride{Days: map[string]int{"Zombie": 30}}).Validate())
+	assert.Error(t, withOverride(PolicyOverride{List: "Work", Days: map[string]int{"Fossil": 30}}).Validate())
+	assert.Error(t, withOverride(PolicyOverride{List: "Work", Days: map[string]int{"Zombie": 5}}).Validate())
+}
+
+func TestGetThroughput(t *testing.T) {
+	completedAt := func(dayBefore int) *todo.DateTimeTimeZone {
+		return &todo.DateTimeTimeZone{Time: getDateFromNow(dayBefore, 0), TimeZone: "UTC"}
+	}
+
+	taskLists := []todo.TaskList{
+		{
+			Name: "List1",
+			Tasks: []todo.Task{
+				{Title: "Open new", CreatedDateTime: getDateFromNow(2, 0)},
+				{Title: "Open old", CreatedDateTime: getDateFromNow(40, 0)},
+			},
+			CompletedTasks: []todo.Task{
+				{Title: "Done fast", CreatedDateTime: getDateFromNow(5, 0), CompletedDateTime: completedAt(4)},
+				{Title: "Done slow", CreatedDateTime: getDateFromNow(30, 0), CompletedDateTime: completedAt(3)},
+				{Title: "Done long ago", CreatedDateTime: getDateFromNow(60, 0), CompletedDateTime: completedAt(50)},
+			},
+		},
+		{
+			Name: "List2",
+			CompletedTasks: []todo.Task{
+				{Title: "Done", CreatedDateTime: getDateFromNow(10, 0), CompletedDateTime: completedAt(8)},
+			},
+		},
+		{
+			Name:  "List3",
+			Tasks: []todo.Task{{Title: "Open", CreatedDateTime: getDateFromNow(1, 0)}},
+		},
+	}
+
+	m := newTestMetrics(taskLists)
+	throughput := m.GetThroughput(28 * 24 * time.Hour)
+
+	assert.Equal(t, 3, throughput.Completed)
+	// Open new, Open (List3), Done fast and Done (List2) were created in the window.
+	assert.Equal(t, 4, throughput.Queued)
+	assert.Equal(t, 1, throughput.NetFlow)
+
+	weeklyTotal := 0
+	for _, w := range throughput.Weekly {
+		weeklyTotal += w.Completed
+		assert.Equal(t, time.Monday, w.WeekStart.Weekday())
+	}
+	assert.Equal(t, 3, weeklyTotal)
+
+	assert.Len(t, throughput.CycleTimes, 2)
+	assert.Equal(t, "List1", throughput.CycleTimes[0].Title)
+	assert.Equal(t, 2, throughput.CycleTimes[0].Completed)
+	assert.InDelta(t, 14.0, throughput.CycleTimes[0].AverageDays, 0.01)
+	assert.InDelta(t, 14.0, throughput.CycleTimes[0].MedianDays, 0.01)
+	assert.Equal(t, "List2", throughput.CycleTimes[1].Title)
+	assert.InDelta(t, 2.0, throughput.CycleTimes[1].AverageDays, 0.01)
+}